@@ -0,0 +1,310 @@
+/*
+=============================================================================
+                    🧰 API CLIENT - HTTP CLIENT EXTENSION
+=============================================================================
+
+A reusable, typed client so demos stop repeating
+NewRequest -> Do -> ReadAll -> Unmarshal by hand.
+
+  client, _ := NewAPIClient("https://api.example.com",
+      WithTimeout(10*time.Second),
+      WithAuth(BearerAuth{Token: token}))
+
+  post, err := Get[Post](ctx, client, "/posts/1", nil)
+  created, err := Post[Post, Post](ctx, client, "/posts", newPost)
+  err = Delete(ctx, client, "/posts/1")
+
+Any non-2xx response becomes an *APIError with the status code and body.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxErrorBody caps how much of a failed response is kept in APIError
+const maxErrorBody = 64 << 10
+
+// 🔐 AUTHENTICATION: Pluggable strategies applied to every request
+type Authenticator interface {
+	Apply(req *http.Request) error
+}
+
+type BearerAuth struct {
+	Token string
+}
+
+func (a BearerAuth) Apply(req *http.Request) error {
+	req.Header.Set("Authorization", "Bearer "+a.Token)
+	return nil
+}
+
+type APIKeyAuth struct {
+	Header string // Defaults to X-API-Key
+	Key    string
+}
+
+func (a APIKeyAuth) Apply(req *http.Request) error {
+	header := a.Header
+	if header == "" {
+		header = "X-API-Key"
+	}
+	req.Header.Set(header, a.Key)
+	return nil
+}
+
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func (a BasicAuth) Apply(req *http.Request) error {
+	req.SetBasicAuth(a.Username, a.Password)
+	return nil
+}
+
+// 🚨 API ERROR: Uniform shape for every non-2xx response
+type APIError struct {
+	StatusCode int
+	Status     string
+	Method     string
+	URL        string
+	Message    string // From a JSON "error"/"message" field when present
+	Body       []byte
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = strings.TrimSpace(string(e.Body))
+	}
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if len(msg) > 200 {
+		msg = msg[:200] + "..."
+	}
+	return fmt.Sprintf("%s %s: HTTP %d: %s", e.Method, e.URL, e.StatusCode, msg)
+}
+
+// Temporary reports whether retrying the same request might succeed
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+func newAPIError(resp *http.Response) *APIError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Body:       body,
+	}
+	if resp.Request != nil {
+		apiErr.Method = resp.Request.Method
+		apiErr.URL = resp.Request.URL.String()
+	}
+
+	var decoded struct {
+		Error   interface{} `json:"error"`
+		Message string      `json:"message"`
+	}
+	if json.Unmarshal(body, &decoded) == nil {
+		switch e := decoded.Error.(type) {
+		case string:
+			apiErr.Message = e
+		case map[string]interface{}:
+			if m, ok := e["message"].(string); ok {
+				apiErr.Message = m
+			}
+		}
+		if apiErr.Message == "" {
+			apiErr.Message = decoded.Message
+		}
+	}
+	return apiErr
+}
+
+// 🧰 API CLIENT
+type APIClient struct {
+	BaseURL    *url.URL
+	HTTPClient *http.Client
+	Headers    http.Header
+	Auth       Authenticator
+}
+
+type ClientOption func(*APIClient)
+
+// WithHTTPClient uses a copy of hc, so later options such as WithTimeout
+// never change a client the caller shares elsewhere
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *APIClient) {
+		copied := *hc
+		c.HTTPClient = &copied
+	}
+}
+
+func WithTransport(rt http.RoundTripper) ClientOption {
+	return func(c *APIClient) { c.HTTPClient.Transport = rt }
+}
+
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *APIClient) { c.HTTPClient.Timeout = d }
+}
+
+func WithHeader(key, value string) ClientOption {
+	return func(c *APIClient) { c.Headers.Set(key, value) }
+}
+
+func WithAuth(auth Authenticator) ClientOption {
+	return func(c *APIClient) { c.Auth = auth }
+}
+
+func WithCookieJar(jar http.CookieJar) ClientOption {
+	return func(c *APIClient) { c.HTTPClient.Jar = jar }
+}
+
+func NewAPIClient(baseURL string, opts ...ClientOption) (*APIClient, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: need scheme and host", baseURL)
+	}
+
+	c := &APIClient{
+		BaseURL:    base,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		Headers:    http.Header{},
+	}
+	c.Headers.Set("Accept", "application/json")
+	c.Headers.Set("User-Agent", "Go-HTTP-Client/1.0")
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// NewRequest appends path to BaseURL's path (https://host/api/v1 + /users
+// is https://host/api/v1/users) and JSON-encodes body (if any). Absolute
+// URLs, like pagination links, are used as they are.
+func (c *APIClient) NewRequest(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Request, error) {
+	ref, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+	u := ref
+	if !ref.IsAbs() {
+		u = c.BaseURL.JoinPath(ref.EscapedPath())
+		u.RawQuery = ref.RawQuery
+	}
+	if len(query) > 0 {
+		q := u.Query()
+		for key, values := range query {
+			for _, v := range values {
+				q.Add(key, v)
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("encode request body: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range c.Headers {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Auth != nil {
+		if err := c.Auth.Apply(req); err != nil {
+			return nil, fmt.Errorf("apply auth: %w", err)
+		}
+	}
+	return req, nil
+}
+
+// Do sends req and decodes a 2xx JSON body into out (skipped when out is nil).
+// Non-2xx responses return an *APIError; the body is always closed.
+func (c *APIClient) Do(req *http.Request, out interface{}) (*http.Response, error) {
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, newAPIError(resp)
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		io.Copy(io.Discard, resp.Body)
+		return resp, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		if errors.Is(err, io.EOF) {
+			return resp, nil // Empty body
+		}
+		return resp, fmt.Errorf("decode %s response: %w", req.URL, err)
+	}
+	return resp, nil
+}
+
+// 🎯 TYPED HELPERS: Generic functions, since methods can't have type params
+func Get[T any](ctx context.Context, c *APIClient, path string, query url.Values) (T, error) {
+	var out T
+	req, err := c.NewRequest(ctx, http.MethodGet, path, query, nil)
+	if err != nil {
+		return out, err
+	}
+	_, err = c.Do(req, &out)
+	return out, err
+}
+
+func Post[Req, Resp any](ctx context.Context, c *APIClient, path string, body Req) (Resp, error) {
+	return send[Req, Resp](ctx, c, http.MethodPost, path, body)
+}
+
+func Put[Req, Resp any](ctx context.Context, c *APIClient, path string, body Req) (Resp, error) {
+	return send[Req, Resp](ctx, c, http.MethodPut, path, body)
+}
+
+func Delete(ctx context.Context, c *APIClient, path string) error {
+	req, err := c.NewRequest(ctx, http.MethodDelete, path, nil, nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.Do(req, nil)
+	return err
+}
+
+func send[Req, Resp any](ctx context.Context, c *APIClient, method, path string, body Req) (Resp, error) {
+	var out Resp
+	req, err := c.NewRequest(ctx, method, path, nil, body)
+	if err != nil {
+		return out, err
+	}
+	_, err = c.Do(req, &out)
+	return out, err
+}
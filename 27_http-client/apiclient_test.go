@@ -0,0 +1,184 @@
+/*
+=============================================================================
+                    🧪 API CLIENT TESTS
+=============================================================================
+
+URL building against base URLs with paths, APIError decoding, and client
+options, including that they never touch a caller's *http.Client.
+Run with: go test -v -run APIClient *.go
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIClientURLBuilding(t *testing.T) {
+	tests := []struct {
+		base  string
+		path  string
+		query url.Values
+		want  string
+	}{
+		{"https://api.example.com", "/users", nil, "https://api.example.com/users"},
+		{"https://api.example.com/", "users", nil, "https://api.example.com/users"},
+		{"https://api.example.com/api/v1", "/users/1", nil, "https://api.example.com/api/v1/users/1"},
+		{"https://api.example.com/api/v1/", "/users/1", nil, "https://api.example.com/api/v1/users/1"},
+		{"https://api.example.com/api/v1", "users/1", nil, "https://api.example.com/api/v1/users/1"},
+		{"https://api.example.com/api/v1", "/users/", nil, "https://api.example.com/api/v1/users/"},
+		{"https://api.example.com/api/v1", "", nil, "https://api.example.com/api/v1"},
+		{"https://api.example.com/api", "/files/a%2Fb", nil, "https://api.example.com/api/files/a%2Fb"},
+		{"https://api.example.com/api", "/search?q=go", url.Values{"page": {"2"}}, "https://api.example.com/api/search?page=2&q=go"},
+		{"https://api.example.com/api", "/search?q=go", url.Values{"q": {"rust"}}, "https://api.example.com/api/search?q=go&q=rust"},
+		{"https://api.example.com/api", "https://cdn.example.com/next?page=3", nil, "https://cdn.example.com/next?page=3"},
+	}
+	for _, tt := range tests {
+		client, err := NewAPIClient(tt.base)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := client.NewRequest(context.Background(), http.MethodGet, tt.path, tt.query, nil)
+		if err != nil {
+			t.Errorf("NewRequest(%q, %q): %v", tt.base, tt.path, err)
+			continue
+		}
+		if got := req.URL.String(); got != tt.want {
+			t.Errorf("base %q + %q = %s; want %s", tt.base, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestAPIClientRequestHeaders(t *testing.T) {
+	client, _ := NewAPIClient("https://api.example.com",
+		WithHeader("X-Trace", "abc"),
+		WithAuth(APIKeyAuth{Key: "secret"}))
+
+	req, err := client.NewRequest(context.Background(), http.MethodPost, "/posts", nil, map[string]string{"title": "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"Accept":       "application/json",
+		"Content-Type": "application/json",
+		"X-Trace":      "abc",
+		"X-Api-Key":    "secret",
+	}
+	for k, v := range want {
+		if got := req.Header.Get(k); got != v {
+			t.Errorf("%s = %q; want %q", k, got, v)
+		}
+	}
+
+	// Requests get their own header slices
+	req.Header.Add("X-Trace", "def")
+	if got := client.Headers.Values("X-Trace"); len(got) != 1 {
+		t.Errorf("client headers changed through a request: %v", got)
+	}
+}
+
+func TestAPIClientErrorDecoding(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantMessage string
+		wantError   string
+		temporary   bool
+	}{
+		{"error string", 400, `{"success":false,"error":"Invalid user ID"}`, "Invalid user ID", "HTTP 400: Invalid user ID", false},
+		{"error object", 422, `{"error":{"code":"invalid","message":"email is taken"}}`, "email is taken", "HTTP 422: email is taken", false},
+		{"message field", 404, `{"message":"Not Found"}`, "Not Found", "HTTP 404: Not Found", false},
+		{"plain text", 502, "bad gateway\n", "", "HTTP 502: bad gateway", true},
+		{"empty body", 503, "", "", "HTTP 503: Service Unavailable", true},
+		{"rate limited", 429, `{"error":"slow down"}`, "slow down", "HTTP 429: slow down", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			client, _ := NewAPIClient(server.URL + "/api")
+
+			_, err := Get[map[string]interface{}](context.Background(), client, "/thing", nil)
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) {
+				t.Fatalf("err = %v; want *APIError", err)
+			}
+			if apiErr.StatusCode != tt.status || apiErr.Message != tt.wantMessage || apiErr.Temporary() != tt.temporary {
+				t.Errorf("APIError = %d %q temporary=%v", apiErr.StatusCode, apiErr.Message, apiErr.Temporary())
+			}
+			if apiErr.Method != http.MethodGet || apiErr.URL != server.URL+"/api/thing" {
+				t.Errorf("APIError request = %s %s", apiErr.Method, apiErr.URL)
+			}
+			if !strings.HasSuffix(err.Error(), tt.wantError) {
+				t.Errorf("Error() = %q; want suffix %q", err.Error(), tt.wantError)
+			}
+		})
+	}
+
+	t.Run("large body", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(strings.Repeat("x", maxErrorBody*2)))
+		}))
+		defer server.Close()
+		client, _ := NewAPIClient(server.URL)
+
+		err := Delete(context.Background(), client, "/thing")
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || len(apiErr.Body) != maxErrorBody {
+			t.Fatalf("err = %T; want an APIError with the body capped at %d", err, maxErrorBody)
+		}
+		if len(err.Error()) > 300 {
+			t.Errorf("Error() is %d bytes; the message should be truncated", len(err.Error()))
+		}
+	})
+}
+
+func TestAPIClientOptionsCopyHTTPClient(t *testing.T) {
+	shared := &http.Client{Timeout: time.Minute}
+	jar, _ := cookiejar.New(nil)
+	transport := &http.Transport{}
+
+	client, err := NewAPIClient("https://api.example.com",
+		WithHTTPClient(shared),
+		WithTimeout(5*time.Second),
+		WithTransport(transport),
+		WithCookieJar(jar))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if shared.Timeout != time.Minute || shared.Transport != nil || shared.Jar != nil {
+		t.Errorf("caller's client was changed: %+v", shared)
+	}
+	hc := client.HTTPClient
+	if hc == shared || hc.Timeout != 5*time.Second || hc.Transport != transport || hc.Jar != jar {
+		t.Errorf("client's HTTPClient = %+v", hc)
+	}
+
+	// Options apply in order: a later WithHTTPClient replaces earlier settings
+	client, _ = NewAPIClient("https://api.example.com", WithTimeout(time.Second), WithHTTPClient(shared))
+	if client.HTTPClient.Timeout != time.Minute {
+		t.Errorf("Timeout = %v; want the shared client's", client.HTTPClient.Timeout)
+	}
+}
+
+func TestNewAPIClientValidation(t *testing.T) {
+	for _, base := range []string{"", "api.example.com", "/api", "http://", "://bad"} {
+		if _, err := NewAPIClient(base); err == nil {
+			t.Errorf("NewAPIClient(%q) accepted an invalid base URL", base)
+		}
+	}
+}
@@ -0,0 +1,433 @@
+/*
+=============================================================================
+                    ⚡ CIRCUIT BREAKER - HTTP CLIENT EXTENSION
+=============================================================================
+
+Stops hammering an upstream that is already down:
+
+  CLOSED ──(too many failures)──▶ OPEN ──(OpenTimeout)──▶ HALF-OPEN
+    ▲                                ▲                        │
+    └──────(probes succeed)──────────┼────────────────────────┤
+                                     └──────(probe fails)─────┘
+
+  cb := NewCircuitBreaker(BreakerSettings{
+      Name: "posts-api",
+      OnStateChange: func(name string, from, to BreakerState) {
+          log.Printf("breaker %s: %s -> %s", name, from, to)
+      },
+  })
+
+  // As a transport...
+  client, _ := NewAPIClient(baseURL, WithTransport(NewBreakerTransport(nil, cb)))
+
+  // ...or around any call
+  err := cb.Execute(func() error { return callUpstream() })
+
+The breaker trips on either an error rate over a rolling window or a run
+of consecutive failures. Only upstream trouble counts: *APIError values
+use Temporary() (429/5xx), so a 404 or 422 never opens the circuit. This
+mirrors the Code field of the APIError in 33_error-handling.
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the upstream while open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// 🚦 BREAKER STATES
+type BreakerState int
+
+const (
+	StateClosed BreakerState = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// ⚙️ BREAKER SETTINGS: Trip on a high error rate or a streak of failures
+type BreakerSettings struct {
+	Name string
+
+	Window      time.Duration // Rolling window for the error rate (default 10s)
+	Buckets     int           // Window resolution (default 10)
+	MinRequests int           // Calls in the window before the rate applies (default 20)
+	FailureRate float64       // Trip when failures/requests >= this (default 0.5)
+
+	ConsecutiveFailures int // Trip after this many failures in a row (default 5)
+
+	OpenTimeout       time.Duration // How long to stay open (default 30s)
+	HalfOpenMaxProbes int           // Probes allowed, and successes needed to close (default 1)
+
+	// IsFailure overrides the default error classification when set
+	IsFailure func(err error) bool
+
+	// OnStateChange is called (outside the lock) on every transition
+	OnStateChange func(name string, from, to BreakerState)
+}
+
+var defaultBreakerSettings = BreakerSettings{
+	Window:              10 * time.Second,
+	Buckets:             10,
+	MinRequests:         20,
+	FailureRate:         0.5,
+	ConsecutiveFailures: 5,
+	OpenTimeout:         30 * time.Second,
+	HalfOpenMaxProbes:   1,
+}
+
+// outcome of a single call as far as the breaker is concerned
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	outcomeIgnored // e.g. the caller cancelled; says nothing about upstream
+)
+
+// bucket counts calls in one slice of the rolling window
+type bucket struct {
+	epoch    int64
+	requests int
+	failures int
+}
+
+// ⚡ CIRCUIT BREAKER
+type CircuitBreaker struct {
+	settings BreakerSettings
+
+	mu             sync.Mutex
+	state          BreakerState
+	generation     uint64 // Bumped on every transition; stale results are dropped
+	openedAt       time.Time
+	buckets        []bucket
+	consecutive    int
+	probes         int // Half-open calls in flight
+	probeSuccesses int
+	changes        []pendingChange
+
+	now func() time.Time // Injection point for tests
+}
+
+func NewCircuitBreaker(settings BreakerSettings) *CircuitBreaker {
+	d := defaultBreakerSettings
+	if settings.Window <= 0 {
+		settings.Window = d.Window
+	}
+	if settings.Buckets <= 0 {
+		settings.Buckets = d.Buckets
+	}
+	if settings.MinRequests <= 0 {
+		settings.MinRequests = d.MinRequests
+	}
+	if settings.FailureRate <= 0 || settings.FailureRate > 1 {
+		settings.FailureRate = d.FailureRate
+	}
+	if settings.ConsecutiveFailures <= 0 {
+		settings.ConsecutiveFailures = d.ConsecutiveFailures
+	}
+	if settings.OpenTimeout <= 0 {
+		settings.OpenTimeout = d.OpenTimeout
+	}
+	if settings.HalfOpenMaxProbes <= 0 {
+		settings.HalfOpenMaxProbes = d.HalfOpenMaxProbes
+	}
+	if settings.IsFailure == nil {
+		settings.IsFailure = isBreakerFailure
+	}
+
+	return &CircuitBreaker{
+		settings: settings,
+		buckets:  make([]bucket, settings.Buckets),
+		now:      time.Now,
+	}
+}
+
+// State reports the current state, moving open -> half-open if due
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.mu.Lock()
+	state := cb.currentState()
+	notify := cb.takeNotification()
+	cb.mu.Unlock()
+	notify()
+	return state
+}
+
+// Counts returns requests and failures in the current rolling window
+func (cb *CircuitBreaker) Counts() (requests, failures int) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.windowCounts()
+}
+
+// Execute runs fn unless the circuit is open, recording its result
+func (cb *CircuitBreaker) Execute(fn func() error) error {
+	generation, err := cb.allow()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			cb.record(generation, outcomeFailure)
+			panic(p)
+		}
+	}()
+
+	err = fn()
+	cb.record(generation, cb.classify(err))
+	return err
+}
+
+func (cb *CircuitBreaker) classify(err error) outcome {
+	if err == nil {
+		return outcomeSuccess
+	}
+	if errors.Is(err, context.Canceled) {
+		return outcomeIgnored
+	}
+	if cb.settings.IsFailure(err) {
+		return outcomeFailure
+	}
+	return outcomeSuccess // The upstream answered; the request was just wrong
+}
+
+// isBreakerFailure: upstream trouble trips the breaker, client errors don't
+func isBreakerFailure(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Temporary()
+	}
+	return true // Network errors, timeouts, resets...
+}
+
+// allow admits a call, returning the generation it belongs to
+func (cb *CircuitBreaker) allow() (uint64, error) {
+	cb.mu.Lock()
+	state := cb.currentState()
+	notify := cb.takeNotification()
+
+	var err error
+	switch state {
+	case StateOpen:
+		err = ErrCircuitOpen
+	case StateHalfOpen:
+		if cb.probes >= cb.settings.HalfOpenMaxProbes {
+			err = ErrCircuitOpen
+		} else {
+			cb.probes++
+		}
+	}
+	generation := cb.generation
+	cb.mu.Unlock()
+
+	notify()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", cb.label(), err)
+	}
+	return generation, nil
+}
+
+func (cb *CircuitBreaker) record(generation uint64, result outcome) {
+	cb.mu.Lock()
+	if generation != cb.generation {
+		// Started before the last transition; its result is stale
+		cb.mu.Unlock()
+		return
+	}
+
+	switch cb.state {
+	case StateClosed:
+		cb.recordClosed(result)
+	case StateHalfOpen:
+		cb.probes--
+		switch result {
+		case outcomeFailure:
+			cb.setState(StateOpen)
+		case outcomeSuccess:
+			cb.probeSuccesses++
+			if cb.probeSuccesses >= cb.settings.HalfOpenMaxProbes {
+				cb.setState(StateClosed)
+			}
+		}
+	}
+	notify := cb.takeNotification()
+	cb.mu.Unlock()
+	notify()
+}
+
+func (cb *CircuitBreaker) recordClosed(result outcome) {
+	if result == outcomeIgnored {
+		return
+	}
+
+	b := cb.currentBucket()
+	b.requests++
+	if result == outcomeSuccess {
+		cb.consecutive = 0
+		return
+	}
+	b.failures++
+	cb.consecutive++
+
+	if cb.consecutive >= cb.settings.ConsecutiveFailures {
+		cb.setState(StateOpen)
+		return
+	}
+	requests, failures := cb.windowCounts()
+	if requests >= cb.settings.MinRequests &&
+		float64(failures)/float64(requests) >= cb.settings.FailureRate {
+		cb.setState(StateOpen)
+	}
+}
+
+// 🪟 ROLLING WINDOW: A ring of buckets keyed by time slice
+
+func (cb *CircuitBreaker) bucketWidth() time.Duration {
+	return max(cb.settings.Window/time.Duration(cb.settings.Buckets), time.Nanosecond)
+}
+
+func (cb *CircuitBreaker) currentBucket() *bucket {
+	epoch := cb.now().UnixNano() / int64(cb.bucketWidth())
+	b := &cb.buckets[epoch%int64(len(cb.buckets))]
+	if b.epoch != epoch {
+		*b = bucket{epoch: epoch}
+	}
+	return b
+}
+
+func (cb *CircuitBreaker) windowCounts() (requests, failures int) {
+	epoch := cb.now().UnixNano() / int64(cb.bucketWidth())
+	oldest := epoch - int64(len(cb.buckets)) + 1
+	for _, b := range cb.buckets {
+		if b.epoch >= oldest && b.epoch <= epoch {
+			requests += b.requests
+			failures += b.failures
+		}
+	}
+	return requests, failures
+}
+
+// 🔄 TRANSITIONS (callers hold cb.mu)
+
+// pendingChange holds a transition to report once the lock is released
+type pendingChange struct {
+	from, to BreakerState
+}
+
+func (cb *CircuitBreaker) currentState() BreakerState {
+	if cb.state == StateOpen && cb.now().Sub(cb.openedAt) >= cb.settings.OpenTimeout {
+		cb.setState(StateHalfOpen)
+	}
+	return cb.state
+}
+
+func (cb *CircuitBreaker) setState(to BreakerState) {
+	from := cb.state
+	if from == to {
+		return
+	}
+
+	cb.state = to
+	cb.generation++
+	cb.consecutive = 0
+	cb.probes = 0
+	cb.probeSuccesses = 0
+
+	switch to {
+	case StateOpen:
+		cb.openedAt = cb.now()
+	case StateClosed:
+		clear(cb.buckets) // Start afresh rather than re-tripping on old failures
+	}
+
+	cb.changes = append(cb.changes, pendingChange{from, to})
+}
+
+// takeNotification returns a func that reports queued transitions;
+// call it after unlocking so callbacks may use the breaker
+func (cb *CircuitBreaker) takeNotification() func() {
+	changes := cb.changes
+	cb.changes = nil
+	if len(changes) == 0 || cb.settings.OnStateChange == nil {
+		return func() {}
+	}
+	return func() {
+		for _, c := range changes {
+			cb.settings.OnStateChange(cb.settings.Name, c.from, c.to)
+		}
+	}
+}
+
+func (cb *CircuitBreaker) label() string {
+	if cb.settings.Name == "" {
+		return "circuit"
+	}
+	return cb.settings.Name
+}
+
+// 🔌 BREAKER TRANSPORT: Wraps an http.RoundTripper
+type BreakerTransport struct {
+	Base    http.RoundTripper
+	Breaker *CircuitBreaker
+}
+
+func NewBreakerTransport(base http.RoundTripper, cb *CircuitBreaker) *BreakerTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &BreakerTransport{Base: base, Breaker: cb}
+}
+
+func (t *BreakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	generation, err := t.Breaker.allow()
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close() // RoundTrippers must close the body, even on error
+		}
+		return nil, err
+	}
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		result := t.Breaker.classify(err)
+		if req.Context().Err() != nil {
+			result = outcomeIgnored // Caller gave up, upstream may be fine
+		}
+		t.Breaker.record(generation, result)
+		return nil, err
+	}
+
+	// Classify the status the same way the APIClient would report it
+	var statusErr error
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		statusErr = &APIError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Method:     req.Method,
+			URL:        req.URL.String(),
+		}
+	}
+	t.Breaker.record(generation, t.Breaker.classify(statusErr))
+	return resp, nil
+}
@@ -0,0 +1,234 @@
+/*
+=============================================================================
+                    🧪 CIRCUIT BREAKER TESTS
+=============================================================================
+
+Uses a fake clock so state transitions are deterministic.
+Run with: go test -v -run Breaker *.go
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errUpstream = errors.New("connection refused")
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+type transition struct{ from, to BreakerState }
+
+func newTestBreaker(settings BreakerSettings) (*CircuitBreaker, *fakeClock, *[]transition) {
+	var changes []transition
+	settings.OnStateChange = func(name string, from, to BreakerState) {
+		changes = append(changes, transition{from, to})
+	}
+	cb := NewCircuitBreaker(settings)
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	cb.now = clock.Now
+	return cb, clock, &changes
+}
+
+func fail() error    { return errUpstream }
+func succeed() error { return nil }
+
+func TestBreakerTripsOnConsecutiveFailures(t *testing.T) {
+	cb, _, changes := newTestBreaker(BreakerSettings{ConsecutiveFailures: 3})
+
+	for i := 0; i < 2; i++ {
+		cb.Execute(fail)
+	}
+	cb.Execute(succeed) // Resets the run
+	for i := 0; i < 2; i++ {
+		cb.Execute(fail)
+	}
+	if got := cb.State(); got != StateClosed {
+		t.Fatalf("state = %v after broken run; want closed", got)
+	}
+
+	cb.Execute(fail)
+	if got := cb.State(); got != StateOpen {
+		t.Fatalf("state = %v; want open", got)
+	}
+
+	called := false
+	err := cb.Execute(func() error { called = true; return nil })
+	if !errors.Is(err, ErrCircuitOpen) || called {
+		t.Errorf("Execute while open = %v (called=%v); want ErrCircuitOpen without calling", err, called)
+	}
+	if len(*changes) != 1 || (*changes)[0] != (transition{StateClosed, StateOpen}) {
+		t.Errorf("transitions = %v; want [closed->open]", *changes)
+	}
+}
+
+func TestBreakerTripsOnErrorRate(t *testing.T) {
+	cb, _, _ := newTestBreaker(BreakerSettings{
+		Window:              10 * time.Second,
+		Buckets:             10,
+		MinRequests:         10,
+		FailureRate:         0.5,
+		ConsecutiveFailures: 100,
+	})
+
+	// Alternate so there is never a consecutive run: 4 failures / 9 calls
+	for i := 0; i < 9; i++ {
+		if i%2 == 1 {
+			cb.Execute(fail)
+		} else {
+			cb.Execute(succeed)
+		}
+	}
+	if got := cb.State(); got != StateClosed {
+		t.Fatalf("state = %v below MinRequests; want closed", got)
+	}
+
+	cb.Execute(fail) // 5/10 = 50%
+	if got := cb.State(); got != StateOpen {
+		t.Fatalf("state = %v at 50%% failures; want open", got)
+	}
+
+	t.Run("old failures roll out of the window", func(t *testing.T) {
+		cb, clock, _ := newTestBreaker(BreakerSettings{MinRequests: 4, FailureRate: 0.5, ConsecutiveFailures: 100})
+		cb.Execute(fail)
+		cb.Execute(fail)
+		clock.Advance(11 * time.Second)
+		cb.Execute(succeed)
+		cb.Execute(fail)
+		cb.Execute(succeed)
+		cb.Execute(succeed)
+
+		if requests, failures := cb.Counts(); requests != 4 || failures != 1 {
+			t.Errorf("window = %d requests, %d failures; want 4, 1", requests, failures)
+		}
+		if got := cb.State(); got != StateClosed {
+			t.Errorf("state = %v; want closed", got)
+		}
+	})
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	settings := BreakerSettings{ConsecutiveFailures: 1, OpenTimeout: 5 * time.Second, HalfOpenMaxProbes: 2}
+
+	t.Run("probes succeed and close", func(t *testing.T) {
+		cb, clock, changes := newTestBreaker(settings)
+		cb.Execute(fail)
+		clock.Advance(5 * time.Second)
+
+		if got := cb.State(); got != StateHalfOpen {
+			t.Fatalf("state = %v after timeout; want half-open", got)
+		}
+		cb.Execute(succeed)
+		cb.Execute(succeed)
+		if got := cb.State(); got != StateClosed {
+			t.Fatalf("state = %v after probes; want closed", got)
+		}
+
+		want := []transition{{StateClosed, StateOpen}, {StateOpen, StateHalfOpen}, {StateHalfOpen, StateClosed}}
+		if len(*changes) != len(want) {
+			t.Fatalf("transitions = %v; want %v", *changes, want)
+		}
+		for i := range want {
+			if (*changes)[i] != want[i] {
+				t.Errorf("transition %d = %v; want %v", i, (*changes)[i], want[i])
+			}
+		}
+	})
+
+	t.Run("probe failure reopens", func(t *testing.T) {
+		cb, clock, _ := newTestBreaker(settings)
+		cb.Execute(fail)
+		clock.Advance(5 * time.Second)
+		cb.Execute(fail)
+		if got := cb.State(); got != StateOpen {
+			t.Errorf("state = %v; want open again", got)
+		}
+	})
+
+	t.Run("limits concurrent probes", func(t *testing.T) {
+		cb, clock, _ := newTestBreaker(settings)
+		cb.Execute(fail)
+		clock.Advance(5 * time.Second)
+
+		release := make(chan struct{})
+		started := make(chan struct{}, 2)
+		done := make(chan error, 2)
+		for i := 0; i < 2; i++ {
+			go func() {
+				done <- cb.Execute(func() error { started <- struct{}{}; <-release; return nil })
+			}()
+		}
+		<-started
+		<-started
+
+		if err := cb.Execute(succeed); !errors.Is(err, ErrCircuitOpen) {
+			t.Errorf("third probe = %v; want ErrCircuitOpen", err)
+		}
+		close(release)
+		<-done
+		<-done
+		if got := cb.State(); got != StateClosed {
+			t.Errorf("state = %v; want closed", got)
+		}
+	})
+}
+
+func TestBreakerClassifiesAPIErrors(t *testing.T) {
+	cb, _, _ := newTestBreaker(BreakerSettings{ConsecutiveFailures: 2})
+
+	notFound := &APIError{StatusCode: http.StatusNotFound}
+	for i := 0; i < 5; i++ {
+		cb.Execute(func() error { return notFound })
+	}
+	cb.Execute(func() error { return context.Canceled })
+	if got := cb.State(); got != StateClosed {
+		t.Fatalf("state = %v after 404s and cancellations; want closed", got)
+	}
+
+	unavailable := &APIError{StatusCode: http.StatusServiceUnavailable}
+	cb.Execute(func() error { return unavailable })
+	cb.Execute(func() error { return unavailable })
+	if got := cb.State(); got != StateOpen {
+		t.Errorf("state = %v after 503s; want open", got)
+	}
+}
+
+func TestBreakerTransport(t *testing.T) {
+	var hits atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cb, _, _ := newTestBreaker(BreakerSettings{ConsecutiveFailures: 3})
+	client, err := NewAPIClient(server.URL, WithTransport(NewBreakerTransport(nil, cb)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		var apiErr *APIError
+		if _, err := Get[BlogPost](ctx, client, "/posts/1", nil); !errors.As(err, &apiErr) {
+			t.Fatalf("call %d: err = %v; want *APIError", i+1, err)
+		}
+	}
+
+	_, err = Get[BlogPost](ctx, client, "/posts/1", nil)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("err = %v; want ErrCircuitOpen", err)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Errorf("upstream hits = %d; want 3 (no call while open)", got)
+	}
+}
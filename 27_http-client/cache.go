@@ -0,0 +1,563 @@
+/*
+=============================================================================
+                    🗄️ RESPONSE CACHE - HTTP CLIENT EXTENSION
+=============================================================================
+
+A private HTTP cache as an http.RoundTripper:
+
+  cache := NewCacheTransport(http.DefaultTransport, NewMemoryCache(8<<20))
+  cache.StaleIfError = time.Minute
+  client, _ := NewAPIClient(baseURL, WithTransport(cache))
+
+• GET responses are stored when Cache-Control/Expires allow it
+• Fresh entries are served without touching the network (X-Cache: HIT)
+• Stale entries, and anything marked no-cache, are revalidated with
+  If-None-Match / If-Modified-Since; a 304 refreshes the entry
+• If revalidation fails (network error or 5xx), a stale entry is served
+  for up to stale-if-error seconds, unless it says must-revalidate
+• Vary'd request headers must match the stored ones
+• Successful POST/PUT/PATCH/DELETE invalidate the URL
+
+Storage is pluggable: MemoryCache (LRU by size) and DiskCache (one file
+per entry) both implement CacheStore.
+*/
+
+package main
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 🗃️ CACHE STORE: Where serialized entries live
+type CacheStore interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte)
+	Delete(key string)
+}
+
+// Values for the X-Cache response header
+const (
+	cacheHit         = "HIT"
+	cacheMiss        = "MISS"
+	cacheRevalidated = "REVALIDATED"
+	cacheStale       = "STALE"
+)
+
+// defaultMaxCacheEntry: larger bodies are passed through uncached
+const defaultMaxCacheEntry = 1 << 20
+
+// cacheEntry is what gets serialized into a CacheStore
+type cacheEntry struct {
+	StatusCode int               `json:"status_code"`
+	Header     http.Header       `json:"header"`
+	Body       []byte            `json:"body"`
+	StoredAt   time.Time         `json:"stored_at"`
+	Vary       map[string]string `json:"vary,omitempty"` // Request header values the response varies on
+}
+
+// 🗄️ CACHE TRANSPORT
+type CacheTransport struct {
+	Base  http.RoundTripper
+	Store CacheStore
+
+	// StaleIfError is the default stale-if-error window when the
+	// response doesn't set one; zero disables it
+	StaleIfError time.Duration
+
+	// MaxEntrySize caps the body size that is cached (default 1 MiB)
+	MaxEntrySize int64
+
+	now func() time.Time // Injection point for tests
+}
+
+func NewCacheTransport(base http.RoundTripper, store CacheStore) *CacheTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &CacheTransport{
+		Base:         base,
+		Store:        store,
+		MaxEntrySize: defaultMaxCacheEntry,
+		now:          time.Now,
+	}
+}
+
+func (t *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := cacheKey(req)
+
+	if req.Method != http.MethodGet {
+		resp, err := t.Base.RoundTrip(req)
+		if err == nil && isUnsafeMethod(req.Method) && resp.StatusCode < 400 {
+			t.Store.Delete(key)
+		}
+		return resp, err
+	}
+
+	reqCC := parseCacheControl(req.Header)
+	if _, ok := reqCC["no-store"]; ok || hasConditionalHeaders(req) {
+		// Caller opted out, or is doing its own revalidation
+		return t.Base.RoundTrip(req)
+	}
+
+	entry, ok := t.load(key)
+	if ok && !entry.matchesVary(req) {
+		ok = false
+	}
+	if !ok {
+		return t.fetch(req, key, nil)
+	}
+
+	_, reqNoCache := reqCC["no-cache"]
+	if !reqNoCache && t.isFresh(entry, reqCC) {
+		return entry.response(req, cacheHit), nil
+	}
+	return t.fetch(req, key, entry)
+}
+
+// fetch goes to the network, revalidating entry when there is one
+func (t *CacheTransport) fetch(req *http.Request, key string, entry *cacheEntry) (*http.Response, error) {
+	outReq := req
+	if entry != nil {
+		outReq = req.Clone(req.Context())
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			outReq.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := entry.Header.Get("Last-Modified"); lastModified != "" {
+			outReq.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	requestTime := t.now()
+	resp, err := t.Base.RoundTrip(outReq)
+
+	if entry != nil {
+		if err != nil || resp.StatusCode >= 500 {
+			if t.canServeStale(entry) {
+				if resp != nil {
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+				}
+				return entry.response(req, cacheStale), nil
+			}
+			return resp, err
+		}
+
+		if resp.StatusCode == http.StatusNotModified {
+			resp.Body.Close()
+			entry.refresh(resp.Header, requestTime)
+			t.save(key, entry)
+			return entry.response(req, cacheRevalidated), nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !isStorable(resp) {
+		t.Store.Delete(key)
+		resp.Header.Set("X-Cache", cacheMiss)
+		return resp, nil
+	}
+	return t.store(req, key, resp, requestTime)
+}
+
+// store buffers the body and saves it, unless it is too large
+func (t *CacheTransport) store(req *http.Request, key string, resp *http.Response, requestTime time.Time) (*http.Response, error) {
+	limit := t.MaxEntrySize
+	if limit <= 0 {
+		limit = defaultMaxCacheEntry
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	resp.Header.Set("X-Cache", cacheMiss)
+
+	if int64(len(body)) > limit {
+		// Too big to cache: hand back what we read plus the rest, and
+		// drop the old entry so it isn't served in place of this one
+		t.Store.Delete(key)
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	entry := &cacheEntry{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+		StoredAt:   requestTime,
+	}
+	entry.Header.Del("X-Cache")
+	for _, name := range varyHeaders(resp.Header) {
+		if entry.Vary == nil {
+			entry.Vary = map[string]string{}
+		}
+		entry.Vary[name] = req.Header.Get(name)
+	}
+	t.save(key, entry)
+	return resp, nil
+}
+
+// ⏱️ FRESHNESS
+
+func (t *CacheTransport) isFresh(entry *cacheEntry, reqCC map[string]string) bool {
+	cc := parseCacheControl(entry.Header)
+	if _, ok := cc["no-cache"]; ok {
+		return false
+	}
+
+	lifetime := entry.freshnessLifetime(cc)
+	if maxAge, ok := directiveSeconds(reqCC, "max-age"); ok && maxAge < lifetime {
+		lifetime = maxAge
+	}
+	return entry.age(t.now()) < lifetime
+}
+
+func (t *CacheTransport) canServeStale(entry *cacheEntry) bool {
+	cc := parseCacheControl(entry.Header)
+	if _, ok := cc["must-revalidate"]; ok {
+		return false
+	}
+
+	window := t.StaleIfError
+	if seconds, ok := directiveSeconds(cc, "stale-if-error"); ok {
+		window = seconds
+	}
+	staleness := entry.age(t.now()) - entry.freshnessLifetime(cc)
+	return window > 0 && staleness <= window
+}
+
+// freshnessLifetime: max-age wins over Expires; neither means zero.
+// Expires is measured from Date, or from when we got the response
+// if the origin sent none (RFC 9110 §6.6.1)
+func (e *cacheEntry) freshnessLifetime(cc map[string]string) time.Duration {
+	if maxAge, ok := directiveSeconds(cc, "max-age"); ok {
+		return maxAge
+	}
+	if expires := e.Header.Get("Expires"); expires != "" {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			return 0 // Invalid Expires means already expired
+		}
+		date, err := http.ParseTime(e.Header.Get("Date"))
+		if err != nil {
+			date = e.StoredAt
+		}
+		return expiresAt.Sub(date)
+	}
+	return 0
+}
+
+func (e *cacheEntry) age(now time.Time) time.Duration {
+	age := now.Sub(e.StoredAt)
+	if seconds, err := strconv.Atoi(e.Header.Get("Age")); err == nil && seconds > 0 {
+		age += time.Duration(seconds) * time.Second
+	}
+	return age
+}
+
+// refresh merges a 304's headers into the entry (RFC 9111 §4.3.4)
+func (e *cacheEntry) refresh(header http.Header, requestTime time.Time) {
+	for name, values := range header {
+		switch name {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding":
+			continue
+		}
+		e.Header[name] = values
+	}
+	if header.Get("Age") == "" {
+		e.Header.Del("Age")
+	}
+	e.StoredAt = requestTime
+}
+
+func (e *cacheEntry) matchesVary(req *http.Request) bool {
+	for _, name := range varyHeaders(e.Header) {
+		if name == "*" || req.Header.Get(name) != e.Vary[name] {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *cacheEntry) response(req *http.Request, status string) *http.Response {
+	header := e.Header.Clone()
+	header.Set("X-Cache", status)
+	return &http.Response{
+		Status:        strconv.Itoa(e.StatusCode) + " " + http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+// 🧩 HELPERS
+
+func (t *CacheTransport) load(key string) (*cacheEntry, bool) {
+	data, ok := t.Store.Get(key)
+	if !ok {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		t.Store.Delete(key) // Corrupt entry; drop it
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (t *CacheTransport) save(key string, entry *cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	t.Store.Set(key, data)
+}
+
+func cacheKey(req *http.Request) string {
+	return req.URL.String()
+}
+
+func isUnsafeMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func hasConditionalHeaders(req *http.Request) bool {
+	for _, name := range []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "If-Range"} {
+		if req.Header.Get(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// isStorable: a status we understand, without no-store, and with some
+// way of deciding freshness or revalidating later
+func isStorable(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusMultipleChoices,
+		http.StatusMovedPermanently, http.StatusNotFound, http.StatusGone:
+	default:
+		return false
+	}
+
+	cc := parseCacheControl(resp.Header)
+	if _, ok := cc["no-store"]; ok {
+		return false
+	}
+	for _, name := range varyHeaders(resp.Header) {
+		if name == "*" {
+			return false
+		}
+	}
+	_, hasMaxAge := cc["max-age"]
+	_, noCache := cc["no-cache"]
+	return hasMaxAge || noCache ||
+		resp.Header.Get("Expires") != "" ||
+		resp.Header.Get("ETag") != "" ||
+		resp.Header.Get("Last-Modified") != ""
+}
+
+// parseCacheControl turns "max-age=60, no-cache" into a directive map
+func parseCacheControl(header http.Header) map[string]string {
+	directives := map[string]string{}
+	for _, line := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(line, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			name, value, _ := strings.Cut(part, "=")
+			directives[strings.ToLower(strings.TrimSpace(name))] = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return directives
+}
+
+func directiveSeconds(cc map[string]string, name string) (time.Duration, bool) {
+	value, ok := cc[name]
+	if !ok {
+		return 0, false
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0, true // Malformed means "treat as stale"
+	}
+	return time.Duration(seconds) * time.Second, true
+}
+
+func varyHeaders(header http.Header) []string {
+	var names []string
+	for _, line := range header.Values("Vary") {
+		for _, name := range strings.Split(line, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// 🧠 MEMORY CACHE: LRU bounded by total entry size
+type MemoryCache struct {
+	maxBytes int64
+
+	mu    sync.Mutex
+	size  int64
+	order *list.List // Front = most recently used
+	items map[string]*list.Element
+}
+
+type memoryItem struct {
+	key   string
+	value []byte
+}
+
+func NewMemoryCache(maxBytes int64) *MemoryCache {
+	return &MemoryCache{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*memoryItem).value, true
+}
+
+func (c *MemoryCache) Set(key string, value []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if int64(len(value)) > c.maxBytes {
+		c.remove(key)
+		return
+	}
+	if elem, ok := c.items[key]; ok {
+		item := elem.Value.(*memoryItem)
+		c.size += int64(len(value) - len(item.value))
+		item.value = value
+		c.order.MoveToFront(elem)
+	} else {
+		c.items[key] = c.order.PushFront(&memoryItem{key: key, value: value})
+		c.size += int64(len(value))
+	}
+
+	for c.size > c.maxBytes {
+		oldest := c.order.Back()
+		c.remove(oldest.Value.(*memoryItem).key)
+	}
+}
+
+func (c *MemoryCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+}
+
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *MemoryCache) remove(key string) {
+	elem, ok := c.items[key]
+	if !ok {
+		return
+	}
+	c.order.Remove(elem)
+	delete(c.items, key)
+	c.size -= int64(len(elem.Value.(*memoryItem).value))
+}
+
+// 💾 DISK CACHE: One file per entry, named by the key's SHA-256
+type DiskCache struct {
+	dir string
+}
+
+func NewDiskCache(dir string) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir}, nil
+}
+
+func (c *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+func (c *DiskCache) Get(key string) ([]byte, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// Set writes atomically so readers never see half an entry
+func (c *DiskCache) Set(key string, value []byte) {
+	writeFileAtomic(c.path(key), value)
+}
+
+func (c *DiskCache) Delete(key string) {
+	os.Remove(c.path(key))
+}
+
+// writeFileAtomic writes to a temp file in the same directory, then
+// renames it over path
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if err := errors.Join(writeErr, closeErr); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,378 @@
+/*
+=============================================================================
+                    🧪 RESPONSE CACHE TESTS
+=============================================================================
+
+Drives CacheTransport against local httptest.Servers, with a fake clock
+for freshness.
+Run with: go test -v -run Cache *.go
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type cacheFixture struct {
+	server *httptest.Server
+	client *http.Client
+	cache  *CacheTransport
+	clock  *fakeClock
+	hits   atomic.Int64
+}
+
+func newCacheFixture(t *testing.T, store CacheStore, handler http.HandlerFunc) *cacheFixture {
+	t.Helper()
+	f := &cacheFixture{clock: &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.hits.Add(1)
+		handler(w, r)
+	}))
+	t.Cleanup(f.server.Close)
+
+	if store == nil {
+		store = NewMemoryCache(1 << 20)
+	}
+	f.cache = NewCacheTransport(nil, store)
+	f.cache.now = f.clock.Now
+	f.client = &http.Client{Transport: f.cache}
+	return f
+}
+
+// get returns the body and X-Cache header for a GET
+func (f *cacheFixture) get(t *testing.T, path string, header http.Header) (string, string, int) {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, f.server.URL+path, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body), resp.Header.Get("X-Cache"), resp.StatusCode
+}
+
+func TestCacheServesFreshEntries(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers func(now time.Time) map[string]string
+	}{
+		{"max-age", func(time.Time) map[string]string {
+			return map[string]string{"Cache-Control": "max-age=60"}
+		}},
+		{"expires", func(now time.Time) map[string]string {
+			return map[string]string{
+				"Date":    now.Format(http.TimeFormat),
+				"Expires": now.Add(60 * time.Second).Format(http.TimeFormat),
+			}
+		}},
+		{"expires without date", func(now time.Time) map[string]string {
+			return map[string]string{
+				"Date":    "", // Measured from when the response arrived
+				"Expires": now.Add(60 * time.Second).Format(http.TimeFormat),
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var f *cacheFixture
+			f = newCacheFixture(t, nil, func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.headers(f.clock.Now()) {
+					if v == "" {
+						w.Header()[k] = nil // Keep net/http from adding it
+						continue
+					}
+					w.Header().Set(k, v)
+				}
+				fmt.Fprintf(w, "version %d", f.hits.Load())
+			})
+
+			if body, status, _ := f.get(t, "/posts/1", nil); body != "version 1" || status != cacheMiss {
+				t.Fatalf("first GET = %q (%s); want version 1 MISS", body, status)
+			}
+			f.clock.Advance(30 * time.Second)
+			if body, status, _ := f.get(t, "/posts/1", nil); body != "version 1" || status != cacheHit {
+				t.Errorf("fresh GET = %q (%s); want version 1 HIT", body, status)
+			}
+			f.clock.Advance(31 * time.Second)
+			if body, _, _ := f.get(t, "/posts/1", nil); body != "version 2" {
+				t.Errorf("stale GET = %q; want refetched version 2", body)
+			}
+		})
+	}
+}
+
+func TestCacheNoStore(t *testing.T) {
+	f := newCacheFixture(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store, max-age=60")
+		io.WriteString(w, "secret")
+	})
+
+	f.get(t, "/", nil)
+	f.get(t, "/", nil)
+	if got := f.hits.Load(); got != 2 {
+		t.Errorf("upstream hits = %d; want 2 for no-store", got)
+	}
+	if n := f.cache.Store.(*MemoryCache).Len(); n != 0 {
+		t.Errorf("stored %d entries; want 0", n)
+	}
+}
+
+func TestCacheRevalidates(t *testing.T) {
+	t.Run("ETag with no-cache", func(t *testing.T) {
+		f := newCacheFixture(t, nil, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			io.WriteString(w, "body v1")
+		})
+
+		f.get(t, "/", nil)
+		body, status, code := f.get(t, "/", nil)
+		if body != "body v1" || status != cacheRevalidated || code != http.StatusOK {
+			t.Errorf("second GET = %d %q (%s); want 200 cached body REVALIDATED", code, body, status)
+		}
+		if got := f.hits.Load(); got != 2 {
+			t.Errorf("upstream hits = %d; want 2 (one was a 304)", got)
+		}
+	})
+
+	t.Run("Last-Modified after max-age", func(t *testing.T) {
+		lastModified := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat)
+		var conditional atomic.Int64
+		f := newCacheFixture(t, nil, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=10")
+			w.Header().Set("Last-Modified", lastModified)
+			if r.Header.Get("If-Modified-Since") == lastModified {
+				conditional.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			io.WriteString(w, "body")
+		})
+
+		f.get(t, "/", nil)
+		f.clock.Advance(11 * time.Second)
+		if _, status, _ := f.get(t, "/", nil); status != cacheRevalidated {
+			t.Errorf("X-Cache = %s; want REVALIDATED", status)
+		}
+		// The 304 restarted the freshness clock
+		if _, status, _ := f.get(t, "/", nil); status != cacheHit {
+			t.Errorf("X-Cache = %s; want HIT after revalidation", status)
+		}
+		if got := conditional.Load(); got != 1 {
+			t.Errorf("conditional requests = %d; want 1", got)
+		}
+	})
+
+	t.Run("request no-cache forces revalidation", func(t *testing.T) {
+		f := newCacheFixture(t, nil, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			io.WriteString(w, "body")
+		})
+
+		f.get(t, "/", nil)
+		_, status, _ := f.get(t, "/", http.Header{"Cache-Control": {"no-cache"}})
+		if status != cacheRevalidated {
+			t.Errorf("X-Cache = %s; want REVALIDATED", status)
+		}
+	})
+}
+
+func TestCacheVary(t *testing.T) {
+	f := newCacheFixture(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		io.WriteString(w, "hello in "+r.Header.Get("Accept-Language"))
+	})
+
+	en := http.Header{"Accept-Language": {"en"}}
+	fr := http.Header{"Accept-Language": {"fr"}}
+
+	f.get(t, "/", en)
+	if body, status, _ := f.get(t, "/", en); status != cacheHit || body != "hello in en" {
+		t.Errorf("same Vary = %q (%s); want cached en HIT", body, status)
+	}
+	if body, status, _ := f.get(t, "/", fr); status != cacheMiss || body != "hello in fr" {
+		t.Errorf("different Vary = %q (%s); want fresh fr MISS", body, status)
+	}
+}
+
+func TestCacheStaleIfError(t *testing.T) {
+	var failing atomic.Bool
+	handler := func(cc string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if failing.Load() {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.Header().Set("Cache-Control", cc)
+			io.WriteString(w, "good copy")
+		}
+	}
+
+	t.Run("serves stale on 5xx", func(t *testing.T) {
+		failing.Store(false)
+		f := newCacheFixture(t, nil, handler("max-age=10"))
+		f.cache.StaleIfError = time.Minute
+
+		f.get(t, "/", nil)
+		failing.Store(true)
+		f.clock.Advance(30 * time.Second)
+		if body, status, code := f.get(t, "/", nil); code != http.StatusOK || status != cacheStale || body != "good copy" {
+			t.Errorf("GET = %d %q (%s); want stale good copy", code, body, status)
+		}
+
+		f.clock.Advance(time.Minute) // Now beyond the stale window
+		if _, _, code := f.get(t, "/", nil); code != http.StatusBadGateway {
+			t.Errorf("status = %d; want 502 once too stale", code)
+		}
+	})
+
+	t.Run("serves stale on network error", func(t *testing.T) {
+		failing.Store(false)
+		f := newCacheFixture(t, nil, handler("max-age=10, stale-if-error=300"))
+
+		f.get(t, "/", nil)
+		f.server.Close()
+		f.clock.Advance(time.Minute)
+		if body, status, _ := f.get(t, "/", nil); status != cacheStale || body != "good copy" {
+			t.Errorf("GET = %q (%s); want stale good copy", body, status)
+		}
+	})
+
+	t.Run("must-revalidate forbids stale", func(t *testing.T) {
+		failing.Store(false)
+		f := newCacheFixture(t, nil, handler("max-age=10, must-revalidate"))
+		f.cache.StaleIfError = time.Hour
+
+		f.get(t, "/", nil)
+		failing.Store(true)
+		f.clock.Advance(30 * time.Second)
+		if _, _, code := f.get(t, "/", nil); code != http.StatusBadGateway {
+			t.Errorf("status = %d; want 502 for must-revalidate", code)
+		}
+	})
+}
+
+func TestCacheDropsEntryReplacedByOversizedBody(t *testing.T) {
+	var size atomic.Int64
+	size.Store(10)
+	f := newCacheFixture(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60, stale-if-error=3600")
+		if size.Load() == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		io.WriteString(w, strings.Repeat("x", int(size.Load())))
+	})
+	f.cache.MaxEntrySize = 100
+
+	f.get(t, "/report", nil)
+	f.clock.Advance(time.Minute + time.Second)
+	size.Store(200)
+	if body, status, _ := f.get(t, "/report", nil); len(body) != 200 || status != cacheMiss {
+		t.Fatalf("oversized GET = %d bytes (%s); want 200 MISS", len(body), status)
+	}
+	if n := f.cache.Store.(*MemoryCache).Len(); n != 0 {
+		t.Errorf("stored %d entries; the superseded one should be gone", n)
+	}
+
+	// The old 10-byte body must not come back as a stale fallback
+	size.Store(0)
+	if body, status, code := f.get(t, "/report", nil); code != http.StatusInternalServerError {
+		t.Errorf("GET after upstream failure = %d %q (%s); want the 500", code, body, status)
+	}
+}
+
+func TestCacheInvalidatesOnUnsafeMethods(t *testing.T) {
+	f := newCacheFixture(t, nil, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		io.WriteString(w, r.Method)
+	})
+
+	f.get(t, "/posts/1", nil)
+	req, _ := http.NewRequestWithContext(context.Background(), http.MethodPut, f.server.URL+"/posts/1", strings.NewReader("{}"))
+	resp, err := f.client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if _, status, _ := f.get(t, "/posts/1", nil); status != cacheMiss {
+		t.Errorf("X-Cache after PUT = %s; want MISS", status)
+	}
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewMemoryCache(10)
+	cache.Set("a", []byte("aaaa"))
+	cache.Set("b", []byte("bbbb"))
+	cache.Get("a") // a is now most recent
+	cache.Set("c", []byte("cccc"))
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("%s should still be cached", key)
+		}
+	}
+
+	cache.Set("huge", make([]byte, 11))
+	if _, ok := cache.Get("huge"); ok {
+		t.Error("entries larger than the cache should not be stored")
+	}
+}
+
+func TestDiskCacheSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=60")
+		io.WriteString(w, "persisted")
+	}
+
+	store, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := newCacheFixture(t, store, handler)
+	f.get(t, "/", nil)
+
+	// A fresh transport over the same directory sees the entry
+	reopened, err := NewDiskCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.cache.Store = reopened
+	if body, status, _ := f.get(t, "/", nil); status != cacheHit || body != "persisted" {
+		t.Errorf("GET = %q (%s); want persisted HIT", body, status)
+	}
+
+	reopened.Delete(f.server.URL + "/")
+	if _, ok := reopened.Get(f.server.URL + "/"); ok {
+		t.Error("Delete should remove the entry file")
+	}
+}
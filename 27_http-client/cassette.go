@@ -0,0 +1,393 @@
+/*
+=============================================================================
+                    📼 RECORD / REPLAY - HTTP CLIENT EXTENSION
+=============================================================================
+
+Records real HTTP interactions to a JSON "cassette" and replays them later,
+so code that talks to jsonplaceholder.typicode.com can be tested offline:
+
+  rec, _ := NewRecorder("testdata/posts.json", ModeAuto)
+  defer rec.Stop() // Writes the cassette when recording
+  client, _ := NewAPIClient(baseURL, WithTransport(rec))
+
+Modes:
+• ModeRecord  - always hit the network and (re)write the cassette
+• ModeReplay  - only serve from the cassette
+• ModeAuto    - replay if the cassette exists, otherwise record
+
+Requests are matched on method and URL by default; add MatchBody or
+MatchHeaders(...) for stricter matching. Each recorded interaction is
+used once, in order, so repeated calls can get different answers.
+A replayed request with nothing left to match fails with
+ErrNoInteraction; set Passthrough to send it to the real network instead.
+
+Secrets never reach the file: RedactHeaders (Authorization, Cookie...)
+and RedactBody (e.g. RedactJSONFields("password", "token")) are applied
+before saving and before matching.
+
+Run the demos offline with:
+
+  go run $(ls *.go | grep -v _test.go) -cassette testdata/demo.json
+
+The first run records; add -record to refresh it. httpWorkerPool in
+41_worker-pools only simulates its httpbin calls, so it has nothing to
+record; a real client there would wrap its transport the same way.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ErrNoInteraction is returned for unmatched requests during replay
+var ErrNoInteraction = errors.New("no matching interaction in cassette")
+
+const redacted = "[REDACTED]"
+
+// 📼 CASSETTE MODES
+type CassetteMode int
+
+const (
+	ModeAuto CassetteMode = iota
+	ModeRecord
+	ModeReplay
+)
+
+func (m CassetteMode) String() string {
+	switch m {
+	case ModeAuto:
+		return "auto"
+	case ModeRecord:
+		return "record"
+	case ModeReplay:
+		return "replay"
+	default:
+		return fmt.Sprintf("CassetteMode(%d)", int(m))
+	}
+}
+
+// 📄 CASSETTE FORMAT
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method string       `json:"method"`
+	URL    string       `json:"url"`
+	Header http.Header  `json:"header,omitempty"`
+	Body   RecordedBody `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int          `json:"status_code"`
+	Header     http.Header  `json:"header,omitempty"`
+	Body       RecordedBody `json:"body,omitempty"`
+}
+
+// RecordedBody is stored as text when it is UTF-8, base64 otherwise
+type RecordedBody []byte
+
+func (b RecordedBody) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+func (b *RecordedBody) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*b = RecordedBody(text)
+		return nil
+	}
+	var encoded struct {
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded.Base64)
+	*b = decoded
+	return err
+}
+
+// 🎯 MATCHERS: Compare a live request with a recorded one
+type Matcher func(live, recorded RecordedRequest) bool
+
+func MatchMethod(live, recorded RecordedRequest) bool {
+	return live.Method == recorded.Method
+}
+
+func MatchURL(live, recorded RecordedRequest) bool {
+	return live.URL == recorded.URL
+}
+
+// MatchBody compares bodies, ignoring formatting differences in JSON
+func MatchBody(live, recorded RecordedRequest) bool {
+	if bytes.Equal(live.Body, recorded.Body) {
+		return true
+	}
+	var a, b interface{}
+	if json.Unmarshal(live.Body, &a) != nil || json.Unmarshal(recorded.Body, &b) != nil {
+		return false
+	}
+	normA, _ := json.Marshal(a)
+	normB, _ := json.Marshal(b)
+	return bytes.Equal(normA, normB)
+}
+
+func MatchHeaders(names ...string) Matcher {
+	return func(live, recorded RecordedRequest) bool {
+		for _, name := range names {
+			if !slices.Equal(live.Header.Values(name), recorded.Header.Values(name)) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// 🙈 REDACTION
+
+var defaultRedactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-API-Key", "Proxy-Authorization"}
+
+// RedactJSONFields blanks the named fields anywhere in a JSON body
+func RedactJSONFields(fields ...string) func([]byte) []byte {
+	return func(body []byte) []byte {
+		var doc interface{}
+		if json.Unmarshal(body, &doc) != nil {
+			return body // Not JSON; leave it alone
+		}
+		out, err := json.Marshal(redactJSON(doc, fields))
+		if err != nil {
+			return body
+		}
+		return out
+	}
+}
+
+func redactJSON(v interface{}, fields []string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if slices.ContainsFunc(fields, func(f string) bool { return strings.EqualFold(f, key) }) {
+				v[key] = redacted
+			} else {
+				v[key] = redactJSON(value, fields)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactJSON(v[i], fields)
+		}
+	}
+	return v
+}
+
+// 📼 RECORDER
+type Recorder struct {
+	Base          http.RoundTripper
+	Matchers      []Matcher           // Default: method and URL
+	Passthrough   bool                // Send replay misses to Base instead of failing
+	RedactHeaders []string            // Default: Authorization, Cookie, Set-Cookie, X-API-Key...
+	RedactBody    func([]byte) []byte // Applied to request and response bodies
+
+	path      string
+	recording bool
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+func NewRecorder(path string, mode CassetteMode) (*Recorder, error) {
+	r := &Recorder{
+		Base:          http.DefaultTransport,
+		Matchers:      []Matcher{MatchMethod, MatchURL},
+		RedactHeaders: defaultRedactedHeaders,
+		path:          path,
+	}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case mode == ModeRecord:
+		r.recording = true
+	case err == nil:
+		if err := json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("load cassette %s: %w", path, err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	case errors.Is(err, os.ErrNotExist) && mode == ModeAuto:
+		r.recording = true
+	default:
+		return nil, fmt.Errorf("load cassette %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Recording reports whether this recorder talks to the network
+func (r *Recorder) Recording() bool {
+	return r.recording
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	live := r.recordRequest(req, body)
+
+	if !r.recording {
+		if interaction, ok := r.match(live); ok {
+			return interaction.Response.toResponse(req), nil
+		}
+		if !r.Passthrough {
+			return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, req.URL)
+		}
+	}
+
+	// Record mode, or a replay miss with Passthrough: go to the network
+	out := req.Clone(req.Context())
+	if body != nil {
+		out.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	resp, err := r.Base.RoundTrip(out)
+	if err != nil || !r.recording {
+		return resp, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request: live,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     r.redactHeader(resp.Header),
+			Body:       r.redactBody(respBody),
+		},
+	})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Stop writes the cassette when recording; replaying is a no-op
+func (r *Recorder) Stop() error {
+	if !r.recording {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(r.path, append(data, '\n'))
+}
+
+// match picks the first unused matching interaction; played ones are never
+// served again, so a cassette replays exactly what was recorded
+func (r *Recorder) match(live RecordedRequest) (Interaction, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if !r.used[i] && r.matches(live, interaction.Request) {
+			r.used[i] = true
+			return interaction, true
+		}
+	}
+	return Interaction{}, false
+}
+
+func (r *Recorder) matches(live, recorded RecordedRequest) bool {
+	for _, m := range r.Matchers {
+		if !m(live, recorded) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Recorder) recordRequest(req *http.Request, body []byte) RecordedRequest {
+	return RecordedRequest{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: r.redactHeader(req.Header),
+		Body:   r.redactBody(body),
+	}
+}
+
+func (r *Recorder) redactHeader(h http.Header) http.Header {
+	clone := h.Clone()
+	for _, name := range r.RedactHeaders {
+		if values := clone.Values(name); len(values) > 0 {
+			clone.Set(name, redacted)
+		}
+	}
+	return clone
+}
+
+func (r *Recorder) redactBody(body []byte) RecordedBody {
+	if r.RedactBody == nil || len(body) == 0 {
+		return body
+	}
+	return r.RedactBody(body)
+}
+
+func (rr RecordedResponse) toResponse(req *http.Request) *http.Response {
+	header := rr.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	// Redaction may have changed the body's length
+	header.Set("Content-Length", strconv.Itoa(len(rr.Body)))
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rr.StatusCode, http.StatusText(rr.StatusCode)),
+		StatusCode:    rr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(rr.Body)),
+		ContentLength: int64(len(rr.Body)),
+		Request:       req,
+	}
+}
+
+// readRequestBody consumes and closes the body, as a RoundTripper must
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	defer req.Body.Close()
+	return io.ReadAll(req.Body)
+}
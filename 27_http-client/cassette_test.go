@@ -0,0 +1,274 @@
+/*
+=============================================================================
+                    🧪 RECORD / REPLAY TESTS
+=============================================================================
+
+Records against a local httptest.Server, shuts it down, then replays.
+Run with: go test -v -run 'Recorder|Cassette' *.go
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// newCounterServer answers every request with its sequence number
+func newCounterServer(t *testing.T) (*httptest.Server, *atomic.Int64) {
+	t.Helper()
+	var calls atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=server-secret")
+		fmt.Fprintf(w, `{"call": %d, "method": %q, "token": "tok-123"}`, n, r.Method)
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func doRequest(t *testing.T, rt http.RoundTripper, method, url, body string, header http.Header) (string, error) {
+	t.Helper()
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	req, _ := http.NewRequestWithContext(context.Background(), method, url, reader)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := (&http.Client{Transport: rt}).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return string(data), nil
+}
+
+func TestRecorderRecordsThenReplaysOffline(t *testing.T) {
+	server, calls := newCounterServer(t)
+	path := filepath.Join(t.TempDir(), "cassettes", "counter.json")
+
+	rec, err := NewRecorder(path, ModeAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rec.Recording() {
+		t.Fatal("ModeAuto without a cassette should record")
+	}
+	first, _ := doRequest(t, rec, http.MethodGet, server.URL+"/posts/1", "", nil)
+	second, _ := doRequest(t, rec, http.MethodGet, server.URL+"/posts/1", "", nil)
+	if err := rec.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	url := server.URL
+	server.Close() // From here on, the network is gone
+
+	replay, err := NewRecorder(path, ModeAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Recording() {
+		t.Fatal("ModeAuto with a cassette should replay")
+	}
+
+	// Same request twice gets the two recorded answers, in order
+	for i, want := range []string{first, second} {
+		got, err := doRequest(t, replay, http.MethodGet, url+"/posts/1", "", nil)
+		if err != nil {
+			t.Fatalf("replay %d: %v", i+1, err)
+		}
+		if got != want {
+			t.Errorf("replay %d = %s; want %s", i+1, got, want)
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("server calls = %d; want 2 (replay never hits the network)", got)
+	}
+
+	// Replay refuses to reuse or invent interactions
+	if _, err := doRequest(t, replay, http.MethodGet, url+"/posts/1", "", nil); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("third replay err = %v; want ErrNoInteraction", err)
+	}
+	if _, err := doRequest(t, replay, http.MethodGet, url+"/posts/2", "", nil); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("unknown URL err = %v; want ErrNoInteraction", err)
+	}
+}
+
+func TestRecorderRedactsSecrets(t *testing.T) {
+	server, _ := newCounterServer(t)
+	path := filepath.Join(t.TempDir(), "secrets.json")
+
+	rec, err := NewRecorder(path, ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.RedactBody = RedactJSONFields("password", "token")
+
+	header := http.Header{"Authorization": {"Bearer live-token"}, "X-Trace": {"abc"}}
+	live, _ := doRequest(t, rec, http.MethodPost, server.URL+"/login", `{"user": "ana", "password": "hunter2"}`, header)
+	if !strings.Contains(live, "tok-123") {
+		t.Errorf("caller should see the real response, got %s", live)
+	}
+	rec.Stop()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cassette := string(data)
+	for _, secret := range []string{"live-token", "hunter2", "tok-123", "server-secret"} {
+		if strings.Contains(cassette, secret) {
+			t.Errorf("cassette leaks %q:\n%s", secret, cassette)
+		}
+	}
+	if !strings.Contains(cassette, "abc") || !strings.Contains(cassette, "ana") {
+		t.Errorf("cassette should keep non-secret values:\n%s", cassette)
+	}
+
+	// Matching sees the same redaction, so a different password still matches
+	replay, _ := NewRecorder(path, ModeReplay)
+	replay.RedactBody = RedactJSONFields("password", "token")
+	replay.Matchers = append(replay.Matchers, MatchBody)
+	if _, err := doRequest(t, replay, http.MethodPost, server.URL+"/login", `{"password": "other", "user": "ana"}`, nil); err != nil {
+		t.Errorf("replay with redacted field: %v", err)
+	}
+}
+
+func TestRecorderMatchers(t *testing.T) {
+	server, _ := newCounterServer(t)
+	path := filepath.Join(t.TempDir(), "matchers.json")
+
+	rec, _ := NewRecorder(path, ModeRecord)
+	doRequest(t, rec, http.MethodPost, server.URL+"/items", `{"name": "a"}`, http.Header{"X-Tenant": {"blue"}})
+	doRequest(t, rec, http.MethodPost, server.URL+"/items", `{"name": "b"}`, http.Header{"X-Tenant": {"green"}})
+	rec.Stop()
+
+	tests := []struct {
+		name     string
+		matchers []Matcher
+		body     string
+		tenant   string
+		want     string // Substring of the replayed response, "" for no match
+	}{
+		{"body picks the second", []Matcher{MatchMethod, MatchURL, MatchBody}, `{ "name" : "b" }`, "blue", `"call": 2`},
+		{"header picks the second", []Matcher{MatchMethod, MatchURL, MatchHeaders("X-Tenant")}, `{}`, "green", `"call": 2`},
+		{"unknown body", []Matcher{MatchMethod, MatchURL, MatchBody}, `{"name": "c"}`, "blue", ""},
+		{"method mismatch", []Matcher{MatchMethod, MatchURL}, "", "blue", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replay, err := NewRecorder(path, ModeReplay)
+			if err != nil {
+				t.Fatal(err)
+			}
+			replay.Matchers = tt.matchers
+
+			method := http.MethodPost
+			if tt.name == "method mismatch" {
+				method = http.MethodPut
+			}
+			got, err := doRequest(t, replay, method, server.URL+"/items", tt.body, http.Header{"X-Tenant": {tt.tenant}})
+			if tt.want == "" {
+				if !errors.Is(err, ErrNoInteraction) {
+					t.Errorf("err = %v; want ErrNoInteraction", err)
+				}
+				return
+			}
+			if err != nil || !strings.Contains(got, tt.want) {
+				t.Errorf("got %s, %v; want response containing %s", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecorderReplayIsStrictByDefault(t *testing.T) {
+	server, calls := newCounterServer(t)
+	path := filepath.Join(t.TempDir(), "partial.json")
+
+	rec, _ := NewRecorder(path, ModeRecord)
+	doRequest(t, rec, http.MethodGet, server.URL+"/known", "", nil)
+	rec.Stop()
+
+	replay, _ := NewRecorder(path, ModeAuto)
+	if got, err := doRequest(t, replay, http.MethodGet, server.URL+"/known", "", nil); err != nil || !strings.Contains(got, `"call": 1`) {
+		t.Fatalf("recorded request = %s, %v", got, err)
+	}
+	for _, path := range []string{"/known", "/unknown"} {
+		if _, err := doRequest(t, replay, http.MethodGet, server.URL+path, "", nil); !errors.Is(err, ErrNoInteraction) {
+			t.Errorf("GET %s = %v; want ErrNoInteraction", path, err)
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("server calls = %d; want only the recording's", n)
+	}
+}
+
+func TestRecorderPassthrough(t *testing.T) {
+	server, calls := newCounterServer(t)
+	path := filepath.Join(t.TempDir(), "partial.json")
+
+	rec, _ := NewRecorder(path, ModeRecord)
+	doRequest(t, rec, http.MethodGet, server.URL+"/known", "", nil)
+	rec.Stop()
+
+	replay, _ := NewRecorder(path, ModeReplay)
+	replay.Passthrough = true
+	doRequest(t, replay, http.MethodGet, server.URL+"/known", "", nil)
+	got, err := doRequest(t, replay, http.MethodGet, server.URL+"/known", "", nil) // Already played
+	if err != nil || !strings.Contains(got, `"call": 2`) {
+		t.Errorf("replayed-out request = %s, %v; want a live response, not a reused one", got, err)
+	}
+	got, err = doRequest(t, replay, http.MethodGet, server.URL+"/unknown", "", nil)
+	if err != nil || !strings.Contains(got, `"call": 3`) {
+		t.Errorf("unmatched request = %s, %v; want a live response", got, err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("server calls = %d; want 3 (one recorded, two passthrough)", n)
+	}
+	if data, _ := os.ReadFile(path); strings.Count(string(data), `"method"`) != 1 {
+		t.Errorf("passthrough responses were added to the cassette:\n%s", data)
+	}
+}
+
+func TestCassetteBinaryBodies(t *testing.T) {
+	binary := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff, 0xfe}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(binary)
+	}))
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "binary.json")
+
+	rec, _ := NewRecorder(path, ModeRecord)
+	doRequest(t, rec, http.MethodGet, server.URL+"/logo.png", "", nil)
+	rec.Stop()
+
+	replay, _ := NewRecorder(path, ModeReplay)
+	got, err := doRequest(t, replay, http.MethodGet, server.URL+"/logo.png", "", nil)
+	if err != nil || got != string(binary) {
+		t.Errorf("replayed %q, %v; want the original bytes", got, err)
+	}
+	if data, _ := os.ReadFile(path); !strings.Contains(string(data), `"base64"`) {
+		t.Errorf("binary body should be stored as base64:\n%s", data)
+	}
+}
+
+func TestRecorderReplayRequiresCassette(t *testing.T) {
+	_, err := NewRecorder(filepath.Join(t.TempDir(), "missing.json"), ModeReplay)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("err = %v; want os.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,519 @@
+/*
+=============================================================================
+                    🍪 PERSISTENT COOKIE JAR - HTTP CLIENT EXTENSION
+=============================================================================
+
+An http.CookieJar that survives restarts, so a CLI can log in once and
+reuse the session on every later run:
+
+  jar, _ := NewPersistentJar(".cookies.json", nil)
+  client, _ := NewAPIClient(baseURL, WithCookieJar(jar))
+
+  reused, err := client.EnsureSession(ctx, SessionLogin{
+      LoginPath:   "/login",
+      Credentials: map[string]string{"user": "ana", "password": pw},
+      CheckPath:   "/me",
+  })
+
+Follows the RFC 6265 storage model:
+• Host-only vs domain cookies, with public suffixes (co.uk, github.io...)
+  refused as cookie domains so one site can't set cookies for all others
+• Path and Secure matching; Max-Age/Expires, with expired cookies pruned
+• Session cookies (no expiry) are kept too: for a CLI, "the session"
+  spans runs, not one browser window
+
+Several processes can share one file: every change takes a lock file,
+re-reads the jar, applies the change and atomically replaces the file;
+readers pick up other processes' changes when the file's mtime changes.
+Changes apply in memory first. If the file can't be locked or written they
+stay pending, and the next SetCookies (or an explicit Save) writes them.
+
+💡 The built-in public suffix list is a small subset. In a module, pass
+publicsuffix.List from golang.org/x/net/publicsuffix instead.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	jarLockTimeout = 5 * time.Second
+	jarLockStale   = 30 * time.Second // Lock files older than this were left by a crash
+)
+
+// ErrJarLocked means another process held the jar's lock for too long
+var ErrJarLocked = errors.New("cookie jar is locked")
+
+// 🌐 PUBLIC SUFFIXES
+type builtinSuffixList struct{}
+
+// BuiltinPublicSuffixes knows TLDs plus a handful of common multi-label
+// suffixes; anything else falls back to the last label
+var BuiltinPublicSuffixes cookiejar.PublicSuffixList = builtinSuffixList{}
+
+var knownPublicSuffixes = map[string]bool{
+	"co.uk": true, "org.uk": true, "ac.uk": true, "gov.uk": true,
+	"com.au": true, "net.au": true, "org.au": true,
+	"co.jp": true, "co.nz": true, "com.br": true, "co.in": true,
+	"github.io": true, "gitlab.io": true, "herokuapp.com": true,
+	"appspot.com": true, "blogspot.com": true, "netlify.app": true,
+	"vercel.app": true, "pages.dev": true, "cloudfront.net": true,
+}
+
+func (builtinSuffixList) PublicSuffix(domain string) string {
+	// Longest known suffix wins: check "a.b.c", then "b.c", then "c"
+	for i := 0; i < len(domain); i++ {
+		if i == 0 || domain[i-1] == '.' {
+			if knownPublicSuffixes[domain[i:]] {
+				return domain[i:]
+			}
+		}
+	}
+	if i := strings.LastIndexByte(domain, '.'); i >= 0 {
+		return domain[i+1:]
+	}
+	return domain
+}
+
+func (builtinSuffixList) String() string { return "builtin public suffix subset" }
+
+// 🍪 STORED COOKIE: The on-disk format
+type storedCookie struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	HostOnly bool      `json:"host_only,omitempty"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"http_only,omitempty"`
+	Expires  time.Time `json:"expires,omitzero"` // Zero for session cookies
+	Created  time.Time `json:"created"`
+}
+
+func (c storedCookie) key() string {
+	return c.Domain + ";" + c.Path + ";" + c.Name
+}
+
+func (c storedCookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !now.Before(c.Expires)
+}
+
+type jarFile struct {
+	Cookies []storedCookie `json:"cookies"`
+}
+
+// 🫙 PERSISTENT JAR
+type PersistentJar struct {
+	path string
+	psl  cookiejar.PublicSuffixList
+	now  func() time.Time
+
+	mu       sync.Mutex
+	entries  map[string]storedCookie
+	pending  []jarChange // Applied to entries, not saved yet
+	modTime  time.Time   // Of the file version in entries
+	fileSize int64
+	err      error
+}
+
+// NewPersistentJar loads path if it exists; psl defaults to BuiltinPublicSuffixes
+func NewPersistentJar(path string, psl cookiejar.PublicSuffixList) (*PersistentJar, error) {
+	if psl == nil {
+		psl = BuiltinPublicSuffixes
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	j := &PersistentJar{
+		path:    path,
+		psl:     psl,
+		now:     time.Now,
+		entries: make(map[string]storedCookie),
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.reload(true); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Err returns the error from the last failed save or reload; the CookieJar
+// interface has no way to report them. It is only cleared by a save that
+// writes every pending change.
+func (j *PersistentJar) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// Save writes changes that earlier SetCookies calls couldn't save
+func (j *PersistentJar) Save() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.save()
+}
+
+// jarChange is one cookie to store, or to delete when remove is set
+type jarChange struct {
+	cookie storedCookie
+	remove bool
+}
+
+func (j *PersistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return
+	}
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return
+	}
+
+	now := j.now()
+	var changes []jarChange
+	for _, c := range cookies {
+		if change, ok := j.newChange(c, u, host, now); ok {
+			changes = append(changes, change)
+		}
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.pending = append(j.pending, changes...)
+	j.applyPending() // Visible right away, even if saving fails
+	j.save()
+}
+
+// save writes pending changes; on failure they stay pending for the next
+// try. j.mu must be held.
+func (j *PersistentJar) save() error {
+	if len(j.pending) == 0 {
+		return nil
+	}
+	if err := j.update(); err != nil {
+		j.err = err
+		return err
+	}
+	j.pending = nil
+	j.err = nil
+	return nil
+}
+
+// applyPending replays unsaved changes onto entries; j.mu must be held
+func (j *PersistentJar) applyPending() {
+	for _, change := range j.pending {
+		key := change.cookie.key()
+		if change.remove {
+			delete(j.entries, key)
+			continue
+		}
+		if old, ok := j.entries[key]; ok {
+			change.cookie.Created = old.Created // Keeps the send order stable
+		}
+		j.entries[key] = change.cookie
+	}
+}
+
+// newChange applies RFC 6265 section 5.3 to one Set-Cookie
+func (j *PersistentJar) newChange(c *http.Cookie, u *url.URL, host string, now time.Time) (jarChange, bool) {
+	if c.Name == "" || (c.Secure && u.Scheme != "https") {
+		return jarChange{}, false
+	}
+
+	domain := strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+	hostOnly := domain == ""
+	switch {
+	case hostOnly:
+		domain = host
+	case net.ParseIP(host) != nil:
+		// IP hosts only get host-only cookies
+		if domain != host {
+			return jarChange{}, false
+		}
+		hostOnly = true
+	default:
+		if j.psl.PublicSuffix(domain) == domain {
+			// "Domain=co.uk" would reach every site under co.uk
+			if domain != host {
+				return jarChange{}, false
+			}
+			hostOnly = true
+		}
+		if !domainMatch(host, domain) {
+			return jarChange{}, false
+		}
+	}
+
+	path := c.Path
+	if path == "" || path[0] != '/' {
+		path = defaultCookiePath(u.Path)
+	}
+
+	stored := storedCookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   domain,
+		Path:     path,
+		HostOnly: hostOnly,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+		Created:  now,
+	}
+	switch {
+	case c.MaxAge < 0:
+		return jarChange{cookie: stored, remove: true}, true
+	case c.MaxAge > 0:
+		stored.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+	case !c.Expires.IsZero():
+		if !c.Expires.After(now) {
+			return jarChange{cookie: stored, remove: true}, true
+		}
+		stored.Expires = c.Expires
+	}
+	return jarChange{cookie: stored}, true
+}
+
+func (j *PersistentJar) Cookies(u *url.URL) []*http.Cookie {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+	host, err := canonicalHost(u.Host)
+	if err != nil {
+		return nil
+	}
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+
+	j.mu.Lock()
+	if err := j.reload(false); err != nil {
+		j.err = err
+	}
+	now := j.now()
+	var selected []storedCookie
+	for _, c := range j.entries {
+		switch {
+		case c.expired(now):
+		case c.Secure && u.Scheme != "https":
+		case c.HostOnly && host != c.Domain:
+		case !c.HostOnly && !domainMatch(host, c.Domain):
+		case !pathMatch(path, c.Path):
+		default:
+			selected = append(selected, c)
+		}
+	}
+	j.mu.Unlock()
+
+	// Longer paths first, then oldest first (RFC 6265 section 5.4)
+	sort.Slice(selected, func(a, b int) bool {
+		if len(selected[a].Path) != len(selected[b].Path) {
+			return len(selected[a].Path) > len(selected[b].Path)
+		}
+		return selected[a].Created.Before(selected[b].Created)
+	})
+	cookies := make([]*http.Cookie, len(selected))
+	for i, c := range selected {
+		cookies[i] = &http.Cookie{Name: c.Name, Value: c.Value}
+	}
+	return cookies
+}
+
+// update runs a read-modify-write cycle under the file lock; j.mu must be held
+func (j *PersistentJar) update() error {
+	unlock, err := lockJarFile(j.path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Start from what other processes may have written meanwhile; reload
+	// puts the pending changes back on top
+	if err := j.reload(true); err != nil {
+		return err
+	}
+
+	now := j.now()
+	file := jarFile{Cookies: make([]storedCookie, 0, len(j.entries))}
+	for key, c := range j.entries {
+		if c.expired(now) {
+			delete(j.entries, key)
+			continue
+		}
+		file.Cookies = append(file.Cookies, c)
+	}
+	sort.Slice(file.Cookies, func(a, b int) bool { return file.Cookies[a].key() < file.Cookies[b].key() })
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(j.path, append(data, '\n')); err != nil {
+		return err
+	}
+	return j.recordStat()
+}
+
+// reload re-reads the file if it changed since we last saw it (or always,
+// with force), keeping unsaved changes; j.mu must be held
+func (j *PersistentJar) reload(force bool) error {
+	info, err := os.Stat(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil // Nothing saved yet
+	}
+	if err != nil {
+		return err
+	}
+	if !force && info.ModTime().Equal(j.modTime) && info.Size() == j.fileSize {
+		return nil
+	}
+
+	data, err := os.ReadFile(j.path)
+	if err != nil {
+		return err
+	}
+	var file jarFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("cookie jar %s: %w", j.path, err)
+	}
+	now := j.now()
+	j.entries = make(map[string]storedCookie, len(file.Cookies))
+	for _, c := range file.Cookies {
+		if !c.expired(now) {
+			j.entries[c.key()] = c
+		}
+	}
+	j.applyPending()
+	j.modTime, j.fileSize = info.ModTime(), info.Size()
+	return nil
+}
+
+func (j *PersistentJar) recordStat() error {
+	info, err := os.Stat(j.path)
+	if err != nil {
+		return err
+	}
+	j.modTime, j.fileSize = info.ModTime(), info.Size()
+	return nil
+}
+
+// lockJarFile creates path.lock exclusively, waiting for other holders.
+// A lock file works everywhere without build tags (flock is Unix-only).
+func lockJarFile(path string) (unlock func(), err error) {
+	lock := path + ".lock"
+	deadline := time.Now().Add(jarLockTimeout)
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		if info, statErr := os.Stat(lock); statErr == nil && time.Since(info.ModTime()) > jarLockStale {
+			os.Remove(lock) // Left behind by a crashed process
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: %s", ErrJarLocked, lock)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// 🧭 MATCHING HELPERS
+
+func canonicalHost(host string) (string, error) {
+	if strings.Contains(host, ":") {
+		h, _, err := net.SplitHostPort(host)
+		if err != nil {
+			return "", err
+		}
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), "."), nil
+}
+
+func domainMatch(host, domain string) bool {
+	return host == domain || (strings.HasSuffix(host, "."+domain) && net.ParseIP(host) == nil)
+}
+
+func pathMatch(requestPath, cookiePath string) bool {
+	if requestPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
+
+// defaultCookiePath is the request path up to its last slash
+func defaultCookiePath(p string) string {
+	i := strings.LastIndexByte(p, '/')
+	if i <= 0 {
+		return "/"
+	}
+	return p[:i]
+}
+
+// 🔑 SESSION LOGIN
+type SessionLogin struct {
+	LoginPath   string      // Credentials are POSTed here as JSON
+	Credentials interface{} // e.g. map[string]string{"user": ..., "password": ...}
+	CheckPath   string      // A 2xx here means the stored session still works
+}
+
+// EnsureSession reuses the session cookie from the jar when CheckPath
+// accepts it, and logs in otherwise. reused reports which happened.
+func (c *APIClient) EnsureSession(ctx context.Context, login SessionLogin) (reused bool, err error) {
+	jar := c.HTTPClient.Jar
+	if jar == nil {
+		return false, errors.New("EnsureSession needs a cookie jar (see WithCookieJar)")
+	}
+
+	check, err := c.NewRequest(ctx, http.MethodGet, login.CheckPath, nil, nil)
+	if err != nil {
+		return false, err
+	}
+	if len(jar.Cookies(check.URL)) > 0 {
+		_, err := c.Do(check, nil)
+		if err == nil {
+			return true, nil
+		}
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || (apiErr.StatusCode != http.StatusUnauthorized && apiErr.StatusCode != http.StatusForbidden) {
+			return false, fmt.Errorf("check session: %w", err)
+		}
+		// Expired or revoked server-side: fall through and log in again
+	}
+
+	req, err := c.NewRequest(ctx, http.MethodPost, login.LoginPath, nil, login.Credentials)
+	if err != nil {
+		return false, err
+	}
+	if _, err := c.Do(req, nil); err != nil {
+		return false, fmt.Errorf("login: %w", err)
+	}
+	return false, nil
+}
@@ -0,0 +1,331 @@
+/*
+=============================================================================
+                    🧪 COOKIE JAR TESTS
+=============================================================================
+
+Domain, path and expiry rules are tested directly against the jar; the
+session tests log in against a local server whose sessions are signed
+cookies, so a second jar instance (a "second run") can reuse them.
+Run with: go test -v -run 'Jar|Session' *.go
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestJar(t *testing.T, path string) *PersistentJar {
+	t.Helper()
+	jar, err := NewPersistentJar(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jar
+}
+
+func mustURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func cookieNames(cookies []*http.Cookie) string {
+	var names []string
+	for _, c := range cookies {
+		names = append(names, c.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestPersistentJarDomainRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		setURL string
+		cookie http.Cookie
+		getURL string
+		want   bool
+	}{
+		{"host-only stays on its host", "https://example.com/", http.Cookie{Name: "c", Value: "1"}, "https://api.example.com/", false},
+		{"host-only on same host", "https://example.com/", http.Cookie{Name: "c", Value: "1"}, "https://example.com/x", true},
+		{"domain cookie reaches subdomains", "https://www.example.co.uk/", http.Cookie{Name: "c", Value: "1", Domain: ".example.co.uk"}, "https://api.example.co.uk/", true},
+		{"public suffix domain refused", "https://www.example.co.uk/", http.Cookie{Name: "c", Value: "1", Domain: "co.uk"}, "https://other.co.uk/", false},
+		{"hosting suffix refused", "https://alice.github.io/", http.Cookie{Name: "c", Value: "1", Domain: "github.io"}, "https://mallory.github.io/", false},
+		{"TLD refused", "https://example.com/", http.Cookie{Name: "c", Value: "1", Domain: "com"}, "https://example.com/", false},
+		{"foreign domain refused", "https://example.com/", http.Cookie{Name: "c", Value: "1", Domain: "evil.com"}, "https://evil.com/", false},
+		{"IP hosts are host-only", "http://127.0.0.1:8080/", http.Cookie{Name: "c", Value: "1"}, "http://127.0.0.1:9090/", true},
+		{"secure needs https to set", "http://example.com/", http.Cookie{Name: "c", Value: "1", Secure: true}, "https://example.com/", false},
+		{"secure not sent over http", "https://example.com/", http.Cookie{Name: "c", Value: "1", Secure: true}, "http://example.com/", false},
+		{"path prefix matches", "https://example.com/", http.Cookie{Name: "c", Value: "1", Path: "/api"}, "https://example.com/api/posts", true},
+		{"path is not a string prefix", "https://example.com/", http.Cookie{Name: "c", Value: "1", Path: "/api"}, "https://example.com/apix", false},
+		{"default path is the directory", "https://example.com/docs/index.html", http.Cookie{Name: "c", Value: "1"}, "https://example.com/other", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jar := newTestJar(t, filepath.Join(t.TempDir(), "jar.json"))
+			cookie := tt.cookie
+			jar.SetCookies(mustURL(t, tt.setURL), []*http.Cookie{&cookie})
+			got := len(jar.Cookies(mustURL(t, tt.getURL))) == 1
+			if got != tt.want {
+				t.Errorf("cookie sent = %v; want %v", got, tt.want)
+			}
+			if err := jar.Err(); err != nil {
+				t.Errorf("Err() = %v", err)
+			}
+		})
+	}
+}
+
+func TestPersistentJarOrderAndReplace(t *testing.T) {
+	jar := newTestJar(t, filepath.Join(t.TempDir(), "jar.json"))
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	jar.now = clock.Now
+	u := mustURL(t, "https://example.com/")
+
+	jar.SetCookies(u, []*http.Cookie{{Name: "old", Value: "1"}})
+	clock.Advance(time.Second)
+	jar.SetCookies(u, []*http.Cookie{{Name: "deep", Value: "1", Path: "/api/v1"}, {Name: "new", Value: "1"}})
+	clock.Advance(time.Second)
+	jar.SetCookies(u, []*http.Cookie{{Name: "old", Value: "2"}}) // Keeps its creation time
+
+	cookies := jar.Cookies(mustURL(t, "https://example.com/api/v1/posts"))
+	if got := cookieNames(cookies); got != "deep,old,new" {
+		t.Errorf("order = %s; want longest path first, then oldest", got)
+	}
+	if cookies[1].Value != "2" {
+		t.Errorf("old = %s; want the replaced value", cookies[1].Value)
+	}
+}
+
+func TestPersistentJarExpiry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jar.json")
+	jar := newTestJar(t, path)
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	jar.now = clock.Now
+	u := mustURL(t, "https://example.com/")
+
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "short", Value: "1", MaxAge: 60},
+		{Name: "dated", Value: "1", Expires: clock.Now().Add(time.Hour)},
+		{Name: "session", Value: "1"},
+		{Name: "doomed", Value: "1"},
+	})
+	jar.SetCookies(u, []*http.Cookie{{Name: "doomed", MaxAge: -1}})
+	if got := cookieNames(jar.Cookies(u)); len(jar.Cookies(u)) != 3 || strings.Contains(got, "doomed") {
+		t.Fatalf("cookies = %s; want short, dated and session", got)
+	}
+
+	clock.Advance(61 * time.Second)
+	if got := len(jar.Cookies(u)); got != 2 {
+		t.Errorf("after Max-Age: %d cookies; want 2", got)
+	}
+
+	// The next write prunes expired cookies from the file
+	jar.SetCookies(u, []*http.Cookie{{Name: "dated", Value: "1", Expires: clock.Now().Add(-time.Minute)}})
+	data, _ := os.ReadFile(path)
+	for _, gone := range []string{"short", "dated", "doomed"} {
+		if strings.Contains(string(data), `"`+gone+`"`) {
+			t.Errorf("file still holds %q:\n%s", gone, data)
+		}
+	}
+	if !strings.Contains(string(data), `"session"`) {
+		t.Errorf("session cookies should persist:\n%s", data)
+	}
+}
+
+func TestPersistentJarSharedBetweenInstances(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shared", "jar.json")
+	a, b := newTestJar(t, path), newTestJar(t, path)
+	u := mustURL(t, "https://example.com/")
+
+	// Two "processes" writing concurrently must not lose each other's cookies
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		for name, jar := range map[string]*PersistentJar{"a": a, "b": b} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				jar.SetCookies(u, []*http.Cookie{{Name: fmt.Sprintf("%s%d", name, i), Value: "1"}})
+			}()
+		}
+	}
+	wg.Wait()
+	for _, jar := range []*PersistentJar{a, b} {
+		if err := jar.Err(); err != nil {
+			t.Fatalf("Err() = %v", err)
+		}
+	}
+
+	if got := len(newTestJar(t, path).Cookies(u)); got != 40 {
+		t.Errorf("reopened jar has %d cookies; want 40", got)
+	}
+	if got := len(a.Cookies(u)); got != 40 {
+		t.Errorf("jar a sees %d cookies; want 40 including b's", got)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Error("lock file should be released")
+	}
+}
+
+func TestPersistentJarRecoversStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jar.json")
+	jar := newTestJar(t, path)
+
+	lock := path + ".lock"
+	os.WriteFile(lock, []byte("99999\n"), 0o600)
+	old := time.Now().Add(-time.Minute)
+	os.Chtimes(lock, old, old)
+
+	jar.SetCookies(mustURL(t, "https://example.com/"), []*http.Cookie{{Name: "c", Value: "1"}})
+	if err := jar.Err(); err != nil {
+		t.Fatalf("stale lock should be taken over: %v", err)
+	}
+}
+
+func TestPersistentJarKeepsUnsavedChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jar.json")
+	jar := newTestJar(t, path)
+	u := mustURL(t, "https://example.com/")
+	jar.SetCookies(u, []*http.Cookie{{Name: "a", Value: "1"}})
+
+	// A directory in the file's place makes every read and write fail
+	os.Remove(path)
+	os.Mkdir(path, 0o700)
+	jar.SetCookies(u, []*http.Cookie{{Name: "b", Value: "2"}})
+	if jar.Err() == nil {
+		t.Fatal("Err() = nil after a failed save")
+	}
+	if got := cookieNames(jar.Cookies(u)); got != "a,b" {
+		t.Errorf("cookies = %q; want a,b: the unsaved cookie still applies", got)
+	}
+	jar.SetCookies(u, []*http.Cookie{{Name: "a", MaxAge: -1}})
+	if got := cookieNames(jar.Cookies(u)); got != "b" {
+		t.Errorf("cookies = %q; want b after deleting a", got)
+	}
+	if jar.Err() == nil {
+		t.Error("Err() cleared while changes are still unsaved")
+	}
+
+	os.Remove(path)
+	if err := jar.Save(); err != nil || jar.Err() != nil {
+		t.Fatalf("Save = %v, Err() = %v", err, jar.Err())
+	}
+	if got := cookieNames(newTestJar(t, path).Cookies(u)); got != "b" {
+		t.Errorf("reopened jar has %q; want the pending changes saved", got)
+	}
+	if err := jar.Save(); err != nil {
+		t.Errorf("Save with nothing pending = %v", err)
+	}
+}
+
+// sessionServer issues HMAC-signed session cookies, so it needs no state
+// to recognise a session from an earlier run
+type sessionServer struct {
+	*httptest.Server
+	logins atomic.Int64
+	mu     sync.Mutex
+	key    []byte
+}
+
+func newSessionServer(t *testing.T) *sessionServer {
+	t.Helper()
+	s := &sessionServer{key: []byte("first-key")}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		s.logins.Add(1)
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: s.sign("ana"), Path: "/", MaxAge: 3600, HttpOnly: true})
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /me", func(w http.ResponseWriter, r *http.Request) {
+		c, err := r.Cookie("session")
+		if err != nil || c.Value != s.sign("ana") {
+			http.Error(w, `{"error": "not logged in"}`, http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"user": "ana"}`)
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *sessionServer) sign(user string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(user))
+	return user + "." + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *sessionServer) rotateKey() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.key = []byte("second-key") // Every existing session becomes invalid
+}
+
+func TestSessionReusedAcrossRuns(t *testing.T) {
+	server := newSessionServer(t)
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	login := SessionLogin{
+		LoginPath:   "/login",
+		Credentials: map[string]string{"user": "ana", "password": "hunter2"},
+		CheckPath:   "/me",
+	}
+
+	run := func() bool {
+		t.Helper()
+		client, _ := NewAPIClient(server.URL, WithCookieJar(newTestJar(t, path)))
+		reused, err := client.EnsureSession(context.Background(), login)
+		if err != nil {
+			t.Fatalf("EnsureSession: %v", err)
+		}
+		me, err := Get[map[string]string](context.Background(), client, "/me", nil)
+		if err != nil || me["user"] != "ana" {
+			t.Fatalf("GET /me = %v, %v; want the logged-in user", me, err)
+		}
+		return reused
+	}
+
+	if run() {
+		t.Error("first run has no session to reuse")
+	}
+	if !run() || !run() {
+		t.Error("later runs should reuse the stored session")
+	}
+	if got := server.logins.Load(); got != 1 {
+		t.Errorf("logins = %d; want 1", got)
+	}
+
+	server.rotateKey()
+	if run() {
+		t.Error("a rejected session should trigger a fresh login")
+	}
+	if got := server.logins.Load(); got != 2 {
+		t.Errorf("logins = %d; want 2 after the server dropped the session", got)
+	}
+}
+
+func TestSessionNeedsJar(t *testing.T) {
+	client, _ := NewAPIClient("http://127.0.0.1")
+	if _, err := client.EnsureSession(context.Background(), SessionLogin{LoginPath: "/login", CheckPath: "/me"}); err == nil {
+		t.Error("EnsureSession without a jar should fail")
+	}
+}
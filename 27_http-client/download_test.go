@@ -0,0 +1,348 @@
+/*
+=============================================================================
+                    🧪 DOWNLOADER TESTS
+=============================================================================
+
+A local server serves a random 1 MiB artifact with http.ServeContent and
+randomly aborts connections halfway through a response body.
+Run with: go test -v -run Download *.go
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const artifactSize = 1 << 20
+
+type artifactServer struct {
+	*httptest.Server
+	content  []byte
+	etag     string
+	dropRate float64 // Fraction of GETs cut off mid-body
+	ranges   bool
+	misrange string // "ignore", "shift" or "grow": how GETs get ranges wrong
+
+	mu       sync.Mutex
+	rng      *rand.Rand
+	gets     atomic.Int64
+	dropped  atomic.Int64
+	rangeLog []string
+}
+
+func newArtifactServer(t *testing.T, dropRate float64, ranges bool) *artifactServer {
+	t.Helper()
+	content := make([]byte, artifactSize)
+	rand.New(rand.NewSource(1)).Read(content)
+
+	s := &artifactServer{
+		content:  content,
+		etag:     `"artifact-v1"`,
+		dropRate: dropRate,
+		ranges:   ranges,
+		rng:      rand.New(rand.NewSource(42)),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *artifactServer) sha256() string {
+	sum := sha256.Sum256(s.content)
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *artifactServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	etag, content := s.etag, s.content
+	drop := r.Method == http.MethodGet && s.rng.Float64() < s.dropRate
+	if r.Method == http.MethodGet {
+		s.rangeLog = append(s.rangeLog, r.Header.Get("Range"))
+	}
+	s.mu.Unlock()
+
+	if r.Method == http.MethodGet {
+		s.gets.Add(1)
+	}
+	if !s.ranges {
+		// A server that ignores Range and never advertises it
+		w.Header().Set("Content-Length", "1048576")
+		if r.Method == http.MethodGet {
+			w.Write(content)
+		}
+		return
+	}
+
+	if drop {
+		s.dropped.Add(1)
+		w = &droppingWriter{ResponseWriter: w}
+	}
+	if r.Method == http.MethodGet {
+		switch s.misrange {
+		case "ignore": // Advertised on HEAD, then a 200 with the whole file
+			r.Header.Del("Range")
+		case "shift": // Off by one byte
+			var start, end int
+			if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err == nil {
+				r.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start+1, end+1))
+			}
+		case "grow": // The file gained a byte since the HEAD
+			content = append(content[:len(content):len(content)], '!')
+		}
+	}
+	w.Header().Set("ETag", etag)
+	http.ServeContent(w, r, "artifact.bin", time.Time{}, bytes.NewReader(content))
+}
+
+// droppingWriter sends half of the first body write, then kills the connection
+type droppingWriter struct {
+	http.ResponseWriter
+}
+
+func (d *droppingWriter) Write(b []byte) (int, error) {
+	d.ResponseWriter.Write(b[:len(b)/2])
+	d.ResponseWriter.(http.Flusher).Flush()
+	panic(http.ErrAbortHandler)
+}
+
+func newTestDownloader(t *testing.T, server *artifactServer, opts DownloadOptions) *Downloader {
+	t.Helper()
+	client, err := NewAPIClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewDownloader(client, opts)
+}
+
+func assertDownloaded(t *testing.T, dest string, want []byte) {
+	t.Helper()
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("read result: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("downloaded %d bytes that differ from the %d-byte original", len(got), len(want))
+	}
+	for _, leftover := range []string{dest + ".part", dest + ".part.json"} {
+		if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed after success", filepath.Base(leftover))
+		}
+	}
+}
+
+func TestDownloadSurvivesDroppedConnections(t *testing.T) {
+	server := newArtifactServer(t, 0.3, true)
+	dest := filepath.Join(t.TempDir(), "artifact.bin")
+
+	var last DownloadProgress
+	var progressMu sync.Mutex
+	d := newTestDownloader(t, server, DownloadOptions{
+		Workers:    4,
+		ChunkSize:  64 << 10,
+		MaxRetries: 10,
+		SHA256:     server.sha256(),
+		OnProgress: func(p DownloadProgress) {
+			progressMu.Lock()
+			last = p
+			progressMu.Unlock()
+		},
+	})
+
+	if err := d.Download(context.Background(), "/artifact.bin", dest); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	assertDownloaded(t, dest, server.content)
+
+	if server.dropped.Load() == 0 {
+		t.Error("expected the server to drop some connections")
+	}
+	if last.Downloaded != artifactSize || last.ChunksDone != 16 || last.Chunks != 16 {
+		t.Errorf("final progress = %+v; want %d bytes and 16/16 chunks", last, artifactSize)
+	}
+}
+
+func TestDownloadResumesFromSidecar(t *testing.T) {
+	server := newArtifactServer(t, 0, true)
+	dest := filepath.Join(t.TempDir(), "artifact.bin")
+	opts := DownloadOptions{Workers: 1, ChunkSize: 128 << 10, SHA256: server.sha256()}
+
+	// First run: interrupt after 3 of 8 chunks
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupted := opts
+	interrupted.OnProgress = func(p DownloadProgress) {
+		if p.ChunksDone == 3 {
+			cancel()
+		}
+	}
+	err := newTestDownloader(t, server, interrupted).Download(ctx, "/artifact.bin", dest)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("interrupted Download = %v; want context.Canceled", err)
+	}
+	if _, err := os.Stat(dest + ".part.json"); err != nil {
+		t.Fatalf("sidecar missing after interruption: %v", err)
+	}
+
+	// Second run fetches only the missing chunks
+	server.mu.Lock()
+	server.rangeLog = nil
+	server.mu.Unlock()
+
+	var resumed atomic.Bool
+	opts.OnProgress = func(p DownloadProgress) { resumed.Store(p.Resumed) }
+	if err := newTestDownloader(t, server, opts).Download(context.Background(), "/artifact.bin", dest); err != nil {
+		t.Fatalf("resumed Download: %v", err)
+	}
+	assertDownloaded(t, dest, server.content)
+
+	if !resumed.Load() {
+		t.Error("progress should report Resumed")
+	}
+	for _, r := range server.rangeLog {
+		if r == "bytes=0-131071" {
+			t.Errorf("chunk 0 was fetched again on resume; ranges = %v", server.rangeLog)
+			break
+		}
+	}
+	if n := len(server.rangeLog); n != 5 {
+		t.Errorf("resume made %d range requests; want the 5 missing chunks: %v", n, server.rangeLog)
+	}
+}
+
+func TestDownloadRestartsWhenRemoteChanges(t *testing.T) {
+	server := newArtifactServer(t, 0, true)
+	dest := filepath.Join(t.TempDir(), "artifact.bin")
+	opts := DownloadOptions{Workers: 1, ChunkSize: 256 << 10}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupted := opts
+	interrupted.OnProgress = func(p DownloadProgress) {
+		if p.ChunksDone == 1 {
+			cancel()
+		}
+	}
+	newTestDownloader(t, server, interrupted).Download(ctx, "/artifact.bin", dest)
+
+	// Publish a new version: same size, new bytes and ETag
+	server.mu.Lock()
+	server.etag = `"artifact-v2"`
+	server.content = bytes.Repeat([]byte("v2"), artifactSize/2)
+	server.mu.Unlock()
+
+	if err := newTestDownloader(t, server, opts).Download(context.Background(), "/artifact.bin", dest); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	assertDownloaded(t, dest, server.content)
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	server := newArtifactServer(t, 0, true)
+	dest := filepath.Join(t.TempDir(), "artifact.bin")
+	d := newTestDownloader(t, server, DownloadOptions{SHA256: strings.Repeat("0", 64)})
+
+	err := d.Download(context.Background(), "/artifact.bin", dest)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("err = %v; want ErrChecksumMismatch", err)
+	}
+	for _, path := range []string{dest, dest + ".part", dest + ".part.json"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s should not exist after a bad checksum", filepath.Base(path))
+		}
+	}
+}
+
+func TestDownloadWithoutRangeSupport(t *testing.T) {
+	server := newArtifactServer(t, 0, false)
+	dest := filepath.Join(t.TempDir(), "artifact.bin")
+	d := newTestDownloader(t, server, DownloadOptions{ChunkSize: 64 << 10, SHA256: server.sha256()})
+
+	if err := d.Download(context.Background(), "/artifact.bin", dest); err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	assertDownloaded(t, dest, server.content)
+	if got := server.gets.Load(); got != 1 {
+		t.Errorf("GETs = %d; want a single streamed request", got)
+	}
+}
+
+func TestDownloadChecksContentRange(t *testing.T) {
+	tests := []struct {
+		misrange string
+		wantErr  error
+	}{
+		{"ignore", nil}, // Falls back to a single stream
+		{"shift", nil},
+		{"grow", ErrRemoteChanged},
+	}
+	for _, tt := range tests {
+		t.Run(tt.misrange, func(t *testing.T) {
+			server := newArtifactServer(t, 0, true)
+			server.misrange = tt.misrange
+			dest := filepath.Join(t.TempDir(), "artifact.bin")
+			d := newTestDownloader(t, server, DownloadOptions{Workers: 2, ChunkSize: 64 << 10, SHA256: server.sha256()})
+
+			err := d.Download(context.Background(), "/artifact.bin", dest)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Download = %v; want %v", err, tt.wantErr)
+				}
+				if _, err := os.Stat(dest + ".part.json"); !os.IsNotExist(err) {
+					t.Error("sidecar kept after the remote changed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Download: %v", err)
+			}
+			assertDownloaded(t, dest, server.content)
+
+			server.mu.Lock()
+			defer server.mu.Unlock()
+			if last := server.rangeLog[len(server.rangeLog)-1]; last != "" {
+				t.Errorf("last GET had Range %q; want the single-stream fallback", last)
+			}
+		})
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		header            string
+		start, end, total int64
+		ok                bool
+	}{
+		{"bytes 0-99/1000", 0, 99, 1000, true},
+		{"bytes 900-999/1000", 900, 999, 1000, true},
+		{"bytes 0-99/*", 0, 99, -1, true},
+		{"", 0, 0, 0, false},
+		{"bytes */1000", 0, 0, 0, false}, // Unsatisfied range
+		{"bytes 0-99", 0, 0, 0, false},
+		{"bytes 99-0/1000", 0, 0, 0, false},
+		{"bytes 0-999/1000x", 0, 0, 0, false},
+		{"bytes 0-1000/1000", 0, 0, 0, false}, // End past the size
+		{"items 0-99/1000", 0, 0, 0, false},
+	}
+	for _, tt := range tests {
+		start, end, total, ok := parseContentRange(tt.header)
+		if start != tt.start || end != tt.end || total != tt.total || ok != tt.ok {
+			t.Errorf("parseContentRange(%q) = %d, %d, %d, %v", tt.header, start, end, total, ok)
+		}
+	}
+}
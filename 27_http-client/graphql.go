@@ -0,0 +1,141 @@
+/*
+=============================================================================
+                    🕸️ GRAPHQL CLIENT - HTTP CLIENT EXTENSION
+=============================================================================
+
+Sends GraphQL operations over the APIClient and decodes "data" into a
+typed struct:
+
+  type postsData struct {
+      User struct {
+          Name  string `json:"name"`
+          Posts []Post `json:"posts"`
+      } `json:"user"`
+  }
+
+  data, err := GraphQL[postsData](ctx, client, "/graphql", GraphQLRequest{
+      Query:         `query UserPosts($id: ID!) { user(id: $id) { name posts { id title } } }`,
+      Variables:     map[string]interface{}{"id": 1},
+      OperationName: "UserPosts",
+  })
+
+GraphQL reports most failures with HTTP 200 and an "errors" array, often
+next to partial data. Those become GraphQLErrors (with message, path and
+source locations); whatever data arrived is still decoded and returned.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// 📨 GRAPHQL REQUEST
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+	OperationName string                 `json:"operationName,omitempty"`
+}
+
+// ❌ GRAPHQL ERRORS
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"` // Field names and list indexes
+	Locations  []GraphQLLocation      `json:"locations,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+func (e GraphQLError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Message)
+	if path := e.PathString(); path != "" {
+		fmt.Fprintf(&b, " (path %s)", path)
+	}
+	for _, loc := range e.Locations {
+		fmt.Fprintf(&b, " (at %d:%d)", loc.Line, loc.Column)
+	}
+	return b.String()
+}
+
+// PathString renders the path as user.posts[2].title
+func (e GraphQLError) PathString() string {
+	var b strings.Builder
+	for _, elem := range e.Path {
+		switch v := elem.(type) {
+		case float64: // JSON numbers decode as float64
+			fmt.Fprintf(&b, "[%d]", int(v))
+		case int:
+			fmt.Fprintf(&b, "[%d]", v)
+		default:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			fmt.Fprint(&b, v)
+		}
+	}
+	return b.String()
+}
+
+// Code returns extensions.code (e.g. "NOT_FOUND", "UNAUTHENTICATED")
+func (e GraphQLError) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+type GraphQLErrors []GraphQLError
+
+func (errs GraphQLErrors) Error() string {
+	if len(errs) == 1 {
+		return "graphql: " + errs[0].Error()
+	}
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return fmt.Sprintf("graphql: %d errors: %s", len(errs), strings.Join(msgs, "; "))
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors GraphQLErrors   `json:"errors"`
+}
+
+// GraphQL POSTs req to path and decodes "data" into T. With an "errors"
+// array the returned error is GraphQLErrors and T holds any partial data.
+func GraphQL[T any](ctx context.Context, c *APIClient, path string, req GraphQLRequest) (T, error) {
+	var out T
+	httpReq, err := c.NewRequest(ctx, http.MethodPost, path, nil, req)
+	if err != nil {
+		return out, err
+	}
+
+	var resp graphQLResponse
+	if _, err := c.Do(httpReq, &resp); err != nil {
+		// Validation failures often come back as HTTP 400 with an errors array
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && json.Unmarshal(apiErr.Body, &resp) == nil && len(resp.Errors) > 0 {
+			return out, errors.Join(resp.Errors, apiErr)
+		}
+		return out, err
+	}
+
+	if len(resp.Data) > 0 && string(resp.Data) != "null" {
+		if err := json.Unmarshal(resp.Data, &out); err != nil {
+			return out, fmt.Errorf("decode graphql data: %w", err)
+		}
+	}
+	if len(resp.Errors) > 0 {
+		return out, resp.Errors
+	}
+	return out, nil
+}
@@ -0,0 +1,157 @@
+/*
+=============================================================================
+                    🧪 GRAPHQL TESTS
+=============================================================================
+
+A tiny local "GraphQL server" answers by operation name with canned
+responses, including partial data and HTTP 400 validation errors.
+Run with: go test -v -run GraphQL *.go
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type userPostsData struct {
+	User struct {
+		Name  string `json:"name"`
+		Posts []struct {
+			ID    int    `json:"id"`
+			Title string `json:"title"`
+		} `json:"posts"`
+	} `json:"user"`
+}
+
+const userPostsQuery = `query UserPosts($id: ID!) { user(id: $id) { name posts { id title } } }`
+
+func newGraphQLServer(t *testing.T) (*APIClient, *GraphQLRequest) {
+	t.Helper()
+	var last GraphQLRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/graphql" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		json.NewDecoder(r.Body).Decode(&last)
+		w.Header().Set("Content-Type", "application/json")
+
+		switch last.OperationName {
+		case "UserPosts":
+			w.Write([]byte(`{"data": {"user": {"name": "Leanne", "posts": [{"id": 1, "title": "sunt aut"}, {"id": 2, "title": "qui est"}]}}}`))
+		case "PartialPosts":
+			// The resolver for the second post's title failed
+			w.Write([]byte(`{
+				"data": {"user": {"name": "Leanne", "posts": [{"id": 1, "title": "sunt aut"}, {"id": 2, "title": null}]}},
+				"errors": [{
+					"message": "title service unavailable",
+					"path": ["user", "posts", 1, "title"],
+					"locations": [{"line": 1, "column": 42}],
+					"extensions": {"code": "UPSTREAM_DOWN"}
+				}]
+			}`))
+		case "NoSuchUser":
+			w.Write([]byte(`{"data": null, "errors": [
+				{"message": "user not found", "path": ["user"], "extensions": {"code": "NOT_FOUND"}},
+				{"message": "rate limit close", "extensions": {"code": "WARNING"}}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"errors": [{"message": "Cannot query field \"nmae\" on type \"User\".", "locations": [{"line": 1, "column": 20}]}]}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewAPIClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, &last
+}
+
+func TestGraphQLDecodesTypedData(t *testing.T) {
+	client, last := newGraphQLServer(t)
+
+	data, err := GraphQL[userPostsData](context.Background(), client, "/graphql", GraphQLRequest{
+		Query:         userPostsQuery,
+		Variables:     map[string]interface{}{"id": 1},
+		OperationName: "UserPosts",
+	})
+	if err != nil {
+		t.Fatalf("GraphQL: %v", err)
+	}
+	if data.User.Name != "Leanne" || len(data.User.Posts) != 2 || data.User.Posts[1].Title != "qui est" {
+		t.Errorf("data = %+v", data)
+	}
+	if last.Query != userPostsQuery || last.Variables["id"] != float64(1) {
+		t.Errorf("server received %+v; want the query and variables", *last)
+	}
+}
+
+func TestGraphQLPartialDataWithErrors(t *testing.T) {
+	client, _ := newGraphQLServer(t)
+
+	data, err := GraphQL[userPostsData](context.Background(), client, "/graphql", GraphQLRequest{
+		Query:         userPostsQuery,
+		OperationName: "PartialPosts",
+	})
+
+	var gqlErrs GraphQLErrors
+	if !errors.As(err, &gqlErrs) || len(gqlErrs) != 1 {
+		t.Fatalf("err = %v; want one GraphQLError", err)
+	}
+	e := gqlErrs[0]
+	if e.PathString() != "user.posts[1].title" || e.Code() != "UPSTREAM_DOWN" {
+		t.Errorf("path = %q, code = %q", e.PathString(), e.Code())
+	}
+	if len(e.Locations) != 1 || e.Locations[0] != (GraphQLLocation{Line: 1, Column: 42}) {
+		t.Errorf("locations = %+v", e.Locations)
+	}
+	if want := "graphql: title service unavailable (path user.posts[1].title) (at 1:42)"; err.Error() != want {
+		t.Errorf("Error() = %q; want %q", err.Error(), want)
+	}
+
+	// Partial data is still returned
+	if data.User.Name != "Leanne" || len(data.User.Posts) != 2 || data.User.Posts[0].Title != "sunt aut" {
+		t.Errorf("partial data = %+v", data)
+	}
+}
+
+func TestGraphQLNullDataAndMultipleErrors(t *testing.T) {
+	client, _ := newGraphQLServer(t)
+
+	_, err := GraphQL[userPostsData](context.Background(), client, "/graphql", GraphQLRequest{
+		Query:         `{ user(id: 999) { name } }`,
+		OperationName: "NoSuchUser",
+	})
+	var gqlErrs GraphQLErrors
+	if !errors.As(err, &gqlErrs) || len(gqlErrs) != 2 || gqlErrs[0].Code() != "NOT_FOUND" {
+		t.Fatalf("err = %v; want NOT_FOUND plus a second error", err)
+	}
+	if want := "graphql: 2 errors: user not found (path user); rate limit close"; err.Error() != want {
+		t.Errorf("Error() = %q; want %q", err.Error(), want)
+	}
+}
+
+func TestGraphQLValidationErrorOverHTTP400(t *testing.T) {
+	client, _ := newGraphQLServer(t)
+
+	_, err := GraphQL[userPostsData](context.Background(), client, "/graphql", GraphQLRequest{
+		Query: `{ user(id: 1) { nmae } }`,
+	})
+
+	var gqlErrs GraphQLErrors
+	var apiErr *APIError
+	if !errors.As(err, &gqlErrs) || !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v; want both GraphQLErrors and the APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || gqlErrs[0].Locations[0].Column != 20 {
+		t.Errorf("status = %d, errors = %+v", apiErr.StatusCode, gqlErrs)
+	}
+}
@@ -34,11 +34,20 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 )
@@ -50,7 +59,7 @@ type User struct {
 	Email string `json:"email"`
 }
 
-type Post struct {
+type BlogPost struct {
 	UserID int    `json:"userId"`
 	ID     int    `json:"id"`
 	Title  string `json:"title"`
@@ -67,207 +76,186 @@ func main() {
 	fmt.Println("🌐 HTTP CLIENT TUTORIAL")
 	fmt.Println("=======================")
 
+	cassette := flag.String("cassette", "", "replay HTTP traffic from this cassette (recorded on first run)")
+	record := flag.Bool("record", false, "re-record the cassette against the live APIs")
+	trace := flag.Bool("trace", false, "print a timing waterfall for every HTTP request")
+	flag.Parse()
+
+	ctx := context.Background()
+
+	// With -cassette the demos run offline from recorded traffic (cassette.go)
+	var base http.RoundTripper = http.DefaultTransport
+	if *cassette != "" {
+		mode := ModeAuto
+		if *record {
+			mode = ModeRecord
+		}
+		rec, err := NewRecorder(*cassette, mode)
+		if err != nil {
+			fmt.Printf("❌ Cassette error: %v\n", err)
+			return
+		}
+		defer func() {
+			if err := rec.Stop(); err != nil {
+				fmt.Printf("❌ Saving cassette: %v\n", err)
+			}
+		}()
+		fmt.Printf("📼 Cassette %s (recording: %v)\n", *cassette, rec.Recording())
+		base = rec
+	}
+	// With -trace every attempt prints DNS/connect/TLS/TTFB/transfer (trace.go)
+	if *trace {
+		base = NewTraceTransport(base, &WaterfallSink{W: os.Stdout})
+	}
+
+	// One reusable client for every demo (see apiclient.go); transient
+	// 5xx responses and connection resets are retried (see retry.go),
+	// a circuit breaker stops us hammering a dead upstream (breaker.go)
+	// and cacheable responses are served from memory (cache.go)
+	retry := NewRetryTransport(base, RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   200 * time.Millisecond,
+		MaxDelay:    2 * time.Second,
+		Jitter:      DecorrelatedJitter,
+	})
+	breaker := NewCircuitBreaker(BreakerSettings{
+		Name: "jsonplaceholder",
+		OnStateChange: func(name string, from, to BreakerState) {
+			fmt.Printf("⚡ Circuit %s: %s -> %s\n", name, from, to)
+		},
+	})
+	cache := NewCacheTransport(NewBreakerTransport(retry, breaker), NewMemoryCache(8<<20))
+	cache.StaleIfError = time.Minute
+	client, err := NewAPIClient("https://jsonplaceholder.typicode.com",
+		WithTimeout(10*time.Second),
+		WithTransport(cache),
+		WithHeader("User-Agent", "Go-HTTP-Client/1.0"),
+	)
+	if err != nil {
+		fmt.Printf("❌ Client setup error: %v\n", err)
+		return
+	}
+
 	// 🎯 DEMO 1: Simple GET Request
 	fmt.Println("\n🎯 DEMO 1: Simple GET Request")
 	fmt.Println("=============================")
 
-	resp, err := http.Get("https://jsonplaceholder.typicode.com/posts/1")
+	post, err := Get[BlogPost](ctx, client, "/posts/1", nil)
 	if err != nil {
 		fmt.Printf("❌ GET error: %v\n", err)
 		return
 	}
-	defer resp.Body.Close()
-
-	fmt.Printf("📊 Status: %s\n", resp.Status)
-	fmt.Printf("📊 Status Code: %d\n", resp.StatusCode)
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Printf("❌ Read body error: %v\n", err)
-		return
-	}
+	fmt.Printf("📝 Post: %+v\n", post)
 
-	var post Post
-	err = json.Unmarshal(body, &post)
-	if err != nil {
-		fmt.Printf("❌ JSON unmarshal error: %v\n", err)
-		return
+	// Fetching it again is answered from the cache while max-age allows
+	for i := 1; i <= 2; i++ {
+		req, err := client.NewRequest(ctx, http.MethodGet, "/posts/1", nil, nil)
+		if err != nil {
+			fmt.Printf("❌ Create request error: %v\n", err)
+			return
+		}
+		resp, err := client.Do(req, nil)
+		if err != nil {
+			fmt.Printf("❌ GET error: %v\n", err)
+			return
+		}
+		fmt.Printf("🗄️  Fetch %d: X-Cache=%s Cache-Control=%q\n",
+			i, resp.Header.Get("X-Cache"), resp.Header.Get("Cache-Control"))
 	}
 
-	fmt.Printf("📝 Post: %+v\n", post)
-
 	// 🎯 DEMO 2: GET with Query Parameters
 	fmt.Println("\n🎯 DEMO 2: GET with Query Parameters")
 	fmt.Println("====================================")
 
-	baseURL := "https://jsonplaceholder.typicode.com/posts"
 	params := url.Values{}
 	params.Add("userId", "1")
 	params.Add("_limit", "3")
+	fmt.Printf("🔗 Query: %s\n", params.Encode())
 
-	fullURL := baseURL + "?" + params.Encode()
-	fmt.Printf("🔗 URL: %s\n", fullURL)
-
-	resp, err = http.Get(fullURL)
+	posts, err := Get[[]BlogPost](ctx, client, "/posts", params)
 	if err != nil {
 		fmt.Printf("❌ GET with params error: %v\n", err)
 		return
 	}
-	defer resp.Body.Close()
-
-	body, err = io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Printf("❌ Read body error: %v\n", err)
-		return
-	}
-
-	var posts []Post
-	err = json.Unmarshal(body, &posts)
-	if err != nil {
-		fmt.Printf("❌ JSON unmarshal error: %v\n", err)
-		return
-	}
 
 	fmt.Printf("📝 Found %d posts:\n", len(posts))
 	for _, p := range posts {
 		fmt.Printf("  - %s\n", p.Title)
 	}
 
+	// Every page, not just the first: jsonplaceholder pages with _page/_limit
+	pages := PagePagination{PageParam: "_page", PerPageParam: "_limit", PerPage: 3}
+	filter := url.Values{"userId": {"1"}}
+	fmt.Println("📚 Paginating (3 per page, first 7 posts):")
+	count := 0
+	for p, err := range Paginate[BlogPost](ctx, client, "/posts", filter, pages, WithMaxItems(7)) {
+		if err != nil {
+			fmt.Printf("❌ Pagination error: %v\n", err)
+			break
+		}
+		count++
+		fmt.Printf("  %d. [#%d] %s\n", count, p.ID, p.Title)
+	}
+
 	// 🎯 DEMO 3: POST Request with JSON
 	fmt.Println("\n🎯 DEMO 3: POST Request with JSON")
 	fmt.Println("=================================")
 
-	newPost := Post{
+	newPost := BlogPost{
 		UserID: 1,
 		Title:  "My New Post",
 		Body:   "This is the content of my new post.",
 	}
 
-	jsonData, err := json.Marshal(newPost)
-	if err != nil {
-		fmt.Printf("❌ JSON marshal error: %v\n", err)
-		return
-	}
-
-	resp, err = http.Post(
-		"https://jsonplaceholder.typicode.com/posts",
-		"application/json",
-		bytes.NewBuffer(jsonData),
-	)
+	createdPost, err := Post[BlogPost, BlogPost](ctx, client, "/posts", newPost)
 	if err != nil {
 		fmt.Printf("❌ POST error: %v\n", err)
 		return
 	}
-	defer resp.Body.Close()
-
-	fmt.Printf("📊 POST Status: %s\n", resp.Status)
-
-	body, err = io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Printf("❌ Read POST response error: %v\n", err)
-		return
-	}
-
-	var createdPost Post
-	err = json.Unmarshal(body, &createdPost)
-	if err != nil {
-		fmt.Printf("❌ JSON unmarshal error: %v\n", err)
-		return
-	}
 
 	fmt.Printf("✅ Created post: %+v\n", createdPost)
 
-	// 🎯 DEMO 4: Custom HTTP Client with Timeout
-	fmt.Println("\n🎯 DEMO 4: Custom HTTP Client")
-	fmt.Println("=============================")
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	// 🎯 DEMO 4: Custom Request with Response Headers
+	fmt.Println("\n🎯 DEMO 4: Custom Request")
+	fmt.Println("=========================")
 
-	req, err := http.NewRequest("GET", "https://jsonplaceholder.typicode.com/users/1", nil)
+	// NewRequest + Do when you need the *http.Response as well
+	req, err := client.NewRequest(ctx, http.MethodGet, "/users/1", nil, nil)
 	if err != nil {
 		fmt.Printf("❌ Create request error: %v\n", err)
 		return
 	}
 
-	// Add custom headers
-	req.Header.Set("User-Agent", "Go-HTTP-Client/1.0")
-	req.Header.Set("Accept", "application/json")
-
-	resp, err = client.Do(req)
+	var user User
+	resp, err := client.Do(req, &user)
 	if err != nil {
-		fmt.Printf("❌ Custom client error: %v\n", err)
+		fmt.Printf("❌ Custom request error: %v\n", err)
 		return
 	}
-	defer resp.Body.Close()
 
 	fmt.Printf("📊 Response Headers:\n")
 	for key, values := range resp.Header {
 		fmt.Printf("  %s: %s\n", key, strings.Join(values, ", "))
 	}
-
-	body, err = io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Printf("❌ Read body error: %v\n", err)
-		return
-	}
-
-	var user User
-	err = json.Unmarshal(body, &user)
-	if err != nil {
-		fmt.Printf("❌ JSON unmarshal error: %v\n", err)
-		return
-	}
-
 	fmt.Printf("👤 User: %+v\n", user)
 
 	// 🎯 DEMO 5: PUT Request
 	fmt.Println("\n🎯 DEMO 5: PUT Request")
 	fmt.Println("======================")
 
-	updatedPost := Post{
+	updatedPost := BlogPost{
 		UserID: 1,
 		ID:     1,
 		Title:  "Updated Post Title",
 		Body:   "This post has been updated.",
 	}
 
-	jsonData, err = json.Marshal(updatedPost)
-	if err != nil {
-		fmt.Printf("❌ JSON marshal error: %v\n", err)
-		return
-	}
-
-	req, err = http.NewRequest("PUT", "https://jsonplaceholder.typicode.com/posts/1", bytes.NewBuffer(jsonData))
-	if err != nil {
-		fmt.Printf("❌ Create PUT request error: %v\n", err)
-		return
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err = client.Do(req)
+	putResponse, err := Put[BlogPost, BlogPost](ctx, client, "/posts/1", updatedPost)
 	if err != nil {
 		fmt.Printf("❌ PUT error: %v\n", err)
 		return
 	}
-	defer resp.Body.Close()
-
-	fmt.Printf("📊 PUT Status: %s\n", resp.Status)
-
-	body, err = io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Printf("❌ Read PUT response error: %v\n", err)
-		return
-	}
-
-	var putResponse Post
-	err = json.Unmarshal(body, &putResponse)
-	if err != nil {
-		fmt.Printf("❌ JSON unmarshal error: %v\n", err)
-		return
-	}
 
 	fmt.Printf("✅ Updated post: %+v\n", putResponse)
 
@@ -275,20 +263,11 @@ func main() {
 	fmt.Println("\n🎯 DEMO 6: DELETE Request")
 	fmt.Println("=========================")
 
-	req, err = http.NewRequest("DELETE", "https://jsonplaceholder.typicode.com/posts/1", nil)
-	if err != nil {
-		fmt.Printf("❌ Create DELETE request error: %v\n", err)
-		return
-	}
-
-	resp, err = client.Do(req)
-	if err != nil {
+	if err := Delete(ctx, client, "/posts/1"); err != nil {
 		fmt.Printf("❌ DELETE error: %v\n", err)
 		return
 	}
-	defer resp.Body.Close()
 
-	fmt.Printf("📊 DELETE Status: %s\n", resp.Status)
 	fmt.Printf("✅ Post deleted (simulated)\n")
 
 	// 🎯 DEMO 7: Form Data POST
@@ -300,7 +279,7 @@ func main() {
 	formData.Set("body", "This is a form post")
 	formData.Set("userId", "1")
 
-	resp, err = http.PostForm("https://jsonplaceholder.typicode.com/posts", formData)
+	resp, err = client.HTTPClient.PostForm(client.BaseURL.JoinPath("posts").String(), formData)
 	if err != nil {
 		fmt.Printf("❌ Form POST error: %v\n", err)
 		return
@@ -309,67 +288,288 @@ func main() {
 
 	fmt.Printf("📊 Form POST Status: %s\n", resp.Status)
 
-	body, err = io.ReadAll(resp.Body)
-	if err != nil {
-		fmt.Printf("❌ Read form response error: %v\n", err)
+	var formPost BlogPost
+	if err := json.NewDecoder(resp.Body).Decode(&formPost); err != nil {
+		fmt.Printf("❌ JSON decode error: %v\n", err)
 		return
 	}
 
-	var formPost Post
-	err = json.Unmarshal(body, &formPost)
+	fmt.Printf("✅ Form post created: %+v\n", formPost)
+
+	// Multipart uploads stream files instead of buffering them (upload.go);
+	// a local server stands in for 28_http-server's PUT /users/{id}/avatar
+	uploadMux := http.NewServeMux()
+	uploadMux.HandleFunc("PUT /users/{id}/avatar", func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("avatar")
+		if err != nil {
+			http.Error(w, `{"error": "missing avatar"}`, http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		n, _ := io.Copy(io.Discard, file)
+		json.NewEncoder(w).Encode(APIResponse{
+			Success: true,
+			Message: fmt.Sprintf("received %s (%d bytes), caption %q", header.Filename, n, r.FormValue("caption")),
+		})
+	})
+	uploadServer := httptest.NewServer(uploadMux)
+	defer uploadServer.Close()
+
+	uploadClient, _ := NewAPIClient(uploadServer.URL)
+	form := NewMultipartForm().
+		AddField("caption", "Profile picture").
+		AddFile("avatar", "avatar.png", bytes.NewReader(bytes.Repeat([]byte{0x89}, 256<<10)))
+	uploaded, err := Upload[APIResponse](ctx, uploadClient, "/users/1/avatar", form, UploadOptions{
+		Method: http.MethodPut,
+		OnProgress: func(p UploadProgress) {
+			if p.Sent == p.Total {
+				fmt.Printf("📤 Sent %d/%d bytes\n", p.Sent, p.Total)
+			}
+		},
+	})
 	if err != nil {
-		fmt.Printf("❌ JSON unmarshal error: %v\n", err)
-		return
+		fmt.Printf("❌ Upload error: %v\n", err)
+	} else {
+		fmt.Printf("✅ Upload: %s\n", uploaded.Message)
 	}
 
-	fmt.Printf("✅ Form post created: %+v\n", formPost)
-
 	// 🎯 DEMO 8: Error Handling and Status Codes
 	fmt.Println("\n🎯 DEMO 8: Error Handling")
 	fmt.Println("=========================")
 
-	resp, err = http.Get("https://jsonplaceholder.typicode.com/posts/999999")
-	if err != nil {
-		fmt.Printf("❌ Request error: %v\n", err)
-		return
-	}
-	defer resp.Body.Close()
-
-	fmt.Printf("📊 Status Code: %d\n", resp.StatusCode)
+	_, err = Get[BlogPost](ctx, client, "/posts/999999", nil)
 
-	switch resp.StatusCode {
-	case http.StatusOK:
+	var apiErr *APIError
+	switch {
+	case err == nil:
 		fmt.Println("✅ Success!")
-	case http.StatusNotFound:
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound:
 		fmt.Println("❌ Resource not found")
-	case http.StatusInternalServerError:
+		fmt.Printf("📊 Status Code: %d\n", apiErr.StatusCode)
+	case errors.As(err, &apiErr) && apiErr.StatusCode >= 500:
 		fmt.Println("❌ Server error")
+	case errors.As(err, &apiErr):
+		fmt.Printf("⚠️ Unexpected status: %s\n", apiErr.Status)
 	default:
-		fmt.Printf("⚠️ Unexpected status: %s\n", resp.Status)
+		fmt.Printf("❌ Request error: %v\n", err)
+	}
+
+	// Context cancellation stops a request before it is even sent
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := Get[BlogPost](cancelled, client, "/posts/1", nil); errors.Is(err, context.Canceled) {
+		fmt.Println("🛑 Cancelled request returned context.Canceled")
 	}
 
 	// 🎯 DEMO 9: Authentication Example
 	fmt.Println("\n🎯 DEMO 9: Authentication")
 	fmt.Println("=========================")
 
-	req, err = http.NewRequest("GET", "https://jsonplaceholder.typicode.com/posts", nil)
+	authenticators := []struct {
+		name string
+		auth Authenticator
+	}{
+		{"Basic Auth", BasicAuth{Username: "username", Password: "password"}},
+		{"API Key", APIKeyAuth{Key: "your-api-key-here"}},
+	}
+
+	for _, a := range authenticators {
+		authed, err := NewAPIClient("https://jsonplaceholder.typicode.com", WithAuth(a.auth))
+		if err != nil {
+			fmt.Printf("❌ Client setup error: %v\n", err)
+			return
+		}
+		req, err := authed.NewRequest(ctx, http.MethodGet, "/posts", nil, nil)
+		if err != nil {
+			fmt.Printf("❌ Create auth request error: %v\n", err)
+			return
+		}
+		fmt.Printf("🔐 %-12s Authorization=%q X-API-Key=%q\n",
+			a.name+":", req.Header.Get("Authorization"), req.Header.Get("X-API-Key"))
+	}
+
+	// Bearer tokens come from an OAuth2 token endpoint (oauth2.go); a local
+	// stub (oauth2server.go) plays the authorization server
+	authServer := NewTokenServer(map[string]string{"demo-client": "s3cret"}, time.Hour)
+	authMux := http.NewServeMux()
+	authMux.Handle("POST /oauth/token", authServer)
+	authMux.Handle("GET /me", authServer.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"client": "demo-client", "scope": "posts:read"}`)
+	})))
+	oauthAPI := httptest.NewServer(authMux)
+	defer oauthAPI.Close()
+
+	tokens := OAuth2Config{
+		TokenURL:     oauthAPI.URL + "/oauth/token",
+		ClientID:     "demo-client",
+		ClientSecret: "s3cret",
+		Scopes:       []string{"posts:read"},
+	}.ClientCredentials()
+	oauthClient, err := NewAPIClient(oauthAPI.URL, WithTransport(NewOAuth2Transport(nil, tokens)))
 	if err != nil {
-		fmt.Printf("❌ Create auth request error: %v\n", err)
+		fmt.Printf("❌ Client setup error: %v\n", err)
 		return
 	}
-
-	// Basic Auth (example)
-	req.SetBasicAuth("username", "password")
-
-	// Bearer Token (example)
-	req.Header.Set("Authorization", "Bearer your-token-here")
-
-	// API Key (example)
-	req.Header.Set("X-API-Key", "your-api-key-here")
-
-	fmt.Println("🔐 Authentication headers set (examples)")
-	fmt.Printf("  Basic Auth: %s\n", req.Header.Get("Authorization"))
-	fmt.Printf("  API Key: %s\n", req.Header.Get("X-API-Key"))
+	for _, label := range []string{"first call", "cached token", "after revoke"} {
+		if label == "after revoke" {
+			token, _ := tokens.Token(ctx)
+			authServer.Revoke(token.AccessToken) // Next call gets a 401 and retries once
+		}
+		me, err := Get[map[string]string](ctx, oauthClient, "/me", nil)
+		if err != nil {
+			fmt.Printf("❌ OAuth2 request error: %v\n", err)
+			return
+		}
+		fmt.Printf("🎟️ OAuth2 %-13s client=%s tokens issued=%d\n", label+":", me["client"], authServer.Issued())
+	}
+
+	// 🎯 DEMO 10: Circuit Breaker
+	fmt.Println("\n🎯 DEMO 10: Circuit Breaker")
+	fmt.Println("===========================")
+
+	flaky := NewCircuitBreaker(BreakerSettings{
+		Name:                "flaky-service",
+		ConsecutiveFailures: 3,
+		OpenTimeout:         100 * time.Millisecond,
+		OnStateChange: func(name string, from, to BreakerState) {
+			fmt.Printf("⚡ Circuit %s: %s -> %s\n", name, from, to)
+		},
+	})
+	unavailable := &APIError{StatusCode: http.StatusServiceUnavailable, Method: "GET", URL: "/flaky"}
+	for i := 1; i <= 4; i++ {
+		err := flaky.Execute(func() error { return unavailable })
+		fmt.Printf("   Call %d: %v\n", i, err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if err := flaky.Execute(func() error { return nil }); err == nil {
+		fmt.Printf("✅ Probe succeeded, circuit is %s\n", flaky.State())
+	}
+
+	// 🎯 DEMO 11: Resumable Parallel Download
+	fmt.Println("\n🎯 DEMO 11: Resumable Download")
+	fmt.Println("==============================")
+
+	// A local file server stands in for an artifact host
+	artifact := bytes.Repeat([]byte("go-http-client "), 1<<16) // ~1 MiB
+	fileServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"demo-artifact"`)
+		http.ServeContent(w, r, "artifact.bin", time.Time{}, bytes.NewReader(artifact))
+	}))
+	defer fileServer.Close()
+
+	sum := sha256.Sum256(artifact)
+	downloadClient, _ := NewAPIClient(fileServer.URL)
+	downloader := NewDownloader(downloadClient, DownloadOptions{
+		Workers:   4,
+		ChunkSize: 256 << 10,
+		SHA256:    hex.EncodeToString(sum[:]),
+		OnProgress: func(p DownloadProgress) {
+			if p.ChunksDone > 0 {
+				fmt.Printf("   %d/%d chunks (%.0f%%)\n", p.ChunksDone, p.Chunks, p.Percent())
+			}
+		},
+	})
+
+	dest := filepath.Join(os.TempDir(), "http-client-demo-artifact.bin")
+	defer os.Remove(dest)
+	if err := downloader.Download(ctx, "/artifact.bin", dest); err != nil {
+		fmt.Printf("❌ Download error: %v\n", err)
+	} else {
+		fmt.Printf("📥 Downloaded %d bytes to %s (SHA-256 verified)\n", len(artifact), dest)
+	}
+
+	// 🎯 DEMO 12: Persistent Session
+	fmt.Println("\n🎯 DEMO 12: Persistent Session")
+	fmt.Println("==============================")
+
+	// Sessions are HMAC-signed cookies, so a fresh server on the next run
+	// still accepts them; run the demo twice to see the login skipped
+	sessionKey := []byte("demo-session-key")
+	sign := func(user string) string {
+		mac := hmac.New(sha256.New, sessionKey)
+		mac.Write([]byte(user))
+		return user + "." + hex.EncodeToString(mac.Sum(nil))
+	}
+	sessionMux := http.NewServeMux()
+	sessionMux.HandleFunc("POST /login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: sign("ana"), Path: "/", MaxAge: 3600, HttpOnly: true})
+		w.WriteHeader(http.StatusNoContent)
+	})
+	sessionMux.HandleFunc("GET /me", func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err != nil || c.Value != sign("ana") {
+			http.Error(w, `{"error": "not logged in"}`, http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"user": "ana"}`)
+	})
+	sessionServer := httptest.NewServer(sessionMux)
+	defer sessionServer.Close()
+
+	jarPath := filepath.Join(os.TempDir(), "go-http-client-demo", "cookies.json")
+	jar, err := NewPersistentJar(jarPath, nil)
+	if err != nil {
+		fmt.Printf("❌ Cookie jar error: %v\n", err)
+		return
+	}
+	sessionClient, err := NewAPIClient(sessionServer.URL, WithCookieJar(jar))
+	if err != nil {
+		fmt.Printf("❌ Client setup error: %v\n", err)
+		return
+	}
+	reused, err := sessionClient.EnsureSession(ctx, SessionLogin{
+		LoginPath:   "/login",
+		Credentials: map[string]string{"user": "ana", "password": "hunter2"},
+		CheckPath:   "/me",
+	})
+	if err != nil {
+		fmt.Printf("❌ Session error: %v\n", err)
+		return
+	}
+	if reused {
+		fmt.Printf("🍪 Reused the session stored in %s\n", jarPath)
+	} else {
+		fmt.Printf("🍪 Logged in; session saved to %s\n", jarPath)
+	}
+
+	// 🎯 DEMO 13: JSON-RPC 2.0 Batch
+	fmt.Println("\n🎯 DEMO 13: JSON-RPC Batch")
+	fmt.Println("==========================")
+
+	rpcServer := httptest.NewServer(RPCHandler{
+		"add": func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			var nums []int
+			if err := json.Unmarshal(params, &nums); err != nil {
+				return nil, &RPCError{Code: RPCInvalidParams, Message: "want an array of numbers"}
+			}
+			sum := 0
+			for _, n := range nums {
+				sum += n
+			}
+			return sum, nil
+		},
+		"log": func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			fmt.Printf("📝 Server log: %s\n", params)
+			return nil, nil
+		},
+	})
+	defer rpcServer.Close()
+
+	rpcAPI, err := NewAPIClient(rpcServer.URL)
+	if err != nil {
+		fmt.Printf("❌ Client setup error: %v\n", err)
+		return
+	}
+	var total int
+	batch := NewRPCClient(rpcAPI, "/rpc").NewBatch()
+	addCall := batch.Call("add", []int{1, 2, 3}, &total)
+	unknownCall := batch.Call("multiply", []int{2, 3}, nil)
+	batch.Notify("log", "batch of 3 sent")
+	if err := batch.Send(ctx); err != nil {
+		fmt.Printf("❌ JSON-RPC error: %v\n", err)
+		return
+	}
+	fmt.Printf("📞 add(1, 2, 3) = %d (err: %v)\n", total, addCall.Err)
+	fmt.Printf("📞 multiply: %v\n", unknownCall.Err)
 
 	fmt.Println("\n✨ All HTTP client demos completed!")
 }
@@ -481,33 +681,83 @@ func main() {
 
 🎯 REAL-WORLD PATTERNS:
 ┌─────────────────────────────────────────────────────────────────────────┐
-│ // API Client struct                                                    │
-│ type APIClient struct {                                                 │
-│     BaseURL    string                                                   │
-│     HTTPClient *http.Client                                             │
-│     APIKey     string                                                   │
-│ }                                                                       │
+│ // API Client (full version in apiclient.go)                            │
+│ client, _ := NewAPIClient("https://api.example.com",                    │
+│     WithTimeout(10*time.Second),                                        │
+│     WithAuth(BearerAuth{Token: token}))                                 │
 │                                                                         │
-│ func (c *APIClient) Get(endpoint string) (*Response, error) {           │
-│     req, err := http.NewRequest("GET", c.BaseURL+endpoint, nil)          │
-│     if err != nil {                                                     │
-│         return nil, err                                                 │
-│     }                                                                   │
-│     req.Header.Set("Authorization", "Bearer "+c.APIKey)                 │
-│     return c.HTTPClient.Do(req)                                         │
-│ }                                                                       │
+│ post, err := Get[BlogPost](ctx, client, "/posts/1", nil)                │
+│ created, err := Post[BlogPost, BlogPost](ctx, client, "/posts", p)      │
+│ err = Delete(ctx, client, "/posts/1")                                   │
 │                                                                         │
-│ // Retry logic                                                          │
-│ func (c *APIClient) GetWithRetry(url string, maxRetries int) {          │
-│     for i := 0; i < maxRetries; i++ {                                   │
-│         resp, err := c.HTTPClient.Get(url)                              │
-│         if err == nil && resp.StatusCode == 200 {                       │
-│             return resp, nil                                            │
-│         }                                                               │
-│         time.Sleep(time.Duration(i+1) * time.Second)                    │
-│     }                                                                   │
-│     return nil, errors.New("max retries exceeded")                      │
-│ }                                                                       │
+│ var apiErr *APIError                                                    │
+│ if errors.As(err, &apiErr) && apiErr.StatusCode == 404 { ... }          │
+│                                                                         │
+│ // Retry logic (full version in retry.go)                               │
+│ rt := NewRetryTransport(http.DefaultTransport, RetryPolicy{             │
+│     MaxAttempts: 4,                                                     │
+│     BaseDelay:   200 * time.Millisecond,                                │
+│     MaxDelay:    5 * time.Second,                                       │
+│     Jitter:      DecorrelatedJitter,                                    │
+│ })                                                                      │
+│ client, _ := NewAPIClient(baseURL, WithTransport(rt))                   │
+│                                                                         │
+│ // POSTs are only retried when they carry an Idempotency-Key            │
+│ req.Header.Set("Idempotency-Key", orderID)                              │
+│                                                                         │
+│ // Circuit breaker (full version in breaker.go)                         │
+│ cb := NewCircuitBreaker(BreakerSettings{Name: "posts-api"})             │
+│ client, _ := NewAPIClient(baseURL,                                      │
+│     WithTransport(NewBreakerTransport(rt, cb)))                         │
+│ if errors.Is(err, ErrCircuitOpen) { ... } // fail fast, use a fallback  │
+│                                                                         │
+│ // Pagination (full version in paginate.go)                             │
+│ pages := PagePagination{PageParam: "_page", PerPageParam: "_limit"}     │
+│ for post, err := range Paginate[BlogPost](ctx, c, "/posts", nil,        │
+│     pages, WithMaxItems(50)) { ... }                                    │
+│                                                                         │
+│ // Streaming multipart upload (full version in upload.go)               │
+│ form := NewMultipartForm().AddField("caption", "Me").                   │
+│     AddFile("avatar", "me.png", file)                                   │
+│ resp, err := Upload[APIResponse](ctx, c, "/users/1/avatar", form,       │
+│     UploadOptions{Method: http.MethodPut, Gzip: true})                  │
+│                                                                         │
+│ // Resumable download (full version in download.go)                     │
+│ d := NewDownloader(client, DownloadOptions{Workers: 4, SHA256: sum})    │
+│ err := d.Download(ctx, "/releases/app.tar.gz", "app.tar.gz")            │
+│                                                                         │
+│ // Response cache (full version in cache.go)                            │
+│ cache := NewCacheTransport(rt, NewMemoryCache(8<<20))                   │
+│ disk, _ := NewDiskCache(".http-cache") // or persist across runs        │
+│ resp.Header.Get("X-Cache") // HIT, MISS, REVALIDATED or STALE           │
+│                                                                         │
+│ // Record once, replay offline (full version in cassette.go)            │
+│ rec, _ := NewRecorder("testdata/posts.json", ModeAuto)                  │
+│ rec.Passthrough = true            // Misses hit the network, not error  │
+│ rec.RedactBody = RedactJSONFields("password", "token")                  │
+│ defer rec.Stop()                  // Writes the cassette if recording   │
+│                                                                         │
+│ // OAuth2 client credentials (full version in oauth2.go)                │
+│ tokens := OAuth2Config{TokenURL: tokenURL, ClientID: id,                │
+│     ClientSecret: secret}.ClientCredentials()                           │
+│ c, _ := NewAPIClient(baseURL,                                           │
+│     WithTransport(NewOAuth2Transport(nil, tokens))) // 401 → 1 retry    │
+│                                                                         │
+│ // Where did the time go? (full version in trace.go; or run with -trace)│
+│ rt := NewTraceTransport(nil, &WaterfallSink{W: os.Stderr})              │
+│ timing, _ := TimingFromResponse(resp) // DNS, Connect, TLS, TTFB...     │
+│                                                                         │
+│ // Sessions that survive restarts (full version in cookiejar.go)        │
+│ jar, _ := NewPersistentJar(".cookies.json", nil)                        │
+│ c, _ := NewAPIClient(baseURL, WithCookieJar(jar))                       │
+│ reused, err := c.EnsureSession(ctx, SessionLogin{LoginPath: "/login",   │
+│     Credentials: creds, CheckPath: "/me"})                              │
+│                                                                         │
+│ // GraphQL and JSON-RPC (full versions in graphql.go and jsonrpc.go)    │
+│ data, err := GraphQL[UserData](ctx, c, "/graphql", GraphQLRequest{      │
+│     Query: query, Variables: map[string]interface{}{"id": 1}})          │
+│ var gqlErrs GraphQLErrors // errors.As: message, path, locations        │
+│ err = NewRPCClient(c, "/rpc").Call(ctx, "add", []int{1, 2}, &sum)       │
 └─────────────────────────────────────────────────────────────────────────┘
 
 ⚡ PERFORMANCE TIPS:
@@ -0,0 +1,317 @@
+/*
+=============================================================================
+                    📞 JSON-RPC 2.0 CLIENT - HTTP CLIENT EXTENSION
+=============================================================================
+
+JSON-RPC over HTTP POST, built on the APIClient:
+
+  rpc := NewRPCClient(client, "/rpc")
+
+  var sum int
+  err := rpc.Call(ctx, "add", []int{2, 3}, &sum)        // Positional params
+  err = rpc.Notify(ctx, "log", map[string]string{"msg": "hi"}) // No reply
+
+  batch := rpc.NewBatch()
+  a := batch.Call("add", []int{1, 2}, &x)
+  b := batch.Call("divide", map[string]int{"a": 1, "b": 0}, &y)
+  batch.Notify("log", "two calls sent")
+  err = batch.Send(ctx) // Transport errors only...
+  if b.Err != nil { ... } // ...each call carries its own *RPCError
+
+Replies are matched to calls by id, so servers may answer a batch in
+any order. RPCHandler is a small server side used by the demo and tests.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// Standard error codes from the JSON-RPC 2.0 spec
+const (
+	RPCParseError     = -32700
+	RPCInvalidRequest = -32600
+	RPCMethodNotFound = -32601
+	RPCInvalidParams  = -32602
+	RPCInternalError  = -32603
+)
+
+// ErrRPCNoResponse means the server answered a batch without this call
+var ErrRPCNoResponse = errors.New("jsonrpc: no response for call")
+
+// 📨 WIRE FORMAT
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  interface{}     `json:"params,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"` // Absent for notifications
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// ❌ RPC ERROR
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *RPCError) Error() string {
+	if len(e.Data) > 0 {
+		return fmt.Sprintf("jsonrpc: %s (code %d): %s", e.Message, e.Code, e.Data)
+	}
+	return fmt.Sprintf("jsonrpc: %s (code %d)", e.Message, e.Code)
+}
+
+// 📞 RPC CLIENT
+type RPCClient struct {
+	api    *APIClient
+	path   string
+	nextID atomic.Int64
+}
+
+func NewRPCClient(c *APIClient, path string) *RPCClient {
+	return &RPCClient{api: c, path: path}
+}
+
+func (r *RPCClient) newID() json.RawMessage {
+	return json.RawMessage(fmt.Sprint(r.nextID.Add(1)))
+}
+
+// Call invokes method and decodes its result into result (skipped when nil)
+func (r *RPCClient) Call(ctx context.Context, method string, params, result interface{}) error {
+	batch := r.NewBatch()
+	call := batch.Call(method, params, result)
+	if err := batch.send(ctx, false); err != nil {
+		return err
+	}
+	return call.Err
+}
+
+// Notify invokes method without waiting for (or getting) a result
+func (r *RPCClient) Notify(ctx context.Context, method string, params interface{}) error {
+	batch := r.NewBatch()
+	batch.Notify(method, params)
+	return batch.send(ctx, false)
+}
+
+// 📦 BATCH
+type RPCBatch struct {
+	client   *RPCClient
+	requests []rpcRequest
+	calls    map[string]*RPCCall
+}
+
+// RPCCall is filled in by Send: Err is nil on success
+type RPCCall struct {
+	Method string
+	Err    error
+	result interface{}
+}
+
+func (r *RPCClient) NewBatch() *RPCBatch {
+	return &RPCBatch{client: r, calls: make(map[string]*RPCCall)}
+}
+
+func (b *RPCBatch) Call(method string, params, result interface{}) *RPCCall {
+	id := b.client.newID()
+	call := &RPCCall{Method: method, Err: ErrRPCNoResponse, result: result}
+	b.calls[string(id)] = call
+	b.requests = append(b.requests, rpcRequest{JSONRPC: "2.0", Method: method, Params: params, ID: id})
+	return call
+}
+
+func (b *RPCBatch) Notify(method string, params interface{}) {
+	b.requests = append(b.requests, rpcRequest{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// Send posts the whole batch in one request. The returned error covers
+// the HTTP exchange; per-call outcomes are in each RPCCall.Err.
+func (b *RPCBatch) Send(ctx context.Context) error {
+	return b.send(ctx, true)
+}
+
+func (b *RPCBatch) send(ctx context.Context, asArray bool) error {
+	if len(b.requests) == 0 {
+		return nil
+	}
+	var body interface{} = b.requests
+	if !asArray {
+		body = b.requests[0] // Single calls go out as a plain object
+	}
+	req, err := b.client.api.NewRequest(ctx, http.MethodPost, b.client.path, nil, body)
+	if err != nil {
+		return err
+	}
+
+	var raw json.RawMessage
+	if _, err := b.client.api.Do(req, &raw); err != nil {
+		return err
+	}
+	raw = bytes.TrimSpace(raw)
+	if len(b.calls) == 0 {
+		return nil // Only notifications: the server sends nothing back
+	}
+	if len(raw) == 0 {
+		return fmt.Errorf("jsonrpc: empty response to %d call(s)", len(b.calls))
+	}
+
+	var responses []rpcResponse
+	if raw[0] == '[' {
+		err = json.Unmarshal(raw, &responses)
+	} else {
+		// A single object: the answer to a lone call, or an error about
+		// the batch as a whole (id null)
+		var single rpcResponse
+		err = json.Unmarshal(raw, &single)
+		responses = []rpcResponse{single}
+	}
+	if err != nil {
+		return fmt.Errorf("jsonrpc: decode response: %w", err)
+	}
+
+	for _, resp := range responses {
+		if string(resp.ID) == "null" || len(resp.ID) == 0 {
+			if resp.Error != nil {
+				for _, call := range b.calls {
+					if call.Err == ErrRPCNoResponse {
+						call.Err = resp.Error
+					}
+				}
+			}
+			continue
+		}
+		call, ok := b.calls[string(resp.ID)]
+		if !ok {
+			continue // Not ours; ignore rather than fail the batch
+		}
+		call.Err = resp.decodeInto(call.result)
+	}
+	return nil
+}
+
+func (resp rpcResponse) decodeInto(result interface{}) error {
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("jsonrpc: decode result: %w", err)
+	}
+	return nil
+}
+
+// 🖥️ RPC HANDLER: A minimal JSON-RPC 2.0 server
+type RPCMethod func(ctx context.Context, params json.RawMessage) (interface{}, error)
+
+// RPCHandler dispatches single and batch requests to registered methods.
+// Returning an *RPCError from a method sends it as is; other errors
+// become RPCInternalError.
+type RPCHandler map[string]RPCMethod
+
+func (h RPCHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "JSON-RPC needs POST", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return
+	}
+	body = bytes.TrimSpace(body)
+
+	var reply interface{}
+	switch {
+	case len(body) > 0 && body[0] == '[':
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil || len(batch) == 0 {
+			reply = rpcErrorResponse(nil, RPCInvalidRequest, "invalid batch")
+			break
+		}
+		var replies []rpcResponse
+		for _, msg := range batch {
+			if resp, ok := h.handle(r.Context(), msg); ok {
+				replies = append(replies, resp)
+			}
+		}
+		if len(replies) > 0 {
+			reply = replies
+		}
+	default:
+		if resp, ok := h.handle(r.Context(), body); ok {
+			reply = resp
+		}
+	}
+
+	if reply == nil {
+		w.WriteHeader(http.StatusNoContent) // Notifications get no body
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reply)
+}
+
+// handle runs one request; ok is false for notifications
+func (h RPCHandler) handle(ctx context.Context, msg json.RawMessage) (rpcResponse, bool) {
+	var req struct {
+		JSONRPC string          `json:"jsonrpc"`
+		Method  string          `json:"method"`
+		Params  json.RawMessage `json:"params"`
+		ID      json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return rpcErrorResponse(nil, RPCParseError, "parse error"), true
+	}
+	id := req.ID
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return rpcErrorResponse(id, RPCInvalidRequest, "invalid request"), true
+	}
+
+	method, found := h[req.Method]
+	var result interface{}
+	var err error
+	if found {
+		result, err = method(ctx, req.Params)
+	}
+	if len(id) == 0 {
+		return rpcResponse{}, false // Notifications never get a reply, even on error
+	}
+
+	switch {
+	case !found:
+		return rpcErrorResponse(id, RPCMethodNotFound, "method not found: "+req.Method), true
+	case err != nil:
+		var rpcErr *RPCError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &RPCError{Code: RPCInternalError, Message: err.Error()}
+		}
+		return rpcResponse{JSONRPC: "2.0", Error: rpcErr, ID: id}, true
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return rpcErrorResponse(id, RPCInternalError, err.Error()), true
+	}
+	return rpcResponse{JSONRPC: "2.0", Result: data, ID: id}, true
+}
+
+func rpcErrorResponse(id json.RawMessage, code int, message string) rpcResponse {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return rpcResponse{JSONRPC: "2.0", Error: &RPCError{Code: code, Message: message}, ID: id}
+}
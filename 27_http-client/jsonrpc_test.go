@@ -0,0 +1,236 @@
+/*
+=============================================================================
+                    🧪 JSON-RPC TESTS
+=============================================================================
+
+Runs the RPCClient against RPCHandler on a local server, plus a
+hand-written server for out-of-order and whole-batch error replies.
+Run with: go test -v -run RPC *.go
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type rpcTestServer struct {
+	client *RPCClient
+	mu     sync.Mutex
+	logged []string
+	bodies []string
+}
+
+func newRPCTestServer(t *testing.T) *rpcTestServer {
+	t.Helper()
+	s := &rpcTestServer{}
+	handler := RPCHandler{
+		"add": func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			var nums []int
+			if err := json.Unmarshal(params, &nums); err != nil {
+				return nil, &RPCError{Code: RPCInvalidParams, Message: "want an array of numbers"}
+			}
+			sum := 0
+			for _, n := range nums {
+				sum += n
+			}
+			return sum, nil
+		},
+		"divide": func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			var p struct{ A, B float64 }
+			if err := json.Unmarshal(params, &p); err != nil {
+				return nil, &RPCError{Code: RPCInvalidParams, Message: err.Error()}
+			}
+			if p.B == 0 {
+				return nil, &RPCError{Code: 1001, Message: "division by zero", Data: json.RawMessage(`{"a":` + jsonNumber(p.A) + `}`)}
+			}
+			return p.A / p.B, nil
+		},
+		"log": func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			var msg string
+			json.Unmarshal(params, &msg)
+			s.mu.Lock()
+			s.logged = append(s.logged, msg)
+			s.mu.Unlock()
+			return nil, nil
+		},
+		"crash": func(ctx context.Context, params json.RawMessage) (interface{}, error) {
+			return nil, errors.New("database on fire")
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.bodies = append(s.bodies, string(body))
+		s.mu.Unlock()
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+		handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	api, err := NewAPIClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.client = NewRPCClient(api, "/rpc")
+	return s
+}
+
+func jsonNumber(f float64) string {
+	data, _ := json.Marshal(f)
+	return string(data)
+}
+
+func TestRPCCall(t *testing.T) {
+	s := newRPCTestServer(t)
+	ctx := context.Background()
+
+	var sum int
+	if err := s.client.Call(ctx, "add", []int{2, 3, 4}, &sum); err != nil || sum != 9 {
+		t.Fatalf("add = %d, %v; want 9", sum, err)
+	}
+	if !strings.Contains(s.bodies[0], `"jsonrpc":"2.0"`) || !strings.HasPrefix(s.bodies[0], "{") {
+		t.Errorf("single call should be a plain object: %s", s.bodies[0])
+	}
+
+	var quotient float64
+	if err := s.client.Call(ctx, "divide", map[string]int{"a": 7, "b": 2}, &quotient); err != nil || quotient != 3.5 {
+		t.Errorf("divide = %v, %v; want 3.5", quotient, err)
+	}
+
+	tests := []struct {
+		method   string
+		params   interface{}
+		wantCode int
+	}{
+		{"divide", map[string]int{"a": 1, "b": 0}, 1001},
+		{"add", "not an array", RPCInvalidParams},
+		{"nope", nil, RPCMethodNotFound},
+		{"crash", nil, RPCInternalError},
+	}
+	for _, tt := range tests {
+		err := s.client.Call(ctx, tt.method, tt.params, nil)
+		var rpcErr *RPCError
+		if !errors.As(err, &rpcErr) || rpcErr.Code != tt.wantCode {
+			t.Errorf("%s err = %v; want code %d", tt.method, err, tt.wantCode)
+		}
+	}
+}
+
+func TestRPCNotify(t *testing.T) {
+	s := newRPCTestServer(t)
+	if err := s.client.Notify(context.Background(), "log", "hello"); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	// Even a failing notification gets no reply
+	if err := s.client.Notify(context.Background(), "nope", nil); err != nil {
+		t.Fatalf("Notify unknown method: %v", err)
+	}
+	if strings.Contains(s.bodies[0], `"id"`) {
+		t.Errorf("notifications must not carry an id: %s", s.bodies[0])
+	}
+	if len(s.logged) != 1 || s.logged[0] != "hello" {
+		t.Errorf("logged = %v", s.logged)
+	}
+}
+
+func TestRPCBatch(t *testing.T) {
+	s := newRPCTestServer(t)
+
+	var sum int
+	var quotient float64
+	batch := s.client.NewBatch()
+	add := batch.Call("add", []int{1, 2}, &sum)
+	div := batch.Call("divide", map[string]int{"a": 1, "b": 0}, &quotient)
+	missing := batch.Call("nope", nil, nil)
+	batch.Notify("log", "batch sent")
+
+	if err := batch.Send(context.Background()); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(s.bodies) != 1 || !strings.HasPrefix(s.bodies[0], "[") {
+		t.Fatalf("batch should be one array request: %v", s.bodies)
+	}
+
+	if add.Err != nil || sum != 3 {
+		t.Errorf("add = %d, %v", sum, add.Err)
+	}
+	var rpcErr *RPCError
+	if !errors.As(div.Err, &rpcErr) || rpcErr.Code != 1001 || string(rpcErr.Data) != `{"a":1}` {
+		t.Errorf("divide err = %v; want code 1001 with data", div.Err)
+	}
+	if !errors.As(missing.Err, &rpcErr) || rpcErr.Code != RPCMethodNotFound {
+		t.Errorf("nope err = %v", missing.Err)
+	}
+	if len(s.logged) != 1 {
+		t.Errorf("notification in batch not delivered: %v", s.logged)
+	}
+
+	// A batch of only notifications gets no body back
+	onlyNotes := s.client.NewBatch()
+	onlyNotes.Notify("log", "a")
+	onlyNotes.Notify("log", "b")
+	if err := onlyNotes.Send(context.Background()); err != nil {
+		t.Errorf("notification batch: %v", err)
+	}
+}
+
+func TestRPCBatchOutOfOrderAndMissingReplies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Answers ids 2 then 1 and forgets id 3
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[
+			{"jsonrpc": "2.0", "result": "second", "id": 2},
+			{"jsonrpc": "2.0", "result": "first", "id": 1}
+		]`))
+	}))
+	defer server.Close()
+	api, _ := NewAPIClient(server.URL)
+	rpc := NewRPCClient(api, "/rpc")
+
+	var a, b, c string
+	batch := rpc.NewBatch()
+	first := batch.Call("one", nil, &a)
+	second := batch.Call("two", nil, &b)
+	third := batch.Call("three", nil, &c)
+	if err := batch.Send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if first.Err != nil || second.Err != nil || a != "first" || b != "second" {
+		t.Errorf("a = %q (%v), b = %q (%v)", a, first.Err, b, second.Err)
+	}
+	if !errors.Is(third.Err, ErrRPCNoResponse) {
+		t.Errorf("third err = %v; want ErrRPCNoResponse", third.Err)
+	}
+}
+
+func TestRPCWholeBatchError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"jsonrpc": "2.0", "error": {"code": -32700, "message": "parse error"}, "id": null}`))
+	}))
+	defer server.Close()
+	api, _ := NewAPIClient(server.URL)
+	rpc := NewRPCClient(api, "/rpc")
+
+	batch := rpc.NewBatch()
+	calls := []*RPCCall{batch.Call("one", nil, nil), batch.Call("two", nil, nil)}
+	if err := batch.Send(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for _, call := range calls {
+		var rpcErr *RPCError
+		if !errors.As(call.Err, &rpcErr) || rpcErr.Code != RPCParseError {
+			t.Errorf("%s err = %v; want the batch-wide parse error", call.Method, call.Err)
+		}
+	}
+}
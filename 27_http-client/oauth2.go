@@ -0,0 +1,326 @@
+/*
+=============================================================================
+                    🎟️ OAUTH2 TOKENS - HTTP CLIENT EXTENSION
+=============================================================================
+
+Fetches OAuth2 access tokens instead of hard-coding a bearer string:
+
+  cfg := OAuth2Config{
+      TokenURL:     "https://auth.example.com/oauth/token",
+      ClientID:     "reporting-job",
+      ClientSecret: os.Getenv("CLIENT_SECRET"),
+      Scopes:       []string{"posts:read"},
+  }
+  source := cfg.ClientCredentials()     // or cfg.RefreshToken(saved)
+  client, _ := NewAPIClient(baseURL,
+      WithTransport(NewOAuth2Transport(nil, source)))
+
+• Tokens are cached until Leeway (default 30s) before they expire
+• Concurrent callers share one token request (single-flight)
+• A 401 from the API drops the token, fetches a fresh one and retries
+  the request exactly once
+• Rotated refresh tokens are picked up automatically
+
+A TokenSource is also an Authenticator, for WithAuth(source) when the
+401 retry isn't needed. oauth2server.go has a small token endpoint so the
+whole flow runs on localhost.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTokenLeeway = 30 * time.Second
+	tokenFetchTimeout  = 30 * time.Second
+)
+
+// 🎟️ TOKEN
+type Token struct {
+	AccessToken  string
+	TokenType    string
+	RefreshToken string
+	Expiry       time.Time // Zero means it never expires
+}
+
+// valid reports whether the token is usable for at least leeway more
+func (t *Token) valid(now time.Time, leeway time.Duration) bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || now.Add(leeway).Before(t.Expiry)
+}
+
+func (t *Token) setAuthHeader(req *http.Request) {
+	tokenType := t.TokenType
+	if tokenType == "" || strings.EqualFold(tokenType, "bearer") {
+		tokenType = "Bearer"
+	}
+	req.Header.Set("Authorization", tokenType+" "+t.AccessToken)
+}
+
+// tokenResponse is the RFC 6749 section 5.1 wire format
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	ExpiresIn    int64  `json:"expires_in,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// ❌ OAUTH2 ERROR: RFC 6749 section 5.2 error response
+type OAuth2Error struct {
+	StatusCode  int
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *OAuth2Error) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("oauth2: %s (%d): %s", e.Code, e.StatusCode, e.Description)
+	}
+	return fmt.Sprintf("oauth2: %s (%d)", e.Code, e.StatusCode)
+}
+
+// ⚙️ CONFIG
+type OAuth2Config struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	HTTPClient   *http.Client // Default: http.DefaultClient
+}
+
+// ClientCredentials returns a source for the client-credentials grant
+func (c OAuth2Config) ClientCredentials() *TokenSource {
+	return newTokenSource(func(ctx context.Context, _ string) (*tokenResponse, error) {
+		form := url.Values{"grant_type": {"client_credentials"}}
+		if len(c.Scopes) > 0 {
+			form.Set("scope", strings.Join(c.Scopes, " "))
+		}
+		return c.exchange(ctx, form)
+	}, "")
+}
+
+// RefreshToken returns a source that trades refreshToken for access tokens
+func (c OAuth2Config) RefreshToken(refreshToken string) *TokenSource {
+	return newTokenSource(func(ctx context.Context, refresh string) (*tokenResponse, error) {
+		return c.exchange(ctx, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {refresh},
+		})
+	}, refreshToken)
+}
+
+// exchange POSTs a token request, authenticating with HTTP Basic
+func (c OAuth2Config) exchange(ctx context.Context, form url.Values) (*tokenResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: token request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("oauth2: read token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		oauthErr := &OAuth2Error{StatusCode: resp.StatusCode}
+		if json.Unmarshal(body, oauthErr) != nil || oauthErr.Code == "" {
+			oauthErr.Code = "server_error"
+			oauthErr.Description = strings.TrimSpace(string(body))
+		}
+		return nil, oauthErr
+	}
+
+	var tr tokenResponse
+	if err := json.Unmarshal(body, &tr); err != nil {
+		return nil, fmt.Errorf("oauth2: decode token response: %w", err)
+	}
+	if tr.AccessToken == "" {
+		return nil, fmt.Errorf("oauth2: token response has no access_token")
+	}
+	return &tr, nil
+}
+
+// 🔄 TOKEN SOURCE: Caching, single-flighted token fetches
+type TokenSource struct {
+	Leeway time.Duration // Refresh this long before expiry; default 30s
+
+	fetch func(ctx context.Context, refreshToken string) (*tokenResponse, error)
+	now   func() time.Time
+
+	mu       sync.Mutex
+	token    *Token
+	refresh  string     // Latest refresh token, kept across invalidation
+	inflight *tokenCall // Non-nil while a fetch is running
+}
+
+type tokenCall struct {
+	done  chan struct{}
+	token *Token
+	err   error
+}
+
+func newTokenSource(fetch func(context.Context, string) (*tokenResponse, error), refreshToken string) *TokenSource {
+	return &TokenSource{
+		Leeway:  defaultTokenLeeway,
+		fetch:   fetch,
+		now:     time.Now,
+		refresh: refreshToken,
+	}
+}
+
+// Token returns the cached token, fetching a new one when it is close to
+// expiry. Callers arriving during a fetch wait for that fetch.
+func (s *TokenSource) Token(ctx context.Context) (*Token, error) {
+	s.mu.Lock()
+	if s.token.valid(s.now(), s.Leeway) {
+		token := s.token
+		s.mu.Unlock()
+		return token, nil
+	}
+	call := s.inflight
+	if call == nil {
+		call = &tokenCall{done: make(chan struct{})}
+		s.inflight = call
+		// Detached from ctx: one caller giving up must not fail the others
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tokenFetchTimeout)
+		go func() {
+			defer cancel()
+			s.run(fetchCtx, call)
+		}()
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.token, call.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *TokenSource) run(ctx context.Context, call *tokenCall) {
+	s.mu.Lock()
+	refresh := s.refresh
+	s.mu.Unlock()
+
+	tr, err := s.fetch(ctx, refresh)
+
+	s.mu.Lock()
+	var token *Token
+	if err == nil {
+		token = &Token{AccessToken: tr.AccessToken, TokenType: tr.TokenType, RefreshToken: tr.RefreshToken}
+		if tr.ExpiresIn > 0 {
+			token.Expiry = s.now().Add(time.Duration(tr.ExpiresIn) * time.Second)
+		}
+		if token.RefreshToken == "" {
+			token.RefreshToken = refresh
+		} else {
+			s.refresh = token.RefreshToken // Servers may rotate it
+		}
+		s.token = token
+	}
+	s.inflight = nil
+	call.token, call.err = token, err
+	s.mu.Unlock()
+	close(call.done)
+}
+
+// Invalidate drops token if it is still the cached one, so the next
+// Token call fetches a fresh one. Stale tokens are ignored, which keeps
+// a burst of 401s from triggering a burst of refreshes.
+func (s *TokenSource) Invalidate(token *Token) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == token {
+		s.token = nil
+	}
+}
+
+// Apply makes a TokenSource usable as an Authenticator
+func (s *TokenSource) Apply(req *http.Request) error {
+	token, err := s.Token(req.Context())
+	if err != nil {
+		return err
+	}
+	token.setAuthHeader(req)
+	return nil
+}
+
+// 🔐 OAUTH2 TRANSPORT: Adds the token and retries once on 401
+type OAuth2Transport struct {
+	Base   http.RoundTripper
+	Source *TokenSource
+}
+
+func NewOAuth2Transport(base http.RoundTripper, source *TokenSource) *OAuth2Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &OAuth2Transport{Base: base, Source: source}
+}
+
+func (t *OAuth2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	token, err := t.Source.Token(ctx)
+	if err != nil {
+		closeRequestBody(req)
+		return nil, err
+	}
+
+	// RoundTrippers must not modify the caller's request
+	first := req.Clone(ctx)
+	token.setAuthHeader(first)
+	resp, err := t.Base.RoundTrip(first)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return resp, nil // The body is gone and can't be replayed
+	}
+
+	// The token was revoked or expired early: refresh and retry once
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	t.Source.Invalidate(token)
+
+	if token, err = t.Source.Token(ctx); err != nil {
+		return nil, err
+	}
+	retry, err := rewind(req)
+	if err != nil {
+		return nil, err
+	}
+	token.setAuthHeader(retry)
+	return t.Base.RoundTrip(retry)
+}
+
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
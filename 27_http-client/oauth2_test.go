@@ -0,0 +1,287 @@
+/*
+=============================================================================
+                    🧪 OAUTH2 TESTS
+=============================================================================
+
+Runs the client against the in-repo TokenServer on localhost, with a
+shared fake clock driving token expiry on both sides.
+Run with: go test -v -run OAuth2 *.go
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// lockedClock is a fakeClock that handler goroutines may read safely
+type lockedClock struct {
+	mu sync.Mutex
+	fakeClock
+}
+
+func (c *lockedClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fakeClock.Now()
+}
+
+func (c *lockedClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fakeClock.Advance(d)
+}
+
+type oauth2Env struct {
+	auth     *TokenServer
+	server   *httptest.Server
+	clock    *lockedClock
+	apiCalls atomic.Int64
+}
+
+// newOAuth2Env serves the token endpoint and a protected /me resource
+func newOAuth2Env(t *testing.T, ttl time.Duration, tokenDelay time.Duration) *oauth2Env {
+	t.Helper()
+	env := &oauth2Env{clock: &lockedClock{fakeClock: fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}}}
+	env.auth = NewTokenServer(map[string]string{"reporting-job": "s3cret"}, ttl)
+	env.auth.now = env.clock.Now
+
+	mux := http.NewServeMux()
+	mux.Handle("POST /oauth/token", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(tokenDelay) // Widens the window for concurrent refreshes
+		env.auth.ServeHTTP(w, r)
+	}))
+	mux.Handle("/me", env.auth.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		env.apiCalls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		w.Write(append([]byte("hello "), body...))
+	})))
+	env.server = httptest.NewServer(mux)
+	t.Cleanup(env.server.Close)
+	return env
+}
+
+func (env *oauth2Env) config(secret string) OAuth2Config {
+	return OAuth2Config{
+		TokenURL:     env.server.URL + "/oauth/token",
+		ClientID:     "reporting-job",
+		ClientSecret: secret,
+		Scopes:       []string{"posts:read"},
+	}
+}
+
+func (env *oauth2Env) source(ts *TokenSource) *TokenSource {
+	ts.now = env.clock.Now
+	ts.Leeway = 10 * time.Second
+	return ts
+}
+
+func TestOAuth2ClientCredentialsCachesUntilNearExpiry(t *testing.T) {
+	env := newOAuth2Env(t, time.Minute, 0)
+	source := env.source(env.config("s3cret").ClientCredentials())
+	ctx := context.Background()
+
+	first, err := source.Token(ctx)
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	again, _ := source.Token(ctx)
+	if again != first || env.auth.Issued() != 1 {
+		t.Fatalf("second call should hit the cache; issued = %d", env.auth.Issued())
+	}
+
+	env.clock.Advance(49 * time.Second) // 11s left, outside the 10s leeway
+	if tok, _ := source.Token(ctx); tok != first {
+		t.Error("token refreshed too early")
+	}
+
+	env.clock.Advance(2 * time.Second) // 9s left, inside the leeway
+	refreshed, err := source.Token(ctx)
+	if err != nil || refreshed == first {
+		t.Fatalf("token should refresh inside the leeway: %v", err)
+	}
+	if got := env.auth.Issued(); got != 2 {
+		t.Errorf("issued = %d; want 2", got)
+	}
+}
+
+func TestOAuth2ConcurrentRefreshIsSingleFlighted(t *testing.T) {
+	env := newOAuth2Env(t, time.Minute, 50*time.Millisecond)
+	source := env.source(env.config("s3cret").ClientCredentials())
+
+	var wg sync.WaitGroup
+	tokens := make([]*Token, 50)
+	for i := range tokens {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			tok, err := source.Token(context.Background())
+			if err != nil {
+				t.Errorf("Token: %v", err)
+			}
+			tokens[i] = tok
+		}()
+	}
+	wg.Wait()
+
+	if got := env.auth.Issued(); got != 1 {
+		t.Errorf("issued = %d; want 1 shared fetch", got)
+	}
+	for _, tok := range tokens {
+		if tok != tokens[0] {
+			t.Fatal("all callers should receive the same token")
+		}
+	}
+}
+
+func TestOAuth2CanceledCallerDoesNotFailOthers(t *testing.T) {
+	env := newOAuth2Env(t, time.Minute, 50*time.Millisecond)
+	source := env.source(env.config("s3cret").ClientCredentials())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := source.Token(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled caller err = %v; want context.Canceled", err)
+	}
+	if _, err := source.Token(context.Background()); err != nil {
+		t.Fatalf("patient caller err = %v", err)
+	}
+	if got := env.auth.Issued(); got != 1 {
+		t.Errorf("issued = %d; want the canceled caller's fetch to be shared", got)
+	}
+}
+
+func TestOAuth2TransportRetriesOnceOn401(t *testing.T) {
+	env := newOAuth2Env(t, time.Hour, 0)
+	source := env.source(env.config("s3cret").ClientCredentials())
+	client, err := NewAPIClient(env.server.URL, WithTransport(NewOAuth2Transport(nil, source)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	post := func() (int, string) {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, env.server.URL+"/me", strings.NewReader("ana"))
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	if status, body := post(); status != http.StatusOK || body != "hello ana" {
+		t.Fatalf("first call = %d %q", status, body)
+	}
+
+	// Revoked server-side while still cached client-side
+	tok, _ := source.Token(ctx)
+	env.auth.Revoke(tok.AccessToken)
+
+	if status, body := post(); status != http.StatusOK || body != "hello ana" {
+		t.Fatalf("after revoke = %d %q; want a transparent refresh with the body replayed", status, body)
+	}
+	if got := env.auth.Issued(); got != 2 {
+		t.Errorf("issued = %d; want exactly one refresh", got)
+	}
+	if got := env.apiCalls.Load(); got != 2 {
+		t.Errorf("successful API calls = %d; want 2", got)
+	}
+}
+
+func TestOAuth2TransportGivesUpAfterOneRetry(t *testing.T) {
+	var attempts atomic.Int64
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer api.Close()
+
+	env := newOAuth2Env(t, time.Hour, 0)
+	source := env.source(env.config("s3cret").ClientCredentials())
+	client, _ := NewAPIClient(api.URL, WithTransport(NewOAuth2Transport(nil, source)))
+
+	_, err := Get[map[string]any](context.Background(), client, "/me", nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("err = %v; want a 401 APIError", err)
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("attempts = %d; want exactly 2", got)
+	}
+	if got := env.auth.Issued(); got != 2 {
+		t.Errorf("issued = %d; want the original token plus one refresh", got)
+	}
+}
+
+func TestOAuth2RefreshTokenRotation(t *testing.T) {
+	env := newOAuth2Env(t, time.Minute, 0)
+	initial := env.auth.IssueRefreshToken("reporting-job", "posts:read")
+	source := env.source(env.config("s3cret").RefreshToken(initial))
+	ctx := context.Background()
+
+	first, err := source.Token(ctx)
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	if first.RefreshToken == "" || first.RefreshToken == initial {
+		t.Fatalf("refresh token should rotate, got %q", first.RefreshToken)
+	}
+
+	env.clock.Advance(time.Minute)
+	second, err := source.Token(ctx)
+	if err != nil {
+		t.Fatalf("refresh with the rotated token: %v", err)
+	}
+	if second.AccessToken == first.AccessToken || second.RefreshToken == first.RefreshToken {
+		t.Error("expected a new access and refresh token")
+	}
+
+	// The server burned the original refresh token
+	replayed := env.source(env.config("s3cret").RefreshToken(initial))
+	var oauthErr *OAuth2Error
+	if _, err := replayed.Token(ctx); !errors.As(err, &oauthErr) || oauthErr.Code != "invalid_grant" {
+		t.Errorf("reused refresh token err = %v; want invalid_grant", err)
+	}
+}
+
+func TestOAuth2BadCredentials(t *testing.T) {
+	env := newOAuth2Env(t, time.Minute, 0)
+	source := env.source(env.config("wrong").ClientCredentials())
+
+	for i := 0; i < 2; i++ {
+		var oauthErr *OAuth2Error
+		_, err := source.Token(context.Background())
+		if !errors.As(err, &oauthErr) || oauthErr.Code != "invalid_client" || oauthErr.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("call %d err = %v; want invalid_client", i+1, err)
+		}
+	}
+	if got := env.auth.Issued(); got != 0 {
+		t.Errorf("issued = %d; want 0", got)
+	}
+}
+
+func TestOAuth2TokenSourceAsAuthenticator(t *testing.T) {
+	env := newOAuth2Env(t, time.Hour, 0)
+	source := env.source(env.config("s3cret").ClientCredentials())
+	client, _ := NewAPIClient(env.server.URL, WithAuth(source))
+
+	req, err := client.NewRequest(context.Background(), http.MethodGet, "/me", nil, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	tok, _ := source.Token(context.Background())
+	if got := req.Header.Get("Authorization"); got != "Bearer "+tok.AccessToken {
+		t.Errorf("Authorization = %q", got)
+	}
+}
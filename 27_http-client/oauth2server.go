@@ -0,0 +1,200 @@
+/*
+=============================================================================
+                    🏛️ OAUTH2 TOKEN SERVER - HTTP CLIENT EXTENSION
+=============================================================================
+
+A deliberately small OAuth2 authorization server so the token flows in
+oauth2.go can be exercised on localhost (demo 9 and oauth2_test.go):
+
+  auth := NewTokenServer(map[string]string{"demo-client": "s3cret"}, time.Hour)
+  mux.Handle("POST /oauth/token", auth)        // Token endpoint
+  mux.Handle("GET /me", auth.Protect(handler)) // Requires a valid bearer
+
+Supported grants: client_credentials and refresh_token (refresh tokens
+rotate on every use). Clients authenticate with HTTP Basic or the
+client_id/client_secret form fields. Tokens live in memory only.
+
+⚠️ This is a teaching stub: no PKCE, no authorization-code flow, no
+persistence. Don't put it in front of anything real.
+*/
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type issuedToken struct {
+	clientID string
+	scope    string
+	expiry   time.Time
+}
+
+// 🏛️ TOKEN SERVER
+type TokenServer struct {
+	TTL time.Duration // Access token lifetime
+
+	clients map[string]string // client_id -> secret
+	now     func() time.Time
+
+	mu      sync.Mutex
+	access  map[string]issuedToken
+	refresh map[string]issuedToken // Refresh tokens never expire here
+	issued  int
+}
+
+func NewTokenServer(clients map[string]string, ttl time.Duration) *TokenServer {
+	return &TokenServer{
+		TTL:     ttl,
+		clients: clients,
+		now:     time.Now,
+		access:  make(map[string]issuedToken),
+		refresh: make(map[string]issuedToken),
+	}
+}
+
+// ServeHTTP is the token endpoint
+func (s *TokenServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeOAuth2Error(w, http.StatusMethodNotAllowed, "invalid_request", "token requests must be POST")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeOAuth2Error(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	clientID, ok := s.authenticate(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="token"`)
+		writeOAuth2Error(w, http.StatusUnauthorized, "invalid_client", "unknown client or bad secret")
+		return
+	}
+
+	switch grant := r.PostForm.Get("grant_type"); grant {
+	case "client_credentials":
+		s.writeToken(w, clientID, r.PostForm.Get("scope"), false)
+
+	case "refresh_token":
+		refresh := r.PostForm.Get("refresh_token")
+		s.mu.Lock()
+		old, found := s.refresh[refresh]
+		valid := found && old.clientID == clientID
+		if valid {
+			delete(s.refresh, refresh) // Rotation: each refresh token works once
+		}
+		s.mu.Unlock()
+		if !valid {
+			writeOAuth2Error(w, http.StatusBadRequest, "invalid_grant", "refresh token is invalid or already used")
+			return
+		}
+		s.writeToken(w, clientID, old.scope, true)
+
+	default:
+		writeOAuth2Error(w, http.StatusBadRequest, "unsupported_grant_type", "unsupported grant_type "+grant)
+	}
+}
+
+// authenticate checks HTTP Basic credentials, falling back to form fields
+func (s *TokenServer) authenticate(r *http.Request) (string, bool) {
+	id, secret, ok := r.BasicAuth()
+	if ok {
+		// RFC 6749 section 2.3.1: Basic credentials are form-encoded first
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	want, known := s.clients[id]
+	if !known || subtle.ConstantTimeCompare([]byte(want), []byte(secret)) != 1 {
+		return "", false
+	}
+	return id, true
+}
+
+func (s *TokenServer) writeToken(w http.ResponseWriter, clientID, scope string, withRefresh bool) {
+	s.mu.Lock()
+	access := randomToken()
+	s.access[access] = issuedToken{clientID: clientID, scope: scope, expiry: s.now().Add(s.TTL)}
+	resp := tokenResponse{
+		AccessToken: access,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.TTL / time.Second),
+		Scope:       scope,
+	}
+	if withRefresh {
+		resp.RefreshToken = randomToken()
+		s.refresh[resp.RefreshToken] = issuedToken{clientID: clientID, scope: scope}
+	}
+	s.issued++
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// IssueRefreshToken stands in for the user-consent step that would
+// normally hand a refresh token to the client
+func (s *TokenServer) IssueRefreshToken(clientID, scope string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token := randomToken()
+	s.refresh[token] = issuedToken{clientID: clientID, scope: scope}
+	return token
+}
+
+// Revoke invalidates an access token before it expires
+func (s *TokenServer) Revoke(accessToken string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.access, accessToken)
+}
+
+// Issued counts access tokens handed out so far
+func (s *TokenServer) Issued() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.issued
+}
+
+// Protect rejects requests without a valid, unexpired bearer token
+func (s *TokenServer) Protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		s.mu.Lock()
+		issued, ok := s.access[token]
+		valid := found && ok && s.now().Before(issued.expiry)
+		s.mu.Unlock()
+
+		if !valid {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeOAuth2Error(w, http.StatusUnauthorized, "invalid_token", "missing, expired or revoked access token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeOAuth2Error(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": description})
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
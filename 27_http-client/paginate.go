@@ -0,0 +1,328 @@
+/*
+=============================================================================
+                    📚 PAGINATION - HTTP CLIENT EXTENSION
+=============================================================================
+
+Walks a list endpoint page by page and yields items as an iter.Seq2:
+
+  pages := PagePagination{PageParam: "_page", PerPageParam: "_limit", PerPage: 10}
+  for post, err := range Paginate[BlogPost](ctx, client, "/posts", nil, pages, WithMaxItems(25)) {
+      if err != nil {
+          return err
+      }
+      fmt.Println(post.Title)
+  }
+
+Three strategies cover most APIs:
+• LinkPagination   - follows Link: <...>; rel="next" (GitHub style)
+• PagePagination   - bumps ?page=N until a short page comes back
+• CursorPagination - reads a cursor token from the body ({"data": [...], "next_cursor": "..."})
+
+The next page is fetched in the background while the current one is
+being consumed. Breaking out of the loop cancels that prefetch.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PageRequest identifies one page: a path (or absolute URL) plus query
+type PageRequest struct {
+	Path  string
+	Query url.Values
+}
+
+// 📑 PAGINATION STRATEGY
+type Pagination interface {
+	// First adds the strategy's parameters to the initial query
+	First(query url.Values) url.Values
+	// Next extracts the page's JSON item array and the following page
+	// (nil when this was the last one)
+	Next(resp *http.Response, body []byte, current PageRequest) (items []byte, next *PageRequest, err error)
+}
+
+// 🔗 LINK HEADER PAGINATION
+type LinkPagination struct{}
+
+func (LinkPagination) First(query url.Values) url.Values { return query }
+
+func (LinkPagination) Next(resp *http.Response, body []byte, current PageRequest) ([]byte, *PageRequest, error) {
+	target, ok := parseLinkHeader(resp.Header.Values("Link"))["next"]
+	if !ok {
+		return body, nil, nil
+	}
+	ref, err := url.Parse(target)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid next link %q: %w", target, err)
+	}
+	if resp.Request != nil {
+		ref = resp.Request.URL.ResolveReference(ref)
+	}
+	return body, &PageRequest{Path: ref.String()}, nil
+}
+
+// 🔢 PAGE NUMBER PAGINATION
+type PagePagination struct {
+	PageParam    string // Default "page"
+	PerPageParam string // Default "per_page"
+	PerPage      int    // Default 20
+	FirstPage    int    // Default 1
+}
+
+func (p PagePagination) withDefaults() PagePagination {
+	if p.PageParam == "" {
+		p.PageParam = "page"
+	}
+	if p.PerPageParam == "" {
+		p.PerPageParam = "per_page"
+	}
+	if p.PerPage <= 0 {
+		p.PerPage = 20
+	}
+	if p.FirstPage == 0 {
+		p.FirstPage = 1
+	}
+	return p
+}
+
+func (p PagePagination) First(query url.Values) url.Values {
+	p = p.withDefaults()
+	q := cloneValues(query)
+	q.Set(p.PageParam, strconv.Itoa(p.FirstPage))
+	q.Set(p.PerPageParam, strconv.Itoa(p.PerPage))
+	return q
+}
+
+func (p PagePagination) Next(resp *http.Response, body []byte, current PageRequest) ([]byte, *PageRequest, error) {
+	p = p.withDefaults()
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return nil, nil, fmt.Errorf("decode page: %w", err)
+	}
+	if len(items) < p.PerPage {
+		return body, nil, nil // Short page: nothing after this
+	}
+
+	page, err := strconv.Atoi(current.Query.Get(p.PageParam))
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid %s parameter: %w", p.PageParam, err)
+	}
+	q := cloneValues(current.Query)
+	q.Set(p.PageParam, strconv.Itoa(page+1))
+	return body, &PageRequest{Path: current.Path, Query: q}, nil
+}
+
+// 🧭 CURSOR PAGINATION
+type CursorPagination struct {
+	Param       string // Query parameter carrying the cursor (default "cursor")
+	ItemsField  string // Body field holding the items (default "data")
+	CursorField string // Body field holding the next cursor (default "next_cursor")
+}
+
+func (CursorPagination) First(query url.Values) url.Values { return query }
+
+func (p CursorPagination) Next(resp *http.Response, body []byte, current PageRequest) ([]byte, *PageRequest, error) {
+	param, itemsField, cursorField := p.Param, p.ItemsField, p.CursorField
+	if param == "" {
+		param = "cursor"
+	}
+	if itemsField == "" {
+		itemsField = "data"
+	}
+	if cursorField == "" {
+		cursorField = "next_cursor"
+	}
+
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, nil, fmt.Errorf("decode page: %w", err)
+	}
+	items, ok := envelope[itemsField]
+	if !ok {
+		return nil, nil, fmt.Errorf("decode page: missing %q field", itemsField)
+	}
+
+	var cursor string
+	if raw, ok := envelope[cursorField]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &cursor); err != nil {
+			return nil, nil, fmt.Errorf("decode %q: %w", cursorField, err)
+		}
+	}
+	if cursor == "" {
+		return items, nil, nil
+	}
+
+	q := cloneValues(current.Query)
+	q.Set(param, cursor)
+	return items, &PageRequest{Path: current.Path, Query: q}, nil
+}
+
+// ⚙️ PAGINATE OPTIONS
+type PageOption func(*pageConfig)
+
+type pageConfig struct {
+	maxItems int
+	prefetch bool
+}
+
+// WithMaxItems stops after n items (0 means no limit)
+func WithMaxItems(n int) PageOption {
+	return func(c *pageConfig) { c.maxItems = n }
+}
+
+// WithPrefetch toggles fetching the next page in the background (default on)
+func WithPrefetch(enabled bool) PageOption {
+	return func(c *pageConfig) { c.prefetch = enabled }
+}
+
+type pageResult[T any] struct {
+	items []T
+	next  *PageRequest
+	err   error
+}
+
+// 🔄 PAGINATE: Yields every item across pages; an error ends the sequence
+func Paginate[T any](ctx context.Context, c *APIClient, path string, query url.Values, p Pagination, opts ...PageOption) iter.Seq2[T, error] {
+	cfg := pageConfig{prefetch: true}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(yield func(T, error) bool) {
+		var zero T
+		fetchCtx, cancel := context.WithCancel(ctx)
+		defer cancel() // Abandons any in-flight prefetch
+
+		fetch := func(pr PageRequest) <-chan pageResult[T] {
+			ch := make(chan pageResult[T], 1) // Buffered so an abandoned fetch can't leak
+			go func() {
+				items, next, err := fetchPage[T](fetchCtx, c, p, pr)
+				ch <- pageResult[T]{items, next, err}
+			}()
+			return ch
+		}
+
+		current := PageRequest{Path: path, Query: p.First(query)}
+		pending := fetch(current)
+		yielded := 0
+
+		for pending != nil {
+			page := <-pending
+			pending = nil
+			if page.err != nil {
+				yield(zero, page.err)
+				return
+			}
+
+			next := page.next
+			if next != nil && samePage(*next, current) {
+				yield(zero, fmt.Errorf("pagination: next page repeats %s", current.Path))
+				return
+			}
+			if cfg.maxItems > 0 && yielded+len(page.items) >= cfg.maxItems {
+				next = nil // This page is enough; don't fetch another
+			}
+			if next != nil && cfg.prefetch {
+				pending = fetch(*next)
+			}
+
+			for _, item := range page.items {
+				if err := ctx.Err(); err != nil {
+					yield(zero, err)
+					return
+				}
+				if !yield(item, nil) {
+					return
+				}
+				yielded++
+				if cfg.maxItems > 0 && yielded >= cfg.maxItems {
+					return
+				}
+			}
+
+			if next != nil {
+				current = *next
+				if pending == nil {
+					pending = fetch(current)
+				}
+			}
+		}
+	}
+}
+
+func fetchPage[T any](ctx context.Context, c *APIClient, p Pagination, pr PageRequest) ([]T, *PageRequest, error) {
+	req, err := c.NewRequest(ctx, http.MethodGet, pr.Path, pr.Query, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var body json.RawMessage
+	resp, err := c.Do(req, &body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw, next, err := p.Next(resp, body, pr)
+	if err != nil {
+		return nil, nil, err
+	}
+	var items []T
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, nil, fmt.Errorf("decode page items: %w", err)
+	}
+	return items, next, nil
+}
+
+func samePage(a, b PageRequest) bool {
+	return a.Path == b.Path && a.Query.Encode() == b.Query.Encode()
+}
+
+func cloneValues(v url.Values) url.Values {
+	clone := url.Values{}
+	for key, values := range v {
+		clone[key] = append([]string(nil), values...)
+	}
+	return clone
+}
+
+// parseLinkHeader maps rel -> URL for `<url>; rel="next", <url>; rel="last"`
+func parseLinkHeader(values []string) map[string]string {
+	links := map[string]string{}
+	for _, value := range values {
+		for value != "" {
+			start := strings.IndexByte(value, '<')
+			end := strings.IndexByte(value, '>')
+			if start < 0 || end < start {
+				break
+			}
+			target := value[start+1 : end]
+			value = value[end+1:]
+
+			params := value
+			if next := strings.IndexByte(value, '<'); next >= 0 {
+				params, value = value[:next], value[next:]
+			} else {
+				value = ""
+			}
+			for _, param := range strings.Split(params, ";") {
+				name, val, ok := strings.Cut(strings.TrimSpace(param), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(val), `",`)) {
+					links[strings.ToLower(rel)] = target
+				}
+			}
+		}
+	}
+	return links
+}
@@ -0,0 +1,264 @@
+/*
+=============================================================================
+                    🧪 PAGINATION TESTS
+=============================================================================
+
+Serves 7 numbered items from a local httptest.Server using each
+pagination style.
+Run with: go test -v -run Paginat *.go
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type numbered struct {
+	ID int `json:"id"`
+}
+
+const totalItems = 7
+
+func itemsPage(offset, limit int) []numbered {
+	var page []numbered
+	for id := offset + 1; id <= min(offset+limit, totalItems); id++ {
+		page = append(page, numbered{ID: id})
+	}
+	return page
+}
+
+// newPagedServer serves the same items in every style and counts requests
+func newPagedServer(t *testing.T, requests *atomic.Int64) *APIClient {
+	t.Helper()
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/link", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		if offset+3 < totalItems {
+			w.Header().Set("Link", fmt.Sprintf(`</link?offset=%d>; rel="next", </link?offset=6>; rel="last"`, offset+3))
+		}
+		json.NewEncoder(w).Encode(itemsPage(offset, 3))
+	})
+
+	mux.HandleFunc("/pages", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		items := itemsPage((page-1)*perPage, perPage)
+		if items == nil {
+			items = []numbered{}
+		}
+		json.NewEncoder(w).Encode(items)
+	})
+
+	mux.HandleFunc("/cursor", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		offset, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		body := map[string]interface{}{"data": itemsPage(offset, 3), "next_cursor": nil}
+		if offset+3 < totalItems {
+			body["next_cursor"] = strconv.Itoa(offset + 3)
+		}
+		json.NewEncoder(w).Encode(body)
+	})
+
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Query().Get("page") == "2" {
+			http.Error(w, `{"error": "page store offline"}`, http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(itemsPage(0, 2))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client, err := NewAPIClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func collectIDs(t *testing.T, seq iter.Seq2[numbered, error]) ([]int, error) {
+	t.Helper()
+	var ids []int
+	for item, err := range seq {
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, item.ID)
+	}
+	return ids, nil
+}
+
+func TestPaginateStrategies(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		pagination   Pagination
+		wantRequests int64
+	}{
+		{"link header", "/link", LinkPagination{}, 3},
+		{"page params", "/pages", PagePagination{PerPage: 3}, 3},
+		{"page params exact multiple", "/pages", PagePagination{PerPage: 7}, 2},
+		{"cursor", "/cursor", CursorPagination{}, 3},
+	}
+
+	want := []int{1, 2, 3, 4, 5, 6, 7}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int64
+			client := newPagedServer(t, &requests)
+
+			ids, err := collectIDs(t, Paginate[numbered](context.Background(), client, tt.path, nil, tt.pagination))
+			if err != nil {
+				t.Fatalf("Paginate: %v", err)
+			}
+			if !reflect.DeepEqual(ids, want) {
+				t.Errorf("ids = %v; want %v", ids, want)
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("requests = %d; want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestPaginateMaxItems(t *testing.T) {
+	var requests atomic.Int64
+	client := newPagedServer(t, &requests)
+
+	ids, err := collectIDs(t, Paginate[numbered](context.Background(), client, "/cursor", nil, CursorPagination{}, WithMaxItems(5)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("ids = %v; want first 5", ids)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("requests = %d; want 2 (no page beyond the cap)", got)
+	}
+}
+
+func TestPaginatePrefetchesNextPage(t *testing.T) {
+	var requests atomic.Int64
+	client := newPagedServer(t, &requests)
+
+	for item, err := range Paginate[numbered](context.Background(), client, "/cursor", nil, CursorPagination{}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if item.ID == 1 {
+			// Still on page 1, but page 2 should already be on its way
+			deadline := time.Now().Add(2 * time.Second)
+			for requests.Load() < 2 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if got := requests.Load(); got != 2 {
+				t.Errorf("requests while reading page 1 = %d; want 2", got)
+			}
+		}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		var requests atomic.Int64
+		client := newPagedServer(t, &requests)
+		for item, err := range Paginate[numbered](context.Background(), client, "/cursor", nil, CursorPagination{}, WithPrefetch(false)) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item.ID == 3 && requests.Load() != 1 {
+				t.Errorf("requests while reading page 1 = %d; want 1", requests.Load())
+			}
+		}
+	})
+}
+
+func TestPaginateStopsEarly(t *testing.T) {
+	var requests atomic.Int64
+	client := newPagedServer(t, &requests)
+
+	var ids []int
+	for item, err := range Paginate[numbered](context.Background(), client, "/link", nil, LinkPagination{}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, item.ID)
+		if item.ID == 2 {
+			break
+		}
+	}
+	if !reflect.DeepEqual(ids, []int{1, 2}) {
+		t.Errorf("ids = %v; want [1 2]", ids)
+	}
+}
+
+func TestPaginateErrors(t *testing.T) {
+	t.Run("failed page ends the sequence", func(t *testing.T) {
+		var requests atomic.Int64
+		client := newPagedServer(t, &requests)
+
+		ids, err := collectIDs(t, Paginate[numbered](context.Background(), client, "/broken", nil, PagePagination{PerPage: 2}))
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("err = %v; want *APIError 500", err)
+		}
+		if !reflect.DeepEqual(ids, []int{1, 2}) {
+			t.Errorf("ids before error = %v; want [1 2]", ids)
+		}
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		var requests atomic.Int64
+		client := newPagedServer(t, &requests)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var ids []int
+		var gotErr error
+		for item, err := range Paginate[numbered](ctx, client, "/link", nil, LinkPagination{}) {
+			if err != nil {
+				gotErr = err
+				break
+			}
+			ids = append(ids, item.ID)
+			cancel()
+		}
+		if !errors.Is(gotErr, context.Canceled) {
+			t.Errorf("err = %v; want context.Canceled", gotErr)
+		}
+		if len(ids) != 1 {
+			t.Errorf("ids = %v; want exactly one item before cancellation", ids)
+		}
+	})
+}
+
+func TestParseLinkHeader(t *testing.T) {
+	links := parseLinkHeader([]string{
+		`<https://api.example.com/items?page=2>; rel="next", <https://api.example.com/items?page=9>; rel="last"`,
+		`<https://api.example.com/items?page=1>; rel="first prev"`,
+	})
+	want := map[string]string{
+		"next":  "https://api.example.com/items?page=2",
+		"last":  "https://api.example.com/items?page=9",
+		"first": "https://api.example.com/items?page=1",
+		"prev":  "https://api.example.com/items?page=1",
+	}
+	if !reflect.DeepEqual(links, want) {
+		t.Errorf("links = %v; want %v", links, want)
+	}
+}
@@ -0,0 +1,273 @@
+/*
+=============================================================================
+                    🔁 RETRY TRANSPORT - HTTP CLIENT EXTENSION
+=============================================================================
+
+An http.RoundTripper that retries transient failures with exponential
+backoff and jitter:
+
+  client, _ := NewAPIClient(baseURL, WithTransport(
+      NewRetryTransport(http.DefaultTransport, RetryPolicy{
+          MaxAttempts: 4,
+          BaseDelay:   200 * time.Millisecond,
+          MaxDelay:    5 * time.Second,
+          Jitter:      DecorrelatedJitter,
+      })))
+
+• Retries connection errors and 429/500/502/503/504 responses
+• Honours Retry-After (seconds or HTTP date)
+• Only retries idempotent methods, or any method carrying Idempotency-Key
+• Rewinds request bodies via Request.GetBody; bodies that can't be
+  rewound are sent exactly once
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 🎲 JITTER STRATEGIES (see "Exponential Backoff And Jitter", AWS blog)
+type JitterStrategy int
+
+const (
+	FullJitter         JitterStrategy = iota // random(0, min(max, base*2^n))
+	DecorrelatedJitter                       // min(max, random(base, prev*3))
+	NoJitter                                 // min(max, base*2^n)
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// ⚙️ RETRY POLICY: How many tries, how far apart, and which failures count
+type RetryPolicy struct {
+	MaxAttempts int // Total tries including the first (default 3)
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	Jitter      JitterStrategy
+
+	// ShouldRetry overrides the default classification when set
+	ShouldRetry func(resp *http.Response, err error) bool
+}
+
+var defaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+// withDefaults fills unset fields from defaultRetryPolicy
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaultRetryPolicy.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = defaultRetryPolicy.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = defaultRetryPolicy.MaxDelay
+	}
+	if p.MaxDelay < p.BaseDelay {
+		p.MaxDelay = p.BaseDelay
+	}
+	return p
+}
+
+// 🔁 RETRY TRANSPORT: A struct literal works too; unset fields get the
+// same defaults NewRetryTransport would fill in
+type RetryTransport struct {
+	Base   http.RoundTripper
+	Policy RetryPolicy
+
+	// Injection points for tests
+	sleep func(ctx context.Context, d time.Duration) error
+	mu    sync.Mutex
+	rng   *rand.Rand
+}
+
+func NewRetryTransport(base http.RoundTripper, policy RetryPolicy) *RetryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &RetryTransport{
+		Base:   base,
+		Policy: policy.withDefaults(),
+		sleep:  sleepContext,
+		rng:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base, sleep := t.Base, t.sleep
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if sleep == nil {
+		sleep = sleepContext
+	}
+	if !t.canRetry(req) {
+		return base.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	policy := t.Policy.withDefaults()
+	prevDelay := policy.BaseDelay
+
+	for attempt := 1; ; attempt++ {
+		attemptReq := req
+		if attempt > 1 {
+			var err error
+			if attemptReq, err = rewind(req); err != nil {
+				// RoundTrippers must close the body, even on errors
+				if req.Body != nil {
+					req.Body.Close()
+				}
+				return nil, err
+			}
+		}
+
+		resp, err := base.RoundTrip(attemptReq)
+		if attempt >= policy.MaxAttempts || !t.shouldRetry(ctx, resp, err) {
+			return resp, err
+		}
+
+		delay := t.backoff(attempt, prevDelay)
+		prevDelay = delay
+		if retryAfter, ok := parseRetryAfter(resp); ok {
+			if retryAfter > policy.MaxDelay {
+				// The server wants a longer pause than we are willing to wait
+				return resp, err
+			}
+			delay = max(delay, retryAfter)
+		}
+
+		if resp != nil {
+			// Drain so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// canRetry: idempotent method (or explicit key) and a rewindable body
+func (t *RetryTransport) canRetry(req *http.Request) bool {
+	if t.Policy.withDefaults().MaxAttempts <= 1 {
+		return false
+	}
+	if !isIdempotent(req.Method) && req.Header.Get(idempotencyKeyHeader) == "" {
+		return false
+	}
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	return !hasBody || req.GetBody != nil
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func (t *RetryTransport) shouldRetry(ctx context.Context, resp *http.Response, err error) bool {
+	if ctx.Err() != nil {
+		return false // Caller gave up; don't keep trying
+	}
+	if t.Policy.ShouldRetry != nil {
+		return t.Policy.ShouldRetry(resp, err)
+	}
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError,
+		http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// backoff returns the pause after the given (1-based) failed attempt
+func (t *RetryTransport) backoff(attempt int, prev time.Duration) time.Duration {
+	policy := t.Policy.withDefaults()
+	base, maxDelay := policy.BaseDelay, policy.MaxDelay
+
+	exp := time.Duration(float64(base) * math.Pow(2, float64(attempt-1)))
+	if exp <= 0 || exp > maxDelay {
+		exp = maxDelay // Also guards against overflow
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.rng == nil {
+		t.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+
+	switch policy.Jitter {
+	case NoJitter:
+		return exp
+	case DecorrelatedJitter:
+		upper := min(maxDelay, prev*3)
+		if upper <= base {
+			return base
+		}
+		return base + time.Duration(t.rng.Int63n(int64(upper-base)+1))
+	default:
+		return time.Duration(t.rng.Int63n(int64(exp) + 1))
+	}
+}
+
+// rewind makes a fresh copy of req with a new body from GetBody
+func rewind(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+	return clone, nil
+}
+
+// parseRetryAfter reads delay-seconds or an HTTP-date
+func parseRetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if when, err := http.ParseTime(value); err == nil {
+		return max(0, time.Until(when)), true
+	}
+	return 0, false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,347 @@
+/*
+=============================================================================
+                    🧪 RETRY TRANSPORT TESTS
+=============================================================================
+
+Drives RetryTransport against a flaky local httptest.Server.
+Run with: go test -v -run Retry *.go
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 🧰 FLAKY SERVER: Fails the first `failures` requests with `status`
+// (status 0 means "reset the connection" instead)
+type flakyServer struct {
+	*httptest.Server
+	attempts atomic.Int64
+	mu       sync.Mutex
+	bodies   []string
+}
+
+func newFlakyServer(t *testing.T, failures int64, status int, header http.Header) *flakyServer {
+	t.Helper()
+	fs := &flakyServer{}
+	fs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := fs.attempts.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		fs.mu.Lock()
+		fs.bodies = append(fs.bodies, string(body))
+		fs.mu.Unlock()
+
+		if n <= failures {
+			if status == 0 {
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			io.WriteString(w, "try again")
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+// newTestRetryTransport records delays instead of sleeping
+func newTestRetryTransport(policy RetryPolicy, delays *[]time.Duration) *RetryTransport {
+	rt := NewRetryTransport(nil, policy)
+	rt.sleep = func(ctx context.Context, d time.Duration) error {
+		*delays = append(*delays, d)
+		return ctx.Err()
+	}
+	return rt
+}
+
+func TestRetryRecoversFromTransientErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{"503", http.StatusServiceUnavailable},
+		{"502", http.StatusBadGateway},
+		{"429", http.StatusTooManyRequests},
+		{"connection reset", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFlakyServer(t, 2, tt.status, nil)
+			var delays []time.Duration
+			client := &http.Client{Transport: newTestRetryTransport(RetryPolicy{MaxAttempts: 3}, &delays)}
+
+			resp, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("status = %d; want 200", resp.StatusCode)
+			}
+			if got := server.attempts.Load(); got != 3 {
+				t.Errorf("attempts = %d; want 3", got)
+			}
+			if len(delays) != 2 {
+				t.Errorf("slept %d times; want 2", len(delays))
+			}
+		})
+	}
+}
+
+func TestRetryTransportLiteral(t *testing.T) {
+	for name, rt := range map[string]*RetryTransport{
+		"zero value":  {},
+		"policy only": {Policy: RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := newFlakyServer(t, 2, http.StatusServiceUnavailable, nil)
+			resp, err := (&http.Client{Transport: rt}).Get(server.URL)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK || server.attempts.Load() != 3 {
+				t.Errorf("status %d after %d attempts; want 200 after 3", resp.StatusCode, server.attempts.Load())
+			}
+		})
+	}
+}
+
+func TestRetryGivesUpAfterMaxAttempts(t *testing.T) {
+	server := newFlakyServer(t, 10, http.StatusServiceUnavailable, nil)
+	var delays []time.Duration
+	client := &http.Client{Transport: newTestRetryTransport(RetryPolicy{MaxAttempts: 4}, &delays)}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusServiceUnavailable || string(body) != "try again" {
+		t.Errorf("final response = %d %q; want the last 503 with its body", resp.StatusCode, body)
+	}
+	if got := server.attempts.Load(); got != 4 {
+		t.Errorf("attempts = %d; want 4", got)
+	}
+}
+
+func TestRetryDoesNotRetryClientErrors(t *testing.T) {
+	server := newFlakyServer(t, 10, http.StatusBadRequest, nil)
+	var delays []time.Duration
+	client := &http.Client{Transport: newTestRetryTransport(RetryPolicy{MaxAttempts: 4}, &delays)}
+
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := server.attempts.Load(); got != 1 {
+		t.Errorf("attempts = %d; want 1 for a 400", got)
+	}
+}
+
+func TestRetryNonIdempotentMethods(t *testing.T) {
+	t.Run("POST without key is sent once", func(t *testing.T) {
+		server := newFlakyServer(t, 1, http.StatusServiceUnavailable, nil)
+		var delays []time.Duration
+		client := &http.Client{Transport: newTestRetryTransport(RetryPolicy{MaxAttempts: 3}, &delays)}
+
+		resp, err := client.Post(server.URL, "text/plain", strings.NewReader("payload"))
+		if err != nil {
+			t.Fatalf("Post: %v", err)
+		}
+		resp.Body.Close()
+
+		if got := server.attempts.Load(); got != 1 {
+			t.Errorf("attempts = %d; want 1", got)
+		}
+	})
+
+	t.Run("POST with Idempotency-Key is retried with the same body", func(t *testing.T) {
+		server := newFlakyServer(t, 2, http.StatusServiceUnavailable, nil)
+		var delays []time.Duration
+		client := &http.Client{Transport: newTestRetryTransport(RetryPolicy{MaxAttempts: 3}, &delays)}
+
+		req, _ := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("payload"))
+		req.Header.Set(idempotencyKeyHeader, "order-42")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("status = %d; want 200", resp.StatusCode)
+		}
+		for i, body := range server.bodies {
+			if body != "payload" {
+				t.Errorf("attempt %d body = %q; want rewound %q", i+1, body, "payload")
+			}
+		}
+	})
+
+	t.Run("body without GetBody is not retried", func(t *testing.T) {
+		server := newFlakyServer(t, 1, http.StatusServiceUnavailable, nil)
+		var delays []time.Duration
+		client := &http.Client{Transport: newTestRetryTransport(RetryPolicy{MaxAttempts: 3}, &delays)}
+
+		req, _ := http.NewRequest(http.MethodPut, server.URL, io.NopCloser(strings.NewReader("stream")))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do: %v", err)
+		}
+		resp.Body.Close()
+
+		if got := server.attempts.Load(); got != 1 {
+			t.Errorf("attempts = %d; want 1 for a non-rewindable body", got)
+		}
+	})
+}
+
+// stubTransport answers 503 without reading or closing the request body
+type stubTransport struct{}
+
+func (stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody, Request: req}, nil
+}
+
+type trackingBody struct {
+	io.Reader
+	closed atomic.Bool
+}
+
+func (b *trackingBody) Close() error {
+	b.closed.Store(true)
+	return nil
+}
+
+func TestRetryClosesBodyWhenRewindFails(t *testing.T) {
+	var delays []time.Duration
+	rt := newTestRetryTransport(RetryPolicy{MaxAttempts: 3}, &delays)
+	rt.Base = stubTransport{}
+
+	body := &trackingBody{Reader: strings.NewReader("payload")}
+	req, _ := http.NewRequest(http.MethodPut, "http://example.invalid/", body)
+	errGone := errors.New("body source is gone")
+	req.GetBody = func() (io.ReadCloser, error) { return nil, errGone }
+
+	if _, err := rt.RoundTrip(req); !errors.Is(err, errGone) {
+		t.Fatalf("RoundTrip = %v; want the GetBody error", err)
+	}
+	if !body.closed.Load() {
+		t.Error("request body left open after rewinding failed")
+	}
+}
+
+func TestRetryHonoursRetryAfter(t *testing.T) {
+	t.Run("seconds", func(t *testing.T) {
+		server := newFlakyServer(t, 1, http.StatusTooManyRequests, http.Header{"Retry-After": {"2"}})
+		var delays []time.Duration
+		policy := RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Second}
+		client := &http.Client{Transport: newTestRetryTransport(policy, &delays)}
+
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		resp.Body.Close()
+
+		if len(delays) != 1 || delays[0] != 2*time.Second {
+			t.Errorf("delays = %v; want [2s]", delays)
+		}
+	})
+
+	t.Run("longer than MaxDelay returns the response", func(t *testing.T) {
+		server := newFlakyServer(t, 1, http.StatusServiceUnavailable, http.Header{"Retry-After": {"3600"}})
+		var delays []time.Duration
+		client := &http.Client{Transport: newTestRetryTransport(RetryPolicy{MaxAttempts: 3}, &delays)}
+
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusServiceUnavailable || len(delays) != 0 {
+			t.Errorf("got %d after %d sleeps; want the 503 without waiting", resp.StatusCode, len(delays))
+		}
+	})
+}
+
+func TestRetryStopsWhenContextCancelled(t *testing.T) {
+	server := newFlakyServer(t, 10, http.StatusServiceUnavailable, nil)
+	rt := NewRetryTransport(nil, RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour, Jitter: NoJitter})
+	client := &http.Client{Transport: rt}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+
+	start := time.Now()
+	_, err := client.Do(req)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v; want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %v; backoff should stop on cancellation", elapsed)
+	}
+}
+
+func TestRetryBackoffBounds(t *testing.T) {
+	base, maxDelay := 10*time.Millisecond, 200*time.Millisecond
+
+	t.Run("full jitter", func(t *testing.T) {
+		rt := NewRetryTransport(nil, RetryPolicy{BaseDelay: base, MaxDelay: maxDelay, Jitter: FullJitter})
+		for attempt := 1; attempt <= 10; attempt++ {
+			ceiling := min(maxDelay, base<<(attempt-1))
+			for i := 0; i < 50; i++ {
+				if d := rt.backoff(attempt, base); d < 0 || d > ceiling {
+					t.Fatalf("attempt %d: delay %v outside [0, %v]", attempt, d, ceiling)
+				}
+			}
+		}
+	})
+
+	t.Run("decorrelated jitter", func(t *testing.T) {
+		rt := NewRetryTransport(nil, RetryPolicy{BaseDelay: base, MaxDelay: maxDelay, Jitter: DecorrelatedJitter})
+		prev := base
+		for attempt := 1; attempt <= 50; attempt++ {
+			d := rt.backoff(attempt, prev)
+			if d < base || d > maxDelay || d > prev*3 {
+				t.Fatalf("attempt %d: delay %v outside [%v, min(%v, 3*%v)]", attempt, d, base, maxDelay, prev)
+			}
+			prev = d
+		}
+	})
+
+	t.Run("no jitter doubles", func(t *testing.T) {
+		rt := NewRetryTransport(nil, RetryPolicy{BaseDelay: base, MaxDelay: maxDelay, Jitter: NoJitter})
+		want := []time.Duration{10, 20, 40, 80, 160, 200, 200}
+		for i, w := range want {
+			if d := rt.backoff(i+1, base); d != w*time.Millisecond {
+				t.Errorf("attempt %d: delay %v; want %v", i+1, d, w*time.Millisecond)
+			}
+		}
+	})
+}
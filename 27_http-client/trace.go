@@ -0,0 +1,333 @@
+/*
+=============================================================================
+                    🔎 REQUEST TRACING - HTTP CLIENT EXTENSION
+=============================================================================
+
+Answers "why was that request slow?" by timing each phase with
+net/http/httptrace:
+
+  DNS ──▶ Connect ──▶ TLS ──▶ (send) ──▶ TTFB ──▶ Transfer
+                                          │          │
+                              server think time   body download
+
+  rt := NewTraceTransport(nil, &WaterfallSink{W: os.Stderr})
+  client, _ := NewAPIClient(baseURL, WithTransport(rt))
+
+  resp, _ := client.Do(req, &out)
+  if timing, ok := TimingFromResponse(resp); ok {
+      fmt.Println(timing.TTFB)
+  }
+
+Timings are final once the body has been read to EOF or closed; that is
+also when the sink is called. Put the tracer below RetryTransport to see
+every attempt, or above it to see one entry per logical request.
+Reused connections skip DNS, Connect and TLS.
+*/
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 📏 REQUEST TIMING
+type RequestTiming struct {
+	Method     string
+	URL        string
+	StatusCode int // 0 when the round trip failed
+	Err        error
+	ConnReused bool
+	Start      time.Time
+
+	DNS      time.Duration
+	Connect  time.Duration
+	TLS      time.Duration
+	TTFB     time.Duration // Request written -> first response byte
+	Transfer time.Duration // First byte -> body fully read
+	Total    time.Duration
+
+	Phases []TracePhase // Same data, laid out for a waterfall
+}
+
+type TracePhase struct {
+	Name     string
+	Offset   time.Duration // Since Start
+	Duration time.Duration
+}
+
+// 📬 TRACE SINKS: Where finished timings go
+type TraceSink interface {
+	Report(RequestTiming)
+}
+
+type TraceSinkFunc func(RequestTiming)
+
+func (f TraceSinkFunc) Report(t RequestTiming) { f(t) }
+
+// 🔎 TRACE TRANSPORT
+type TraceTransport struct {
+	Base http.RoundTripper
+	Sink TraceSink // Optional
+}
+
+func NewTraceTransport(base http.RoundTripper, sink TraceSink) *TraceTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &TraceTransport{Base: base, Sink: sink}
+}
+
+type timingKey struct{}
+
+// TimingFromResponse returns the timing recorded for resp, if it was traced.
+// Read or close the body first for Transfer and Total to be filled in.
+func TimingFromResponse(resp *http.Response) (*RequestTiming, bool) {
+	if resp == nil || resp.Request == nil {
+		return nil, false
+	}
+	rec, ok := resp.Request.Context().Value(timingKey{}).(*traceRecorder)
+	if !ok {
+		return nil, false
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	timing := rec.timing
+	timing.Phases = slices.Clone(timing.Phases)
+	return &timing, true
+}
+
+func (t *TraceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := &traceRecorder{sink: t.Sink}
+	rec.timing.Method = req.Method
+	rec.timing.URL = req.URL.String()
+	rec.timing.Start = time.Now()
+
+	ctx := context.WithValue(req.Context(), timingKey{}, rec)
+	ctx = httptrace.WithClientTrace(ctx, rec.clientTrace())
+	traced := req.WithContext(ctx)
+
+	resp, err := t.Base.RoundTrip(traced)
+	if err != nil {
+		rec.finish(err)
+		return nil, err
+	}
+	if resp.Request == nil {
+		resp.Request = traced // Transports normally set this already
+	}
+	rec.setStatus(resp.StatusCode)
+	resp.Body = &tracedBody{ReadCloser: resp.Body, rec: rec}
+	return resp, nil
+}
+
+// 🧾 TRACE RECORDER: httptrace callbacks may fire on other goroutines
+type traceRecorder struct {
+	sink TraceSink
+
+	mu                               sync.Mutex
+	timing                           RequestTiming
+	dnsStart, connectStart, tlsStart time.Time
+	wroteRequest, firstByte          time.Time
+	dnsDone, connectDone, tlsDone    bool
+	finished                         bool
+}
+
+func (r *traceRecorder) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			r.mark(func(now time.Time) { r.dnsStart = now })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			r.mark(func(now time.Time) {
+				if !r.dnsDone {
+					r.dnsDone = true
+					r.timing.DNS = r.addPhase("dns", r.dnsStart, now)
+				}
+			})
+		},
+		ConnectStart: func(network, addr string) {
+			r.mark(func(now time.Time) {
+				if r.connectStart.IsZero() {
+					r.connectStart = now // Happy Eyeballs may dial twice
+				}
+			})
+		},
+		ConnectDone: func(network, addr string, err error) {
+			r.mark(func(now time.Time) {
+				if err == nil && !r.connectDone {
+					r.connectDone = true
+					r.timing.Connect = r.addPhase("connect", r.connectStart, now)
+				}
+			})
+		},
+		TLSHandshakeStart: func() {
+			r.mark(func(now time.Time) { r.tlsStart = now })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			r.mark(func(now time.Time) {
+				if !r.tlsDone {
+					r.tlsDone = true
+					r.timing.TLS = r.addPhase("tls", r.tlsStart, now)
+				}
+			})
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			r.mark(func(time.Time) { r.timing.ConnReused = info.Reused })
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			r.mark(func(now time.Time) { r.wroteRequest = now })
+		},
+		GotFirstResponseByte: func() {
+			r.mark(func(now time.Time) {
+				r.firstByte = now
+				start := r.wroteRequest
+				if start.IsZero() {
+					start = r.timing.Start
+				}
+				r.timing.TTFB = r.addPhase("ttfb", start, now)
+			})
+		},
+	}
+}
+
+func (r *traceRecorder) mark(fn func(now time.Time)) {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.finished {
+		fn(now)
+	}
+}
+
+// addPhase records a phase and returns its duration; r.mu must be held
+func (r *traceRecorder) addPhase(name string, from, to time.Time) time.Duration {
+	if from.IsZero() {
+		return 0
+	}
+	d := to.Sub(from)
+	r.timing.Phases = append(r.timing.Phases, TracePhase{
+		Name:     name,
+		Offset:   from.Sub(r.timing.Start),
+		Duration: d,
+	})
+	return d
+}
+
+func (r *traceRecorder) setStatus(code int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.timing.StatusCode = code
+}
+
+// finish seals the timing and reports it exactly once
+func (r *traceRecorder) finish(err error) {
+	now := time.Now()
+	r.mu.Lock()
+	if r.finished {
+		r.mu.Unlock()
+		return
+	}
+	r.finished = true
+	r.timing.Err = err
+	if !r.firstByte.IsZero() {
+		r.timing.Transfer = r.addPhase("transfer", r.firstByte, now)
+	}
+	r.timing.Total = now.Sub(r.timing.Start)
+	timing := r.timing
+	timing.Phases = slices.Clone(timing.Phases)
+	r.mu.Unlock()
+
+	if r.sink != nil {
+		r.sink.Report(timing)
+	}
+}
+
+// tracedBody closes the timing when the caller is done with the body
+type tracedBody struct {
+	io.ReadCloser
+	rec *traceRecorder
+}
+
+func (b *tracedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	switch {
+	case err == io.EOF:
+		b.rec.finish(nil)
+	case err != nil:
+		b.rec.finish(err)
+	}
+	return n, err
+}
+
+func (b *tracedBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.rec.finish(nil)
+	return err
+}
+
+// 📊 WATERFALL SINK: One table per request
+type WaterfallSink struct {
+	W     io.Writer
+	Width int // Bar width in characters (default 40)
+
+	mu sync.Mutex // Keeps concurrent tables from interleaving
+}
+
+func (s *WaterfallSink) Report(t RequestTiming) {
+	width := s.Width
+	if width <= 0 {
+		width = 40
+	}
+
+	var b strings.Builder
+	outcome := fmt.Sprintf("%d", t.StatusCode)
+	if t.Err != nil {
+		outcome = "error: " + t.Err.Error()
+	}
+	conn := "new connection"
+	if t.ConnReused {
+		conn = "reused connection"
+	}
+	fmt.Fprintf(&b, "🔎 %s %s → %s in %s (%s)\n", t.Method, t.URL, outcome, roundDuration(t.Total), conn)
+
+	for _, p := range t.Phases {
+		from, to := barSpan(p.Offset, p.Duration, t.Total, width)
+		bar := strings.Repeat(" ", from) + strings.Repeat("█", to-from) + strings.Repeat(" ", width-to)
+		fmt.Fprintf(&b, "   %-8s %10s │%s│\n", p.Name, roundDuration(p.Duration), bar)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	io.WriteString(s.W, b.String())
+}
+
+// barSpan maps a phase onto [0, width) columns, at least one wide
+func barSpan(offset, d, total time.Duration, width int) (int, int) {
+	if total <= 0 {
+		return 0, 1
+	}
+	from := int(int64(width) * int64(offset) / int64(total))
+	to := int(int64(width) * int64(offset+d) / int64(total))
+	from = min(max(from, 0), width-1)
+	to = min(max(to, from+1), width)
+	return from, to
+}
+
+func roundDuration(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
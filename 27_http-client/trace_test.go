@@ -0,0 +1,188 @@
+/*
+=============================================================================
+                    🧪 TRACING TESTS
+=============================================================================
+
+Traces requests to local HTTP and HTTPS servers; the handler sleeps so
+TTFB and transfer times are measurable.
+Run with: go test -v -run Trace *.go
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	serverThink = 30 * time.Millisecond
+	bodyStall   = 20 * time.Millisecond
+)
+
+// slowHandler waits before the headers and again halfway through the body
+func slowHandler(w http.ResponseWriter, r *http.Request) {
+	time.Sleep(serverThink)
+	w.Write([]byte(`{"part": 1,`))
+	w.(http.Flusher).Flush()
+	time.Sleep(bodyStall)
+	w.Write([]byte(`"done": true}`))
+}
+
+type collectingSink struct {
+	mu      sync.Mutex
+	timings []RequestTiming
+}
+
+func (s *collectingSink) Report(t RequestTiming) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timings = append(s.timings, t)
+}
+
+func (s *collectingSink) all() []RequestTiming {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RequestTiming(nil), s.timings...)
+}
+
+func phaseNames(t RequestTiming) string {
+	var names []string
+	for _, p := range t.Phases {
+		names = append(names, p.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+func TestTraceTransportTimingBreakdown(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(slowHandler))
+	defer server.Close()
+
+	sink := &collectingSink{}
+	rt := NewTraceTransport(server.Client().Transport, sink)
+	client, _ := NewAPIClient(server.URL, WithTransport(rt))
+
+	for i := 0; i < 2; i++ {
+		req, _ := client.NewRequest(context.Background(), http.MethodGet, "/slow", nil, nil)
+		resp, err := client.Do(req, nil)
+		if err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+		timing, ok := TimingFromResponse(resp)
+		if !ok {
+			t.Fatal("response should carry its timing")
+		}
+		if timing.Total == 0 {
+			t.Error("Do reads the body, so Total should be final")
+		}
+	}
+
+	timings := sink.all()
+	if len(timings) != 2 {
+		t.Fatalf("sink got %d timings; want 2", len(timings))
+	}
+
+	first := timings[0]
+	if first.ConnReused || first.Connect <= 0 || first.TLS <= 0 {
+		t.Errorf("first request should dial and handshake: %+v", first)
+	}
+	if first.TTFB < serverThink || first.Transfer < bodyStall {
+		t.Errorf("TTFB = %v, Transfer = %v; want at least %v and %v", first.TTFB, first.Transfer, serverThink, bodyStall)
+	}
+	if first.Total < first.Connect+first.TLS+first.TTFB+first.Transfer {
+		t.Errorf("Total %v is less than the sum of its phases", first.Total)
+	}
+	if got := phaseNames(first); got != "connect,tls,ttfb,transfer" {
+		t.Errorf("phases = %s", got)
+	}
+	for i := 1; i < len(first.Phases); i++ {
+		if first.Phases[i].Offset < first.Phases[i-1].Offset {
+			t.Errorf("phases out of order: %+v", first.Phases)
+		}
+	}
+
+	second := timings[1]
+	if !second.ConnReused || second.Connect != 0 || second.TLS != 0 {
+		t.Errorf("second request should reuse the connection: %+v", second)
+	}
+	if got := phaseNames(second); got != "ttfb,transfer" {
+		t.Errorf("reused phases = %s", got)
+	}
+	if second.StatusCode != http.StatusOK || second.Method != http.MethodGet || !strings.HasSuffix(second.URL, "/slow") {
+		t.Errorf("second = %+v", second)
+	}
+}
+
+func TestTraceTransportReportsOncePerRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	sink := &collectingSink{}
+	hc := &http.Client{Transport: NewTraceTransport(nil, sink)}
+
+	resp, err := hc.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(resp.Body) // EOF reports...
+	resp.Body.Close()     // ...and Close must not report again
+
+	if got := len(sink.all()); got != 1 {
+		t.Errorf("reports = %d; want 1", got)
+	}
+}
+
+func TestTraceTransportReportsErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close() // Nothing listens here any more
+
+	sink := &collectingSink{}
+	hc := &http.Client{Transport: NewTraceTransport(nil, sink)}
+	if _, err := hc.Get(url); err == nil {
+		t.Fatal("expected a connection error")
+	}
+
+	timings := sink.all()
+	if len(timings) != 1 || timings[0].Err == nil || timings[0].StatusCode != 0 {
+		t.Errorf("timings = %+v; want one failed entry", timings)
+	}
+}
+
+func TestWaterfallSink(t *testing.T) {
+	var out strings.Builder
+	sink := &WaterfallSink{W: &out, Width: 20}
+	sink.Report(RequestTiming{
+		Method:     http.MethodGet,
+		URL:        "https://api.example.com/posts/1",
+		StatusCode: 200,
+		Total:      100 * time.Millisecond,
+		Phases: []TracePhase{
+			{Name: "dns", Offset: 0, Duration: 10 * time.Millisecond},
+			{Name: "connect", Offset: 10 * time.Millisecond, Duration: 20 * time.Millisecond},
+			{Name: "ttfb", Offset: 30 * time.Millisecond, Duration: 60 * time.Millisecond},
+			{Name: "transfer", Offset: 90 * time.Millisecond, Duration: 10 * time.Millisecond},
+		},
+	})
+	sink.Report(RequestTiming{Method: http.MethodGet, URL: "https://down.example.com", Err: errors.New("connection refused")})
+
+	want := "" +
+		"🔎 GET https://api.example.com/posts/1 → 200 in 100ms (new connection)\n" +
+		"   dns            10ms │██                  │\n" +
+		"   connect        20ms │  ████              │\n" +
+		"   ttfb           60ms │      ████████████  │\n" +
+		"   transfer       10ms │                  ██│\n" +
+		"🔎 GET https://down.example.com → error: connection refused in 0s (new connection)\n"
+	if out.String() != want {
+		t.Errorf("waterfall =\n%s\nwant\n%s", out.String(), want)
+	}
+}
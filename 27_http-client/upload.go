@@ -0,0 +1,301 @@
+/*
+=============================================================================
+                    📤 STREAMING UPLOADS - HTTP CLIENT EXTENSION
+=============================================================================
+
+Builds multipart/form-data bodies that mix fields and files without
+holding the files in memory:
+
+  form := NewMultipartForm().
+      AddField("caption", "Holiday").
+      AddFile("avatar", "me.png", file)
+
+  resp, err := Upload[APIResponse](ctx, client, "/users/1/avatar", form, UploadOptions{
+      Method:     http.MethodPut, // 28_http-server's avatar route
+      OnProgress: func(p UploadProgress) { fmt.Printf("\r%d/%d", p.Sent, p.Total) },
+  })
+
+The body is produced by a goroutine writing into an io.Pipe while the
+transport reads from the other end, so memory use stays flat no matter
+how big the files are. The goroutine starts on the transport's first
+Read, so a request that is built but never sent leaks nothing.
+
+• When every file size is known the request carries a Content-Length;
+  otherwise (or with Chunked) it uses chunked transfer encoding
+• Gzip compresses the whole body and sets Content-Encoding: gzip (the
+  server has to support that; many don't)
+• Streamed bodies can be sent once: they are not retried or redirected
+*/
+
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// 📎 FORM PARTS
+type formField struct {
+	name, value string
+}
+
+type formFile struct {
+	field       string
+	fileName    string
+	contentType string
+	reader      io.Reader
+	size        int64 // -1 when unknown
+}
+
+// 📋 MULTIPART FORM
+type MultipartForm struct {
+	fields []formField
+	files  []formFile
+	closer []io.Closer // Files opened by AddFileFromPath
+}
+
+func NewMultipartForm() *MultipartForm {
+	return &MultipartForm{}
+}
+
+func (f *MultipartForm) AddField(name, value string) *MultipartForm {
+	f.fields = append(f.fields, formField{name, value})
+	return f
+}
+
+// AddFile streams r as a file part; the size is detected for common
+// readers (bytes/strings readers, *os.File) so Content-Length can be set
+func (f *MultipartForm) AddFile(field, fileName string, r io.Reader) *MultipartForm {
+	return f.AddFileWithType(field, fileName, "application/octet-stream", r)
+}
+
+func (f *MultipartForm) AddFileWithType(field, fileName, contentType string, r io.Reader) *MultipartForm {
+	f.files = append(f.files, formFile{
+		field:       field,
+		fileName:    fileName,
+		contentType: contentType,
+		reader:      r,
+		size:        readerSize(r),
+	})
+	return f
+}
+
+// AddFileFromPath opens path; it is closed once the body has been sent
+func (f *MultipartForm) AddFileFromPath(field, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	f.closer = append(f.closer, file)
+	f.AddFile(field, filepath.Base(path), file)
+	return nil
+}
+
+// Close releases files opened by AddFileFromPath
+func (f *MultipartForm) Close() error {
+	var firstErr error
+	for _, c := range f.closer {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	f.closer = nil
+	return firstErr
+}
+
+// write streams the whole form into mw
+func (f *MultipartForm) write(mw *multipart.Writer) error {
+	for _, field := range f.fields {
+		if err := mw.WriteField(field.name, field.value); err != nil {
+			return err
+		}
+	}
+	for _, file := range f.files {
+		part, err := mw.CreatePart(file.header())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, file.reader); err != nil {
+			return fmt.Errorf("upload %s: %w", file.fileName, err)
+		}
+	}
+	return mw.Close()
+}
+
+// contentLength is the exact body size, or -1 if any file size is unknown
+func (f *MultipartForm) contentLength(boundary string) int64 {
+	var total int64
+	for _, file := range f.files {
+		if file.size < 0 {
+			return -1
+		}
+		total += file.size
+	}
+
+	// Render the form with empty files to measure the framing
+	counter := &countingWriter{}
+	mw := multipart.NewWriter(counter)
+	mw.SetBoundary(boundary)
+	for _, field := range f.fields {
+		mw.WriteField(field.name, field.value)
+	}
+	for _, file := range f.files {
+		mw.CreatePart(file.header())
+	}
+	mw.Close()
+	return total + counter.n
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func (file formFile) header() textproto.MIMEHeader {
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(file.field), quoteEscaper.Replace(file.fileName)))
+	h.Set("Content-Type", file.contentType)
+	return h
+}
+
+func readerSize(r io.Reader) int64 {
+	switch v := r.(type) {
+	case interface{ Len() int }: // bytes.Reader, strings.Reader, bytes.Buffer
+		return int64(v.Len())
+	case *os.File:
+		info, err := v.Stat()
+		if err != nil || !info.Mode().IsRegular() {
+			return -1
+		}
+		offset, err := v.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return -1
+		}
+		return info.Size() - offset
+	}
+	return -1
+}
+
+// ⚙️ UPLOAD OPTIONS
+type UploadOptions struct {
+	Method     string // Defaults to POST
+	Chunked    bool   // Force chunked transfer encoding
+	Gzip       bool   // Compress the body (implies chunked)
+	OnProgress func(UploadProgress)
+}
+
+// 📊 UPLOAD PROGRESS
+type UploadProgress struct {
+	Sent  int64 // Body bytes handed to the transport (compressed, if Gzip)
+	Total int64 // -1 when unknown (chunked)
+}
+
+// NewMultipartRequest builds a request whose body streams form
+func (c *APIClient) NewMultipartRequest(ctx context.Context, method, path string, form *MultipartForm, opts UploadOptions) (*http.Request, error) {
+	req, err := c.NewRequest(ctx, method, path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	var gz *gzip.Writer
+	target := io.Writer(pw)
+	if opts.Gzip {
+		gz = gzip.NewWriter(pw)
+		target = gz
+	}
+	mw := multipart.NewWriter(target)
+
+	length := int64(-1)
+	if !opts.Chunked && !opts.Gzip {
+		length = form.contentLength(mw.Boundary())
+	}
+
+	writeBody := func() {
+		err := form.write(mw)
+		if gz != nil {
+			if closeErr := gz.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		pw.CloseWithError(err) // nil means a clean EOF for the reader
+	}
+
+	req.Body = &progressReader{
+		r:      pr,
+		total:  length,
+		fn:     opts.OnProgress,
+		closer: pr,
+		start:  func() { go writeBody() },
+	}
+	req.ContentLength = length
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if opts.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	return req, nil
+}
+
+// Upload sends form with opts.Method and decodes a JSON response into Resp
+func Upload[Resp any](ctx context.Context, c *APIClient, path string, form *MultipartForm, opts UploadOptions) (Resp, error) {
+	var out Resp
+	defer form.Close()
+
+	method := opts.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := c.NewMultipartRequest(ctx, method, path, form, opts)
+	if err != nil {
+		return out, err
+	}
+	_, err = c.Do(req, &out)
+	return out, err
+}
+
+// 🧰 HELPERS
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// progressReader counts bytes as the transport pulls them, starting the
+// producer on the first Read
+type progressReader struct {
+	r       io.Reader
+	sent    int64
+	total   int64
+	fn      func(UploadProgress)
+	closer  io.Closer
+	start   func()
+	started sync.Once
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	p.started.Do(p.start)
+	n, err := p.r.Read(b)
+	p.sent += int64(n)
+	if n > 0 && p.fn != nil {
+		p.fn(UploadProgress{Sent: p.sent, Total: p.total})
+	}
+	return n, err
+}
+
+// Close unblocks the writer goroutine if the transport gives up early,
+// and keeps it from ever starting if nothing was read yet
+func (p *progressReader) Close() error {
+	p.started.Do(func() {})
+	return p.closer.Close()
+}
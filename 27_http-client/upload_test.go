@@ -0,0 +1,337 @@
+/*
+=============================================================================
+                    🧪 UPLOAD TESTS
+=============================================================================
+
+Round-trips multipart uploads through a local copy of the avatar upload
+handler from 28_http-server (PUT route, size limit, ParseMultipartForm,
+sniffed content type, JSON APIResponse), plus gzip request decoding and
+the body writer starting only once the transport reads.
+Run with: go test -v -run Upload *.go
+*/
+
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const maxTestAvatarSize = 2 << 20
+
+// receivedUpload is what the handler saw on the wire
+type receivedUpload struct {
+	contentLength    int64
+	transferEncoding []string
+	contentEncoding  string
+	filename         string
+	caption          string
+}
+
+// avatarTestServer mimics 28_http-server's PUT /users/{id}/avatar; like the
+// real route, any other method gets a 405 from the mux
+func avatarTestServer(t *testing.T) (*APIClient, func() receivedUpload) {
+	t.Helper()
+	var mu sync.Mutex
+	var last receivedUpload
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /users/{id}/avatar", func(w http.ResponseWriter, r *http.Request) {
+		record := func(update func(*receivedUpload)) {
+			mu.Lock()
+			defer mu.Unlock()
+			update(&last)
+		}
+		record(func(u *receivedUpload) {
+			*u = receivedUpload{contentLength: r.ContentLength, transferEncoding: r.TransferEncoding, contentEncoding: r.Header.Get("Content-Encoding")}
+		})
+
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				writeTestJSON(w, http.StatusBadRequest, APIResponse{Message: "bad gzip body"})
+				return
+			}
+			r.Body = gz
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxTestAvatarSize+1<<10)
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			writeTestJSON(w, http.StatusBadRequest, APIResponse{Message: "invalid multipart form: " + err.Error()})
+			return
+		}
+		file, header, err := r.FormFile("avatar")
+		if err != nil {
+			writeTestJSON(w, http.StatusBadRequest, APIResponse{Message: "missing avatar file"})
+			return
+		}
+		defer file.Close()
+		record(func(u *receivedUpload) { u.filename, u.caption = header.Filename, r.FormValue("caption") })
+
+		data, _ := io.ReadAll(io.LimitReader(file, maxTestAvatarSize+1))
+		if len(data) > maxTestAvatarSize {
+			writeTestJSON(w, http.StatusRequestEntityTooLarge, APIResponse{Message: "avatar too large"})
+			return
+		}
+		if ct := http.DetectContentType(data); ct != "image/png" {
+			writeTestJSON(w, http.StatusUnsupportedMediaType, APIResponse{Message: "unsupported type " + ct})
+			return
+		}
+
+		// The real handler answers 200 with the user and its new avatar
+		id, _ := strconv.Atoi(r.PathValue("id"))
+		sum := sha256.Sum256(data)
+		writeTestJSON(w, http.StatusOK, APIResponse{
+			Success: true,
+			Message: "Avatar uploaded successfully",
+			Data: map[string]interface{}{
+				"id": id,
+				"avatar": map[string]interface{}{
+					"hash":         hex.EncodeToString(sum[:]),
+					"content_type": "image/png",
+					"size":         len(data),
+				},
+			},
+		})
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	client, err := NewAPIClient(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, func() receivedUpload {
+		mu.Lock()
+		defer mu.Unlock()
+		return last
+	}
+}
+
+func writeTestJSON(w http.ResponseWriter, status int, resp APIResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func testPNG(t *testing.T, size int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 7), uint8(y * 13), uint8(x ^ y), 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// uploadedHash digs the avatar hash out of the upload response's user
+func uploadedHash(resp APIResponse) string {
+	user, _ := resp.Data.(map[string]interface{})
+	avatar, _ := user["avatar"].(map[string]interface{})
+	hash, _ := avatar["hash"].(string)
+	return hash
+}
+
+func TestUploadAvatarRoundTrip(t *testing.T) {
+	avatar := testPNG(t, 256)
+
+	tests := []struct {
+		name         string
+		opts         UploadOptions
+		reader       func() io.Reader
+		wantChunked  bool
+		wantEncoding string
+	}{
+		{"content length", UploadOptions{Method: http.MethodPut}, func() io.Reader { return bytes.NewReader(avatar) }, false, ""},
+		{"forced chunked", UploadOptions{Method: http.MethodPut, Chunked: true}, func() io.Reader { return bytes.NewReader(avatar) }, true, ""},
+		{"unknown size streams chunked", UploadOptions{Method: http.MethodPut}, func() io.Reader { return io.MultiReader(bytes.NewReader(avatar)) }, true, ""},
+		{"gzip", UploadOptions{Method: http.MethodPut, Gzip: true}, func() io.Reader { return bytes.NewReader(avatar) }, true, "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, received := avatarTestServer(t)
+
+			var progress []UploadProgress
+			opts := tt.opts
+			opts.OnProgress = func(p UploadProgress) { progress = append(progress, p) }
+
+			form := NewMultipartForm().
+				AddField("caption", `Me, "smiling"`).
+				AddFileWithType("avatar", "me.png", "image/png", tt.reader())
+
+			resp, err := Upload[APIResponse](context.Background(), client, "/users/7/avatar", form, opts)
+			if err != nil {
+				t.Fatalf("Upload: %v", err)
+			}
+
+			user, _ := resp.Data.(map[string]interface{})
+			if !resp.Success || uploadedHash(resp) != sha256Hex(avatar) || user["id"] != 7.0 {
+				t.Errorf("response = %+v; want user 7 with the avatar intact", resp)
+			}
+
+			got := received()
+			if got.filename != "me.png" || got.caption != `Me, "smiling"` {
+				t.Errorf("server saw file %q, caption %q", got.filename, got.caption)
+			}
+			if chunked := len(got.transferEncoding) > 0 && got.transferEncoding[0] == "chunked"; chunked != tt.wantChunked {
+				t.Errorf("chunked = %v (Content-Length %d); want %v", chunked, got.contentLength, tt.wantChunked)
+			}
+			if got.contentEncoding != tt.wantEncoding {
+				t.Errorf("Content-Encoding = %q; want %q", got.contentEncoding, tt.wantEncoding)
+			}
+
+			if len(progress) == 0 {
+				t.Fatal("no progress reported")
+			}
+			final := progress[len(progress)-1]
+			if tt.wantChunked {
+				if final.Total != -1 {
+					t.Errorf("Total = %d; want -1 for chunked", final.Total)
+				}
+			} else if final.Sent != final.Total || final.Total != got.contentLength {
+				t.Errorf("final progress = %+v; want Sent == Total == Content-Length %d", final, got.contentLength)
+			}
+		})
+	}
+}
+
+func TestUploadFromPath(t *testing.T) {
+	client, received := avatarTestServer(t)
+	avatar := testPNG(t, 64)
+	path := filepath.Join(t.TempDir(), "portrait.png")
+	if err := os.WriteFile(path, avatar, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	form := NewMultipartForm()
+	if err := form.AddFileFromPath("avatar", path); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := Upload[APIResponse](context.Background(), client, "/users/1/avatar", form, UploadOptions{Method: http.MethodPut})
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	got := received()
+	if got.filename != "portrait.png" || uploadedHash(resp) != sha256Hex(avatar) {
+		t.Errorf("response = %+v, filename %q", resp, got.filename)
+	}
+	if got.contentLength <= int64(len(avatar)) {
+		t.Errorf("Content-Length = %d; want the full multipart size from the file's stat", got.contentLength)
+	}
+}
+
+func TestUploadErrors(t *testing.T) {
+	t.Run("server rejects non-images", func(t *testing.T) {
+		client, _ := avatarTestServer(t)
+		form := NewMultipartForm().AddFile("avatar", "notes.txt", strings.NewReader("just text"))
+
+		_, err := Upload[APIResponse](context.Background(), client, "/users/1/avatar", form, UploadOptions{Method: http.MethodPut})
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnsupportedMediaType {
+			t.Errorf("err = %v; want 415 APIError", err)
+		}
+	})
+
+	t.Run("default POST is not the avatar route", func(t *testing.T) {
+		client, _ := avatarTestServer(t)
+		form := NewMultipartForm().AddFile("avatar", "me.png", bytes.NewReader(testPNG(t, 8)))
+
+		_, err := Upload[APIResponse](context.Background(), client, "/users/1/avatar", form, UploadOptions{})
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusMethodNotAllowed {
+			t.Errorf("err = %v; want 405 APIError", err)
+		}
+	})
+
+	t.Run("reader failure aborts the request", func(t *testing.T) {
+		client, _ := avatarTestServer(t)
+		errDisk := errors.New("disk read failed")
+		broken := io.MultiReader(strings.NewReader("partial"), &failingReader{err: errDisk})
+		form := NewMultipartForm().AddFile("avatar", "me.png", broken)
+
+		_, err := Upload[APIResponse](context.Background(), client, "/users/1/avatar", form, UploadOptions{Method: http.MethodPut})
+		if !errors.Is(err, errDisk) {
+			t.Errorf("err = %v; want it to wrap the reader error", err)
+		}
+	})
+}
+
+// uploadWriters counts the goroutines producing multipart bodies
+func uploadWriters() int {
+	buf := make([]byte, 1<<20)
+	buf = buf[:runtime.Stack(buf, true)]
+	return bytes.Count(buf, []byte("NewMultipartRequest.func"))
+}
+
+func TestUploadWriterStartsOnFirstRead(t *testing.T) {
+	newForm := func() *MultipartForm {
+		return NewMultipartForm().AddFile("avatar", "me.png", strings.NewReader("pixels"))
+	}
+
+	t.Run("request never sent", func(t *testing.T) {
+		client, _ := NewAPIClient("http://127.0.0.1")
+		req, err := client.NewMultipartRequest(context.Background(), http.MethodPut, "/users/1/avatar", newForm(), UploadOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if n := uploadWriters(); n != 0 {
+			t.Errorf("%d body writers running for a request nobody sent", n)
+		}
+		req.Body.Close()
+		if _, err := req.Body.Read(make([]byte, 1)); err == nil {
+			t.Error("Read after Close succeeded")
+		}
+		if n := uploadWriters(); n != 0 {
+			t.Errorf("Read after Close started %d body writers", n)
+		}
+	})
+
+	t.Run("transport fails before reading", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		client, _ := NewAPIClient(server.URL)
+		server.Close() // Connection refused
+
+		if _, err := Upload[APIResponse](context.Background(), client, "/users/1/avatar", newForm(), UploadOptions{Method: http.MethodPut}); err == nil {
+			t.Fatal("Upload to a closed server succeeded")
+		}
+		time.Sleep(10 * time.Millisecond)
+		if n := uploadWriters(); n != 0 {
+			t.Errorf("%d body writers left behind", n)
+		}
+	})
+}
+
+type failingReader struct{ err error }
+
+func (r *failingReader) Read([]byte) (int, error) { return 0, r.err }
@@ -0,0 +1,467 @@
+/*
+=============================================================================
+                    🖼️ AVATAR UPLOADS - HTTP SERVER EXTENSION
+=============================================================================
+
+Multipart avatar uploads for users, stored in a content-addressed directory
+and served back with http.ServeContent (Range, Last-Modified, ETag and
+Cache-Control all come for free).
+
+  PUT /users/{id}/avatar            - upload (multipart field "avatar")
+  GET /users/{id}/avatar            - current avatar of a user
+  DELETE /users/{id}/avatar         - remove a user's avatar
+  GET /users/{id}/avatar/thumbnail  - resized thumbnail of that avatar
+  GET /avatars/{file}               - immutable content-addressed file
+
+Every route sits behind auth and is cached privately: an avatar is only
+as public as the user it belongs to.
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ⚙️ UPLOAD LIMITS: Keep uploads small and predictable
+const (
+	maxAvatarBytes     = 2 << 20 // 2 MiB per file
+	maxAvatarDimension = 4096    // Reject decompression bombs early
+	thumbnailSize      = 128     // Thumbnails fit in a 128x128 box
+	avatarFormField    = "avatar"
+)
+
+// 📁 STORAGE ROOT: Files live at <root>/<hash[:2]>/<hash><ext>
+var avatarRoot = filepath.Join("uploads", "avatars")
+
+// 🔍 ALLOWED TYPES: Sniffed content type -> file extension
+var avatarExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+}
+
+// 🖼️ AVATAR METADATA: Attached to a User after a successful upload
+type Avatar struct {
+	Hash         string    `json:"hash"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	UploadedAt   time.Time `json:"uploaded_at"`
+}
+
+func (a *Avatar) fileName() string {
+	return a.Hash + avatarExtensions[a.ContentType]
+}
+
+func (a *Avatar) thumbnailName() string {
+	return a.Hash + "_thumb" + avatarExtensions[a.ContentType]
+}
+
+// 📦 CONTENT-ADDRESSED PATHS: Shard by the first two hex digits
+func avatarPath(name string) string {
+	return filepath.Join(avatarRoot, name[:2], name)
+}
+
+// 🎯 AVATAR HANDLER: PUT uploads, GET/HEAD serves, DELETE removes
+func avatarHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Invalid user ID"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		handleUploadAvatar(w, r, userID)
+	case http.MethodGet, http.MethodHead:
+		handleServeAvatar(w, r, userID, false)
+	case http.MethodDelete:
+		handleDeleteAvatar(w, r, userID)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func avatarThumbnailHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Invalid user ID"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		handleServeAvatar(w, r, userID, true)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func handleUploadAvatar(w http.ResponseWriter, r *http.Request, userID int) {
+	usersMu.RLock()
+	index := findUserIndex(userID)
+	usersMu.RUnlock()
+	if index < 0 {
+		writeJSON(w, http.StatusNotFound, APIResponse{Success: false, Error: "User not found"})
+		return
+	}
+
+	// Cap the whole body; leave a little room for multipart framing
+	r.Body = http.MaxBytesReader(w, r.Body, maxAvatarBytes+64<<10)
+	if err := r.ParseMultipartForm(maxAvatarBytes); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, APIResponse{
+				Success: false,
+				Error:   fmt.Sprintf("Avatar must be at most %d bytes", maxAvatarBytes),
+			})
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Invalid multipart form"})
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile(avatarFormField)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Missing %q file field", avatarFormField),
+		})
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxAvatarBytes {
+		writeJSON(w, http.StatusRequestEntityTooLarge, APIResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Avatar must be at most %d bytes", maxAvatarBytes),
+		})
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, maxAvatarBytes+1))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Could not read upload"})
+		return
+	}
+
+	avatar, err := storeAvatar(data)
+	if err != nil {
+		var uploadErr *avatarUploadError
+		if errors.As(err, &uploadErr) {
+			writeJSON(w, uploadErr.status, APIResponse{Success: false, Error: uploadErr.message})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, APIResponse{Success: false, Error: "Could not store avatar"})
+		return
+	}
+
+	// Look the user up again: it may have moved or gone during the upload
+	usersMu.Lock()
+	defer usersMu.Unlock()
+	index = findUserIndex(userID)
+	if index < 0 {
+		writeJSON(w, http.StatusNotFound, APIResponse{Success: false, Error: "User not found"})
+		return
+	}
+	updated := users[index]
+	updated.Avatar = avatar
+	if err := persistUser(updated); err != nil {
+		writeJSON(w, http.StatusInternalServerError, APIResponse{Success: false, Error: "Could not save user"})
+		return
+	}
+	users[index] = updated
+
+	writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    presentUser(apiVersionFrom(r), updated),
+		Message: "Avatar uploaded successfully",
+	})
+}
+
+// The files stay on disk: they are content-addressed and another user may
+// have uploaded the same image
+func handleDeleteAvatar(w http.ResponseWriter, r *http.Request, userID int) {
+	usersMu.Lock()
+	defer usersMu.Unlock()
+	index := findUserIndex(userID)
+	if index < 0 {
+		writeJSON(w, http.StatusNotFound, APIResponse{Success: false, Error: "User not found"})
+		return
+	}
+	if users[index].Avatar == nil {
+		writeJSON(w, http.StatusNotFound, APIResponse{Success: false, Error: "User has no avatar"})
+		return
+	}
+
+	updated := users[index]
+	updated.Avatar = nil
+	if err := persistUser(updated); err != nil {
+		writeJSON(w, http.StatusInternalServerError, APIResponse{Success: false, Error: "Could not save user"})
+		return
+	}
+	users[index] = updated
+
+	writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    presentUser(apiVersionFrom(r), updated),
+		Message: "Avatar deleted successfully",
+	})
+}
+
+func handleServeAvatar(w http.ResponseWriter, r *http.Request, userID int, thumbnail bool) {
+	usersMu.RLock()
+	index := findUserIndex(userID)
+	var avatar *Avatar
+	if index >= 0 {
+		avatar = users[index].Avatar
+	}
+	usersMu.RUnlock()
+	if index < 0 {
+		writeJSON(w, http.StatusNotFound, APIResponse{Success: false, Error: "User not found"})
+		return
+	}
+
+	if avatar == nil {
+		writeJSON(w, http.StatusNotFound, APIResponse{Success: false, Error: "User has no avatar"})
+		return
+	}
+
+	name := avatar.fileName()
+	if thumbnail {
+		name = avatar.thumbnailName()
+	}
+
+	// The per-user URL sits behind auth and changes on re-upload: keep it
+	// out of shared caches and short-lived
+	serveAvatarFile(w, r, name, avatar.UploadedAt, "private, max-age=300")
+}
+
+// 🗂️ STATIC AVATAR FILES: Content-addressed, so they never change. Still
+// behind auth like the per-user route, so no shared cache may keep them.
+func avatarFileHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("file")
+	if !validAvatarFileName(name) {
+		http.NotFound(w, r)
+		return
+	}
+
+	info, err := os.Stat(avatarPath(name))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	serveAvatarFile(w, r, name, info.ModTime(), "private, max-age=31536000, immutable")
+}
+
+func serveAvatarFile(w http.ResponseWriter, r *http.Request, name string, modTime time.Time, cacheControl string) {
+	f, err := os.Open(avatarPath(name))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("ETag", `"`+strings.TrimSuffix(name, filepath.Ext(name))+`"`)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// ServeContent handles Range, If-Modified-Since, If-None-Match and HEAD
+	http.ServeContent(w, r, name, modTime, f)
+}
+
+// 🛡️ PATH SAFETY: Only accept names this package could have generated
+func validAvatarFileName(name string) bool {
+	ext := filepath.Ext(name)
+	if ext != ".png" && ext != ".jpg" {
+		return false
+	}
+	base := strings.TrimSuffix(strings.TrimSuffix(name, ext), "_thumb")
+	if len(base) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(base)
+	return err == nil
+}
+
+// 🚨 UPLOAD ERRORS: Carry the HTTP status back to the handler
+type avatarUploadError struct {
+	status  int
+	message string
+}
+
+func (e *avatarUploadError) Error() string {
+	return e.message
+}
+
+// 💾 STORE AVATAR: Validate, hash, write original + thumbnail
+func storeAvatar(data []byte) (*Avatar, error) {
+	if len(data) > maxAvatarBytes {
+		return nil, &avatarUploadError{http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("Avatar must be at most %d bytes", maxAvatarBytes)}
+	}
+
+	// Trust the bytes, not the client's Content-Type header
+	contentType := http.DetectContentType(data)
+	if _, ok := avatarExtensions[contentType]; !ok {
+		return nil, &avatarUploadError{http.StatusUnsupportedMediaType,
+			fmt.Sprintf("Unsupported avatar type %q (use PNG or JPEG)", contentType)}
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, &avatarUploadError{http.StatusBadRequest, "Avatar is not a valid image"}
+	}
+	if config.Width > maxAvatarDimension || config.Height > maxAvatarDimension {
+		return nil, &avatarUploadError{http.StatusBadRequest,
+			fmt.Sprintf("Avatar must be at most %dx%d pixels", maxAvatarDimension, maxAvatarDimension)}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, &avatarUploadError{http.StatusBadRequest, "Avatar is not a valid image"}
+	}
+
+	sum := sha256.Sum256(data)
+	avatar := &Avatar{
+		Hash:        hex.EncodeToString(sum[:]),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Width:       config.Width,
+		Height:      config.Height,
+		UploadedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+	avatar.URL = "/avatars/" + avatar.fileName()
+	avatar.ThumbnailURL = "/avatars/" + avatar.thumbnailName()
+
+	if err := writeFileAtomic(avatarPath(avatar.fileName()), data); err != nil {
+		return nil, err
+	}
+
+	var thumb bytes.Buffer
+	resized := resizeToFit(img, thumbnailSize)
+	if contentType == "image/png" {
+		err = png.Encode(&thumb, resized)
+	} else {
+		err = jpeg.Encode(&thumb, resized, &jpeg.Options{Quality: 85})
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(avatarPath(avatar.thumbnailName()), thumb.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return avatar, nil
+}
+
+// ✍️ ATOMIC WRITE: Temp file + rename; identical content is written once
+func writeFileAtomic(path string, data []byte) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// 📐 THUMBNAILS: Box-filter downscale that keeps the aspect ratio
+func resizeToFit(src image.Image, maxSize int) image.Image {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= maxSize && srcH <= maxSize {
+		return src
+	}
+
+	dstW, dstH := maxSize, maxSize
+	if srcW > srcH {
+		dstH = max(1, srcH*maxSize/srcW)
+	} else {
+		dstW = max(1, srcW*maxSize/srcH)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := bounds.Min.Y + y*srcH/dstH
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*srcH/dstH)
+		for x := 0; x < dstW; x++ {
+			x0 := bounds.Min.X + x*srcW/dstW
+			x1 := max(x0+1, bounds.Min.X+(x+1)*srcW/dstW)
+
+			// Average every source pixel that falls into this output pixel
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.NRGBAModel.Convert(src.At(sx, sy)).(color.NRGBA)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / n),
+				G: uint8(g / n),
+				B: uint8(b / n),
+				A: uint8(a / n),
+			})
+		}
+	}
+	return dst
+}
+
+// 🔧 SHARED HELPERS
+// findUserIndex expects the caller to hold usersMu
+func findUserIndex(userID int) int {
+	for i, user := range users {
+		if user.ID == userID {
+			return i
+		}
+	}
+	return -1
+}
+
+func writeJSON(w http.ResponseWriter, status int, response APIResponse) {
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,222 @@
+/*
+=============================================================================
+                    🧪 AVATAR UPLOAD TESTS
+=============================================================================
+
+Upload limits and content sniffing, PUT/GET/DELETE on /users/{id}/avatar,
+thumbnails, cache headers and conditional GETs. Files go to a temp dir.
+Run with: go test -v -run Avatar *.go
+*/
+
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// newAvatarServer serves the routes with demo users and a temp avatar root
+func newAvatarServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	withUsers(t, demoUsers)
+	saved := avatarRoot
+	avatarRoot = t.TempDir()
+	t.Cleanup(func() { avatarRoot = saved })
+	server := httptest.NewServer(setupRoutes())
+	t.Cleanup(server.Close)
+	return server
+}
+
+func pngBytes(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		img.Set(x, 0, color.RGBA{R: uint8(x), A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// uploadAvatar PUTs data as a multipart form under field
+func uploadAvatar(t *testing.T, server *httptest.Server, path, field string, data []byte) (*http.Response, []byte) {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile(field, "avatar.png")
+	part.Write(data)
+	form.Close()
+
+	resp, respBody, err := apiRequest(server, http.MethodPut, path, body.String(),
+		http.Header{"Content-Type": {form.FormDataContentType()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, respBody
+}
+
+func TestAvatarUploadServeDelete(t *testing.T) {
+	server := newAvatarServer(t)
+	original := pngBytes(t, 300, 200)
+
+	resp, body := uploadAvatar(t, server, "/users/1/avatar", avatarFormField, original)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT = %d: %s", resp.StatusCode, body)
+	}
+	var user User
+	decodeData(t, body, &user)
+	if a := user.Avatar; a == nil || a.ContentType != "image/png" || a.Width != 300 || a.Height != 200 || a.Size != int64(len(original)) {
+		t.Fatalf("uploaded avatar = %+v", user.Avatar)
+	}
+
+	resp, got, _ := apiRequest(server, http.MethodGet, "/users/1/avatar", "", nil)
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(got, original) {
+		t.Fatalf("GET = %d, %d bytes; want the uploaded %d", resp.StatusCode, len(got), len(original))
+	}
+	if cc := resp.Header.Get("Cache-Control"); cc != "private, max-age=300" {
+		t.Errorf("per-user Cache-Control = %q; shared caches must not keep it", cc)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "image/png" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	resp, thumb, _ := apiRequest(server, http.MethodGet, "/users/1/avatar/thumbnail", "", nil)
+	config, err := png.DecodeConfig(bytes.NewReader(thumb))
+	if resp.StatusCode != http.StatusOK || err != nil || config.Width != thumbnailSize || config.Height != 85 {
+		t.Errorf("thumbnail = %d, %dx%d, %v; want %dx85", resp.StatusCode, config.Width, config.Height, err, thumbnailSize)
+	}
+
+	// The content-addressed URLs are immutable, but just as private
+	for _, url := range []string{user.Avatar.URL, user.Avatar.ThumbnailURL} {
+		resp, _, _ = apiRequest(server, http.MethodGet, url, "", nil)
+		if cc := resp.Header.Get("Cache-Control"); resp.StatusCode != http.StatusOK || cc != "private, max-age=31536000, immutable" {
+			t.Errorf("GET %s = %d, Cache-Control %q", url, resp.StatusCode, cc)
+		}
+		anon, err := http.Get(server.URL + url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		anon.Body.Close()
+		if anon.StatusCode != http.StatusUnauthorized {
+			t.Errorf("GET %s without credentials = %d; want 401", url, anon.StatusCode)
+		}
+	}
+
+	file := avatarPath(user.Avatar.fileName())
+	resp, body, _ = apiRequest(server, http.MethodDelete, "/users/1/avatar", "", nil)
+	var cleared User
+	decodeData(t, body, &cleared)
+	if resp.StatusCode != http.StatusOK || cleared.Avatar != nil {
+		t.Fatalf("DELETE = %d: %s", resp.StatusCode, body)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if resp, _, _ := apiRequest(server, method, "/users/1/avatar", "", nil); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("%s after delete = %d; want 404", method, resp.StatusCode)
+		}
+	}
+	if _, err := os.Stat(file); err != nil {
+		t.Errorf("content-addressed file removed with the avatar: %v", err)
+	}
+}
+
+func TestAvatarUploadRejects(t *testing.T) {
+	server := newAvatarServer(t)
+	valid := pngBytes(t, 10, 10)
+	oversized := append(pngBytes(t, 10, 10), make([]byte, maxAvatarBytes)...)
+
+	tests := []struct {
+		name   string
+		path   string
+		field  string
+		data   []byte
+		status int
+	}{
+		{"just over the limit", "/users/1/avatar", avatarFormField, oversized[:maxAvatarBytes+1], http.StatusRequestEntityTooLarge},
+		{"body over the limit", "/users/1/avatar", avatarFormField, oversized, http.StatusRequestEntityTooLarge},
+		{"text with a .png name", "/users/1/avatar", avatarFormField, []byte("definitely not an image"), http.StatusUnsupportedMediaType},
+		{"GIF", "/users/1/avatar", avatarFormField, []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"), http.StatusUnsupportedMediaType},
+		{"PNG header, broken body", "/users/1/avatar", avatarFormField, valid[:40], http.StatusBadRequest},
+		{"too many pixels", "/users/1/avatar", avatarFormField, pngBytes(t, maxAvatarDimension+1, 1), http.StatusBadRequest},
+		{"wrong field", "/users/1/avatar", "photo", valid, http.StatusBadRequest},
+		{"unknown user", "/users/999/avatar", avatarFormField, valid, http.StatusNotFound},
+		{"bad user ID", "/users/abc/avatar", avatarFormField, valid, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := uploadAvatar(t, server, tt.path, tt.field, tt.data)
+			if resp.StatusCode != tt.status {
+				t.Errorf("PUT = %d; want %d (%s)", resp.StatusCode, tt.status, body)
+			}
+		})
+	}
+
+	usersMu.RLock()
+	defer usersMu.RUnlock()
+	if users[0].Avatar != nil {
+		t.Error("a rejected upload set the avatar")
+	}
+	if entries, _ := os.ReadDir(avatarRoot); len(entries) != 0 {
+		t.Errorf("rejected uploads left files: %v", entries)
+	}
+}
+
+func TestAvatarConditionalGet(t *testing.T) {
+	server := newAvatarServer(t)
+	if resp, body := uploadAvatar(t, server, "/users/2/avatar", avatarFormField, pngBytes(t, 64, 64)); resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT = %d: %s", resp.StatusCode, body)
+	}
+	resp, _, _ := apiRequest(server, http.MethodGet, "/users/2/avatar", "", nil)
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag == "" || lastModified == "" {
+		t.Fatalf("validators missing: ETag %q, Last-Modified %q", etag, lastModified)
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		status int
+	}{
+		{"matching ETag", http.Header{"If-None-Match": {etag}}, http.StatusNotModified},
+		{"ETag in a list", http.Header{"If-None-Match": {`"other", ` + etag}}, http.StatusNotModified},
+		{"other ETag", http.Header{"If-None-Match": {`"other"`}}, http.StatusOK},
+		{"not modified since", http.Header{"If-Modified-Since": {lastModified}}, http.StatusNotModified},
+		{"modified since", http.Header{"If-Modified-Since": {"Mon, 01 Jan 2001 00:00:00 GMT"}}, http.StatusOK},
+		{"range", http.Header{"Range": {"bytes=0-7"}}, http.StatusPartialContent},
+	}
+	for _, tt := range tests {
+		resp, body, err := apiRequest(server, http.MethodGet, "/users/2/avatar", "", tt.header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: status %d; want %d", tt.name, resp.StatusCode, tt.status)
+		}
+		if tt.status == http.StatusNotModified && len(body) != 0 {
+			t.Errorf("%s: 304 with a %d byte body", tt.name, len(body))
+		}
+	}
+
+	// Re-uploading changes the validator
+	uploadAvatar(t, server, "/users/2/avatar", avatarFormField, pngBytes(t, 32, 32))
+	resp, _, _ = apiRequest(server, http.MethodGet, "/users/2/avatar", "", http.Header{"If-None-Match": {etag}})
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("stale ETag after re-upload = %d; want 200", resp.StatusCode)
+	}
+}
+
+func TestAvatarMethodNotAllowed(t *testing.T) {
+	server := newAvatarServer(t)
+	resp, _, _ := apiRequest(server, http.MethodPost, "/users/1/avatar", "", nil)
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST = %d; want 405", resp.StatusCode)
+	}
+}
@@ -0,0 +1,349 @@
+/*
+=============================================================================
+                    🚪 API GATEWAY MODE - HTTP SERVER EXTENSION
+=============================================================================
+
+With -gateway=gateway.json the tutorial server also fronts other services.
+Each route maps a path prefix to a set of upstreams:
+
+  {
+    "health_interval": "10s",
+    "routes": [
+      {
+        "prefix": "/api/orders",
+        "upstreams": ["http://localhost:9001", "http://localhost:9002"],
+        "strategy": "least_connections",
+        "health_path": "/health",
+        "strip_prefix": true
+      }
+    ]
+  }
+
+• Load balancing: "round_robin" (default) or "least_connections"
+• Active health checks take failing upstreams out of rotation
+• The same CORS, logging and API-key middleware run in front of proxies
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ⚖️ LOAD BALANCING STRATEGIES
+const (
+	strategyRoundRobin       = "round_robin"
+	strategyLeastConnections = "least_connections"
+)
+
+// ⚙️ GATEWAY DEFAULTS
+const (
+	defaultHealthInterval     = 10 * time.Second
+	defaultHealthTimeout      = 2 * time.Second
+	defaultHealthPath         = "/health"
+	defaultUnhealthyThreshold = 2
+)
+
+// 📋 CONFIGURATION: Loaded from the -gateway JSON file
+type GatewayConfig struct {
+	HealthInterval     string         `json:"health_interval"`
+	HealthTimeout      string         `json:"health_timeout"`
+	UnhealthyThreshold int            `json:"unhealthy_threshold"`
+	Routes             []GatewayRoute `json:"routes"`
+}
+
+type GatewayRoute struct {
+	Prefix      string   `json:"prefix"`
+	Upstreams   []string `json:"upstreams"`
+	Strategy    string   `json:"strategy"`
+	HealthPath  string   `json:"health_path"`
+	StripPrefix bool     `json:"strip_prefix"`
+}
+
+func LoadGatewayConfig(path string) (GatewayConfig, error) {
+	var cfg GatewayConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// 🖥️ UPSTREAM: One backend server plus its live state
+type upstream struct {
+	target *url.URL
+	proxy  *httputil.ReverseProxy
+
+	healthy  atomic.Bool
+	active   atomic.Int64 // In-flight proxied requests
+	failures atomic.Int32 // Consecutive failed health checks
+}
+
+// 🛣️ PROXY ROUTE: A prefix and the upstreams behind it
+type proxyRoute struct {
+	prefix     string
+	strategy   string
+	healthPath string
+	upstreams  []*upstream
+	next       atomic.Uint64
+}
+
+// 🚪 GATEWAY: All proxy routes plus the health checker
+type Gateway struct {
+	routes             []*proxyRoute
+	healthInterval     time.Duration
+	unhealthyThreshold int
+	client             *http.Client
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+func NewGateway(cfg GatewayConfig) (*Gateway, error) {
+	g := &Gateway{
+		healthInterval:     defaultHealthInterval,
+		unhealthyThreshold: defaultUnhealthyThreshold,
+		stop:               make(chan struct{}),
+	}
+
+	timeout := defaultHealthTimeout
+	if cfg.HealthInterval != "" {
+		d, err := time.ParseDuration(cfg.HealthInterval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid health_interval %q", cfg.HealthInterval)
+		}
+		g.healthInterval = d
+	}
+	if cfg.HealthTimeout != "" {
+		d, err := time.ParseDuration(cfg.HealthTimeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid health_timeout %q", cfg.HealthTimeout)
+		}
+		timeout = d
+	}
+	if cfg.UnhealthyThreshold > 0 {
+		g.unhealthyThreshold = cfg.UnhealthyThreshold
+	}
+	g.client = &http.Client{Timeout: timeout}
+
+	// Catch clashes here: ServeMux panics on a pattern registered twice
+	reserved := reservedPrefixes()
+	for _, rc := range cfg.Routes {
+		route, err := newProxyRoute(rc)
+		if err != nil {
+			return nil, err
+		}
+		for _, own := range reserved {
+			if overlaps(route.prefix, own) {
+				return nil, fmt.Errorf("route prefix %s overlaps the server's own %s routes", route.prefix, own)
+			}
+		}
+		for _, other := range g.routes {
+			if route.prefix == other.prefix {
+				return nil, fmt.Errorf("route prefix %s is configured twice", route.prefix)
+			}
+		}
+		g.routes = append(g.routes, route)
+	}
+	return g, nil
+}
+
+// reservedPrefixes are served by the tutorial server itself (see setupRoutes)
+func reservedPrefixes() []string {
+	reserved := []string{"/about", "/avatars", "/users"}
+	for version := range apiVersions {
+		reserved = append(reserved, "/"+version.String())
+	}
+	return reserved
+}
+
+// overlaps reports whether one prefix equals or contains the other
+func overlaps(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+func newProxyRoute(rc GatewayRoute) (*proxyRoute, error) {
+	prefix := strings.TrimSuffix(rc.Prefix, "/")
+	if !strings.HasPrefix(prefix, "/") || prefix == "" {
+		return nil, fmt.Errorf("route prefix %q must start with / and not be the root", rc.Prefix)
+	}
+	if len(rc.Upstreams) == 0 {
+		return nil, fmt.Errorf("route %s has no upstreams", prefix)
+	}
+
+	route := &proxyRoute{
+		prefix:     prefix,
+		strategy:   rc.Strategy,
+		healthPath: rc.HealthPath,
+	}
+	switch route.strategy {
+	case "":
+		route.strategy = strategyRoundRobin
+	case strategyRoundRobin, strategyLeastConnections:
+	default:
+		return nil, fmt.Errorf("route %s: unknown strategy %q", prefix, rc.Strategy)
+	}
+	if route.healthPath == "" {
+		route.healthPath = defaultHealthPath
+	}
+
+	for _, raw := range rc.Upstreams {
+		target, err := url.Parse(raw)
+		if err != nil || target.Scheme == "" || target.Host == "" {
+			return nil, fmt.Errorf("route %s: invalid upstream %q", prefix, raw)
+		}
+		route.upstreams = append(route.upstreams, newUpstream(target, prefix, rc.StripPrefix))
+	}
+	return route, nil
+}
+
+func newUpstream(target *url.URL, prefix string, stripPrefix bool) *upstream {
+	u := &upstream{target: target}
+	u.healthy.Store(true) // Optimistic until the first check says otherwise
+
+	u.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			if stripPrefix {
+				pr.Out.URL.Path = "/" + strings.TrimPrefix(strings.TrimPrefix(pr.Out.URL.Path, prefix), "/")
+				pr.Out.URL.RawPath = ""
+			}
+			pr.SetURL(target)
+			pr.SetXForwarded()
+
+			// The gateway consumes the API key; upstreams never see it
+			pr.Out.Header.Del("X-API-Key")
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("🚪 upstream %s failed: %v", target, err)
+			writeJSON(w, http.StatusBadGateway, APIResponse{
+				Success: false,
+				Error:   "Upstream unavailable",
+			})
+		},
+	}
+	return u
+}
+
+// ⚖️ PICK: Choose a healthy upstream according to the route strategy
+func (pr *proxyRoute) pick() *upstream {
+	n := len(pr.upstreams)
+	start := int((pr.next.Add(1) - 1) % uint64(n))
+
+	var best *upstream
+	for i := 0; i < n; i++ {
+		u := pr.upstreams[(start+i)%n]
+		if !u.healthy.Load() {
+			continue
+		}
+		if pr.strategy == strategyRoundRobin {
+			return u
+		}
+		if best == nil || u.active.Load() < best.active.Load() {
+			best = u
+		}
+	}
+	return best
+}
+
+func (pr *proxyRoute) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u := pr.pick()
+	if u == nil {
+		writeJSON(w, http.StatusServiceUnavailable, APIResponse{
+			Success: false,
+			Error:   fmt.Sprintf("No healthy upstream for %s", pr.prefix),
+		})
+		return
+	}
+
+	u.active.Add(1)
+	defer u.active.Add(-1)
+
+	u.proxy.ServeHTTP(w, r)
+}
+
+// 🔗 REGISTER: Mount every route behind the usual middleware chain
+func (g *Gateway) Register(mux *http.ServeMux) {
+	for _, route := range g.routes {
+		handler := corsMiddleware(loggingMiddleware(authMiddleware(route.ServeHTTP)))
+		mux.HandleFunc(route.prefix, handler)
+		mux.HandleFunc(route.prefix+"/", handler)
+	}
+}
+
+// 🩺 HEALTH CHECKS: Probe every upstream on an interval
+func (g *Gateway) StartHealthChecks() {
+	go func() {
+		ticker := time.NewTicker(g.healthInterval)
+		defer ticker.Stop()
+
+		g.CheckHealth(context.Background())
+		for {
+			select {
+			case <-ticker.C:
+				g.CheckHealth(context.Background())
+			case <-g.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (g *Gateway) Stop() {
+	g.stopOnce.Do(func() {
+		close(g.stop)
+	})
+}
+
+// CheckHealth runs one round of probes and waits for all of them
+func (g *Gateway) CheckHealth(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, route := range g.routes {
+		for _, u := range route.upstreams {
+			wg.Add(1)
+			go func(route *proxyRoute, u *upstream) {
+				defer wg.Done()
+				g.probe(ctx, route, u)
+			}(route, u)
+		}
+	}
+	wg.Wait()
+}
+
+func (g *Gateway) probe(ctx context.Context, route *proxyRoute, u *upstream) {
+	ok := false
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.target.JoinPath(route.healthPath).String(), nil)
+	if err == nil {
+		resp, err := g.client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+			ok = resp.StatusCode >= 200 && resp.StatusCode < 300
+		}
+	}
+
+	if ok {
+		u.failures.Store(0)
+		if !u.healthy.Swap(true) {
+			log.Printf("🩺 upstream %s is healthy again", u.target)
+		}
+		return
+	}
+
+	failures := int(u.failures.Add(1))
+	if failures >= g.unhealthyThreshold && u.healthy.Swap(false) {
+		log.Printf("🩺 upstream %s removed from rotation after %d failed checks", u.target, failures)
+	}
+}
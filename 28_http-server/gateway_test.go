@@ -0,0 +1,288 @@
+/*
+=============================================================================
+                    🧪 API GATEWAY TESTS
+=============================================================================
+
+Every upstream is a local httptest.Server, so these run offline.
+Run with: go test -v -run Gateway *.go
+*/
+
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 🧰 TEST UPSTREAM: Answers with its name and a switchable /health
+type testUpstream struct {
+	*httptest.Server
+	healthy atomic.Bool
+	hits    atomic.Int64
+	lastReq atomic.Pointer[http.Request]
+}
+
+func newTestUpstream(t *testing.T, name string, handler http.HandlerFunc) *testUpstream {
+	t.Helper()
+	u := &testUpstream{}
+	u.healthy.Store(true)
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			if !u.healthy.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			return
+		}
+		u.hits.Add(1)
+		u.lastReq.Store(r)
+		if handler != nil {
+			handler(w, r)
+		}
+		io.WriteString(w, name)
+	}))
+	t.Cleanup(u.Close)
+	return u
+}
+
+func newTestGateway(t *testing.T, routes ...GatewayRoute) (*Gateway, *httptest.Server) {
+	t.Helper()
+	gateway, err := NewGateway(GatewayConfig{UnhealthyThreshold: 1, Routes: routes})
+	if err != nil {
+		t.Fatalf("NewGateway: %v", err)
+	}
+	mux := setupRoutes()
+	gateway.Register(mux)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return gateway, server
+}
+
+func gatewayGet(t *testing.T, url string) (int, string, http.Header) {
+	t.Helper()
+	status, body, header, err := gatewayDo(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	return status, body, header
+}
+
+// gatewayDo is gatewayGet for other goroutines, where t.Fatal can't be used
+func gatewayDo(url string) (int, string, http.Header, error) {
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("X-API-Key", "demo-api-key")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, "", nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body), resp.Header, err
+}
+
+func TestGatewayRoundRobin(t *testing.T) {
+	a := newTestUpstream(t, "a", nil)
+	b := newTestUpstream(t, "b", nil)
+	_, server := newTestGateway(t, GatewayRoute{
+		Prefix:    "/api/orders",
+		Upstreams: []string{a.URL, b.URL},
+	})
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		status, body, _ := gatewayGet(t, server.URL+"/api/orders/42")
+		if status != http.StatusOK {
+			t.Fatalf("status = %d; want 200", status)
+		}
+		got = append(got, body)
+	}
+
+	want := []string{"a", "b", "a", "b"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("round robin order = %v; want %v", got, want)
+		}
+	}
+}
+
+func TestGatewayLeastConnections(t *testing.T) {
+	release := make(chan struct{})
+	slow := newTestUpstream(t, "slow", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	fast := newTestUpstream(t, "fast", nil)
+	gateway, server := newTestGateway(t, GatewayRoute{
+		Prefix:    "/api",
+		Upstreams: []string{slow.URL, fast.URL},
+		Strategy:  strategyLeastConnections,
+	})
+
+	// Park one request on the slow upstream
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, _, _, err := gatewayDo(server.URL + "/api/slow"); err != nil {
+			t.Errorf("GET /api/slow: %v", err)
+		}
+	}()
+	waitFor(t, func() bool { return gateway.routes[0].upstreams[0].active.Load() == 1 })
+
+	for i := 0; i < 3; i++ {
+		if _, body, _ := gatewayGet(t, server.URL+"/api/x"); body != "fast" {
+			t.Errorf("request %d went to %q; want the idle upstream", i, body)
+		}
+	}
+
+	close(release)
+	<-done
+}
+
+func TestGatewayHealthChecks(t *testing.T) {
+	a := newTestUpstream(t, "a", nil)
+	b := newTestUpstream(t, "b", nil)
+	gateway, server := newTestGateway(t, GatewayRoute{
+		Prefix:    "/api",
+		Upstreams: []string{a.URL, b.URL},
+	})
+
+	a.healthy.Store(false)
+	gateway.CheckHealth(context.Background())
+
+	for i := 0; i < 4; i++ {
+		if _, body, _ := gatewayGet(t, server.URL+"/api"); body != "b" {
+			t.Fatalf("request %d went to %q; unhealthy upstream should be out of rotation", i, body)
+		}
+	}
+
+	b.healthy.Store(false)
+	gateway.CheckHealth(context.Background())
+	if status, _, _ := gatewayGet(t, server.URL+"/api"); status != http.StatusServiceUnavailable {
+		t.Errorf("status with no healthy upstreams = %d; want 503", status)
+	}
+
+	a.healthy.Store(true)
+	gateway.CheckHealth(context.Background())
+	if _, body, _ := gatewayGet(t, server.URL+"/api"); body != "a" {
+		t.Errorf("recovered upstream not back in rotation, got %q", body)
+	}
+}
+
+func TestGatewayMiddlewareAndRewrite(t *testing.T) {
+	up := newTestUpstream(t, "orders", nil)
+	_, server := newTestGateway(t, GatewayRoute{
+		Prefix:      "/api/orders",
+		Upstreams:   []string{up.URL},
+		StripPrefix: true,
+	})
+
+	// Auth runs in front of the proxy
+	resp, err := http.Get(server.URL + "/api/orders/7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status without API key = %d; want 401", resp.StatusCode)
+	}
+	if up.hits.Load() != 0 {
+		t.Errorf("unauthenticated request reached the upstream")
+	}
+
+	status, _, header := gatewayGet(t, server.URL+"/api/orders/7?expand=items")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d; want 200", status)
+	}
+	if header.Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("CORS header missing on proxied response")
+	}
+
+	req := up.lastReq.Load()
+	if req.URL.Path != "/7" || req.URL.RawQuery != "expand=items" {
+		t.Errorf("upstream saw %s?%s; want /7?expand=items", req.URL.Path, req.URL.RawQuery)
+	}
+	if req.Header.Get("X-API-Key") != "" {
+		t.Errorf("API key leaked to the upstream")
+	}
+	if req.Header.Get("X-Forwarded-For") == "" {
+		t.Errorf("X-Forwarded-For not set")
+	}
+}
+
+func TestGatewayUpstreamDown(t *testing.T) {
+	up := newTestUpstream(t, "gone", nil)
+	up.Close()
+	_, server := newTestGateway(t, GatewayRoute{Prefix: "/api", Upstreams: []string{up.URL}})
+
+	if status, _, _ := gatewayGet(t, server.URL+"/api"); status != http.StatusBadGateway {
+		t.Errorf("status for dead upstream = %d; want 502", status)
+	}
+}
+
+func TestNewGatewayValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		route GatewayRoute
+	}{
+		{"root prefix", GatewayRoute{Prefix: "/", Upstreams: []string{"http://x"}}},
+		{"relative prefix", GatewayRoute{Prefix: "api", Upstreams: []string{"http://x"}}},
+		{"no upstreams", GatewayRoute{Prefix: "/api"}},
+		{"bad upstream", GatewayRoute{Prefix: "/api", Upstreams: []string{"localhost:9000"}}},
+		{"bad strategy", GatewayRoute{Prefix: "/api", Upstreams: []string{"http://x"}, Strategy: "random"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewGateway(GatewayConfig{Routes: []GatewayRoute{tt.route}}); err == nil {
+				t.Errorf("NewGateway accepted %+v", tt.route)
+			}
+		})
+	}
+}
+
+func TestNewGatewayRejectsServerRoutes(t *testing.T) {
+	tests := []struct {
+		prefixes []string
+		wantErr  string
+	}{
+		{[]string{"/users"}, "overlaps the server's own /users routes"},
+		{[]string{"/users/"}, "overlaps the server's own /users routes"},
+		{[]string{"/about"}, "overlaps the server's own /about routes"},
+		{[]string{"/v1/users"}, "overlaps the server's own /v1 routes"},
+		{[]string{"/v2"}, "overlaps the server's own /v2 routes"},
+		{[]string{"/avatars/cdn"}, "overlaps the server's own /avatars routes"},
+		{[]string{"/api", "/api/"}, "route prefix /api is configured twice"},
+	}
+	for _, tt := range tests {
+		var routes []GatewayRoute
+		for _, prefix := range tt.prefixes {
+			routes = append(routes, GatewayRoute{Prefix: prefix, Upstreams: []string{"http://x"}})
+		}
+		_, err := NewGateway(GatewayConfig{Routes: routes})
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("NewGateway(%v) = %v; want %q", tt.prefixes, err, tt.wantErr)
+		}
+	}
+
+	// Prefixes that only look similar still mount without a panic
+	newTestGateway(t,
+		GatewayRoute{Prefix: "/usersvc", Upstreams: []string{"http://x"}},
+		GatewayRoute{Prefix: "/api", Upstreams: []string{"http://x"}},
+		GatewayRoute{Prefix: "/api/orders", Upstreams: []string{"http://x"}},
+	)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 2s")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
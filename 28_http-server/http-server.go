@@ -34,19 +34,29 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-// 📊 DATA STRUCTURES: For API responses
+// 📊 DATA STRUCTURES: Internal model (wire formats live in versioning.go)
 type User struct {
-	ID    int    `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
+	ID        int       `json:"id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Email     string    `json:"email"`
+	Avatar    *Avatar   `json:"avatar,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (u User) FullName() string {
+	return strings.TrimSpace(u.FirstName + " " + u.LastName)
 }
 
 type APIResponse struct {
@@ -57,24 +67,36 @@ type APIResponse struct {
 }
 
 // 💾 IN-MEMORY DATA STORE: Simple storage for demo
+var seedTime = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
 var users = []User{
-	{ID: 1, Name: "John Doe", Email: "john@example.com"},
-	{ID: 2, Name: "Jane Smith", Email: "jane@example.com"},
-	{ID: 3, Name: "Bob Johnson", Email: "bob@example.com"},
+	{ID: 1, FirstName: "John", LastName: "Doe", Email: "john@example.com", CreatedAt: seedTime},
+	{ID: 2, FirstName: "Jane", LastName: "Smith", Email: "jane@example.com", CreatedAt: seedTime},
+	{ID: 3, FirstName: "Bob", LastName: "Johnson", Email: "bob@example.com", CreatedAt: seedTime},
 }
 
 var nextUserID = 4
 
+// usersMu guards users, nextUserID and writes to userStore, so a request's
+// validate-persist-update sequence runs as one step
+var usersMu sync.RWMutex
+
 // 🎯 BASIC HANDLERS: Simple request handlers
 func homeHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "🏠 Welcome to Go HTTP Server!\n")
 	fmt.Fprintf(w, "📅 Current time: %s\n", time.Now().Format(time.RFC3339))
 	fmt.Fprintf(w, "🔗 Available endpoints:\n")
 	fmt.Fprintf(w, "  GET  /users     - List all users\n")
+	fmt.Fprintf(w, "  GET  /users/search?q=jo - Search users\n")
 	fmt.Fprintf(w, "  GET  /users/1   - Get user by ID\n")
 	fmt.Fprintf(w, "  POST /users     - Create new user\n")
 	fmt.Fprintf(w, "  PUT  /users/1   - Update user\n")
 	fmt.Fprintf(w, "  DELETE /users/1 - Delete user\n")
+	fmt.Fprintf(w, "  PUT  /users/1/avatar - Upload avatar (multipart)\n")
+	fmt.Fprintf(w, "  GET  /users/1/avatar - Get avatar image\n")
+	fmt.Fprintf(w, "  DELETE /users/1/avatar - Remove avatar\n")
+	fmt.Fprintf(w, "🏷️ Versions: /v1/users (deprecated), /v2/users,\n")
+	fmt.Fprintf(w, "   or Accept: application/vnd.tutorial.v2+json on /users\n")
 }
 
 func aboutHandler(w http.ResponseWriter, r *http.Request) {
@@ -93,7 +115,7 @@ func aboutHandler(w http.ResponseWriter, r *http.Request) {
 
 // 👥 USER HANDLERS: CRUD operations for users
 func usersHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", mediaTypeFor(apiVersionFrom(r)))
 	
 	switch r.Method {
 	case http.MethodGet:
@@ -106,7 +128,7 @@ func usersHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func userHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", mediaTypeFor(apiVersionFrom(r)))
 	
 	// Extract user ID from URL path
 	path := strings.TrimPrefix(r.URL.Path, "/users/")
@@ -134,24 +156,32 @@ func userHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleGetUsers(w http.ResponseWriter, r *http.Request) {
+	usersMu.RLock()
 	response := APIResponse{
 		Success: true,
-		Data:    users,
+		Data:    presentUsers(apiVersionFrom(r), users),
 		Message: fmt.Sprintf("Found %d users", len(users)),
 	}
+	usersMu.RUnlock()
 	json.NewEncoder(w).Encode(response)
 }
 
 func handleGetUser(w http.ResponseWriter, r *http.Request, userID int) {
-	for _, user := range users {
-		if user.ID == userID {
-			response := APIResponse{
-				Success: true,
-				Data:    user,
-			}
-			json.NewEncoder(w).Encode(response)
-			return
+	usersMu.RLock()
+	index := findUserIndex(userID)
+	var user User
+	if index >= 0 {
+		user = users[index]
+	}
+	usersMu.RUnlock()
+
+	if index >= 0 {
+		response := APIResponse{
+			Success: true,
+			Data:    presentUser(apiVersionFrom(r), user),
 		}
+		json.NewEncoder(w).Encode(response)
+		return
 	}
 	
 	response := APIResponse{
@@ -163,8 +193,7 @@ func handleGetUser(w http.ResponseWriter, r *http.Request, userID int) {
 }
 
 func handleCreateUser(w http.ResponseWriter, r *http.Request) {
-	var newUser User
-	err := json.NewDecoder(r.Body).Decode(&newUser)
+	newUser, err := decodeUser(apiVersionFrom(r), r.Body)
 	if err != nil {
 		response := APIResponse{
 			Success: false,
@@ -175,16 +204,32 @@ func handleCreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	
-	// Assign new ID
+	// Assign new ID; concurrent creates must not share one or both pass
+	// the email check, so hold the lock from here to the append
+	newUser = normalizeUser(newUser)
+	newUser.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	usersMu.Lock()
+	defer usersMu.Unlock()
 	newUser.ID = nextUserID
+	
+	// Validate and persist before touching the in-memory copy
+	if err := validateUser(newUser, users); err != nil {
+		writeUserError(w, err)
+		return
+	}
+	if err := persistUser(newUser); err != nil {
+		writeUserError(w, err)
+		return
+	}
 	nextUserID++
 	
 	// Add to users slice
 	users = append(users, newUser)
+	userIndex.Index(newUser)
 	
 	response := APIResponse{
 		Success: true,
-		Data:    newUser,
+		Data:    presentUser(apiVersionFrom(r), newUser),
 		Message: "User created successfully",
 	}
 	w.WriteHeader(http.StatusCreated)
@@ -192,8 +237,7 @@ func handleCreateUser(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleUpdateUser(w http.ResponseWriter, r *http.Request, userID int) {
-	var updatedUser User
-	err := json.NewDecoder(r.Body).Decode(&updatedUser)
+	updatedUser, err := decodeUser(apiVersionFrom(r), r.Body)
 	if err != nil {
 		response := APIResponse{
 			Success: false,
@@ -205,14 +249,28 @@ func handleUpdateUser(w http.ResponseWriter, r *http.Request, userID int) {
 	}
 	
 	// Find and update user
+	usersMu.Lock()
+	defer usersMu.Unlock()
 	for i, user := range users {
 		if user.ID == userID {
-			updatedUser.ID = userID // Preserve ID
+			updatedUser = normalizeUser(updatedUser)
+			updatedUser.ID = userID         // Preserve ID
+			updatedUser.Avatar = user.Avatar // Avatars change via PUT /users/{id}/avatar
+			updatedUser.CreatedAt = user.CreatedAt
+			if err := validateUser(updatedUser, users); err != nil {
+				writeUserError(w, err)
+				return
+			}
+			if err := persistUser(updatedUser); err != nil {
+				writeUserError(w, err)
+				return
+			}
 			users[i] = updatedUser
+			userIndex.Index(updatedUser)
 			
 			response := APIResponse{
 				Success: true,
-				Data:    updatedUser,
+				Data:    presentUser(apiVersionFrom(r), updatedUser),
 				Message: "User updated successfully",
 			}
 			json.NewEncoder(w).Encode(response)
@@ -229,10 +287,18 @@ func handleUpdateUser(w http.ResponseWriter, r *http.Request, userID int) {
 }
 
 func handleDeleteUser(w http.ResponseWriter, r *http.Request, userID int) {
+	usersMu.Lock()
+	defer usersMu.Unlock()
 	for i, user := range users {
 		if user.ID == userID {
+			if err := persistDelete(userID); err != nil {
+				writeUserError(w, err)
+				return
+			}
+
 			// Remove user from slice
 			users = append(users[:i], users[i+1:]...)
+			userIndex.Remove(userID)
 			
 			response := APIResponse{
 				Success: true,
@@ -310,15 +376,49 @@ func setupRoutes() *http.ServeMux {
 	// Apply middleware to handlers
 	mux.HandleFunc("/", corsMiddleware(loggingMiddleware(authMiddleware(homeHandler))))
 	mux.HandleFunc("/about", corsMiddleware(loggingMiddleware(aboutHandler)))
+	mux.HandleFunc("GET /avatars/{file}", corsMiddleware(loggingMiddleware(authMiddleware(avatarFileHandler))))
+
+	// Users API: /users negotiates via Accept, /v1 and /v2 pin a version
+	api := userRoutes()
+	mux.Handle("/users", versionMiddleware(0, api))
+	mux.Handle("/users/", versionMiddleware(0, api))
+	for version := range apiVersions {
+		prefix := "/" + version.String()
+		versioned := http.StripPrefix(prefix, versionMiddleware(version, api))
+		mux.Handle(prefix+"/users", versioned)
+		mux.Handle(prefix+"/users/", versioned)
+	}
+	
+	return mux
+}
+
+// 👥 USER ROUTES: Shared by every API version
+func userRoutes() *http.ServeMux {
+	mux := http.NewServeMux()
+
 	mux.HandleFunc("/users", corsMiddleware(loggingMiddleware(authMiddleware(usersHandler))))
+	mux.HandleFunc("GET /users/search", corsMiddleware(loggingMiddleware(authMiddleware(searchUsersHandler))))
 	
 	// Handle user-specific routes
 	mux.HandleFunc("/users/", corsMiddleware(loggingMiddleware(authMiddleware(userHandler))))
-	
+
+	// Avatar uploads (see avatars.go)
+	mux.HandleFunc("/users/{id}/avatar", corsMiddleware(loggingMiddleware(authMiddleware(avatarHandler))))
+	mux.HandleFunc("/users/{id}/avatar/thumbnail", corsMiddleware(loggingMiddleware(authMiddleware(avatarThumbnailHandler))))
+
 	return mux
 }
 
 func main() {
+	// usersctl shares this binary (see usersctl.go)
+	if isUsersctl(os.Args) {
+		os.Exit(runUsersctl(usersctlArgs(os.Args), os.Stdout, os.Stderr))
+	}
+
+	storePath := flag.String("store", defaultStorePath, "user store file (see store.go)")
+	gatewayConfig := flag.String("gateway", "", "JSON file with reverse-proxy routes (see gateway.go)")
+	flag.Parse()
+
 	fmt.Println("🌐 HTTP SERVER TUTORIAL")
 	fmt.Println("=======================")
 
@@ -326,8 +426,39 @@ func main() {
 	fmt.Println("\n🎯 Starting HTTP Server")
 	fmt.Println("=======================")
 
+	// Load persisted users (see store.go)
+	store, err := loadUserStore(*storePath)
+	if err != nil {
+		log.Fatalf("❌ user store: %v", err)
+	}
+	defer store.Close()
+	userStore = store
+	log.Printf("💾 Loaded %d users from %s", len(users), store.Path())
+
+	// Build the search index from the store (see search.go)
+	userIndex.Rebuild(users)
+	log.Printf("🔎 Search index built for %d users", len(users))
+
 	// Setup routes
 	mux := setupRoutes()
+
+	// Optional API gateway mode (see gateway.go)
+	if *gatewayConfig != "" {
+		cfg, err := LoadGatewayConfig(*gatewayConfig)
+		if err != nil {
+			log.Fatalf("❌ gateway config: %v", err)
+		}
+		gateway, err := NewGateway(cfg)
+		if err != nil {
+			log.Fatalf("❌ gateway config: %v", err)
+		}
+		gateway.Register(mux)
+		gateway.StartHealthChecks()
+		defer gateway.Stop()
+		for _, route := range cfg.Routes {
+			log.Printf("🚪 Proxying %s -> %s", route.Prefix, strings.Join(route.Upstreams, ", "))
+		}
+	}
 	
 	// Create server with custom configuration
 	server := &http.Server{
@@ -344,9 +475,17 @@ func main() {
 	fmt.Println("  GET    http://localhost:8080/about")
 	fmt.Println("  GET    http://localhost:8080/users")
 	fmt.Println("  POST   http://localhost:8080/users")
+	fmt.Println("  GET    http://localhost:8080/users/search?q=john")
 	fmt.Println("  GET    http://localhost:8080/users/1")
 	fmt.Println("  PUT    http://localhost:8080/users/1")
 	fmt.Println("  DELETE http://localhost:8080/users/1")
+	fmt.Println("  PUT    http://localhost:8080/users/1/avatar")
+	fmt.Println("  GET    http://localhost:8080/users/1/avatar")
+	fmt.Println("  DELETE http://localhost:8080/users/1/avatar")
+	fmt.Println("  GET    http://localhost:8080/users/1/avatar/thumbnail")
+	fmt.Println("  GET    http://localhost:8080/avatars/{hash}.png")
+	fmt.Println("  *      http://localhost:8080/v1/users...  (deprecated)")
+	fmt.Println("  *      http://localhost:8080/v2/users...")
 	fmt.Println()
 	fmt.Println("🔑 API Key required for protected endpoints: X-API-Key: demo-api-key")
 	fmt.Println()
@@ -356,6 +495,10 @@ func main() {
 	fmt.Println(`  curl -X POST -H "X-API-Key: demo-api-key" -H "Content-Type: application/json" \`)
 	fmt.Println(`       -d '{"name":"New User","email":"new@example.com"}' \`)
 	fmt.Println(`       http://localhost:8080/users`)
+	fmt.Println(`  curl -H "X-API-Key: demo-api-key" -H "Accept: application/vnd.tutorial.v2+json" \`)
+	fmt.Println(`       http://localhost:8080/users`)
+	fmt.Println(`  curl -X PUT -H "X-API-Key: demo-api-key" -F "avatar=@me.png" \`)
+	fmt.Println(`       http://localhost:8080/users/1/avatar`)
 	fmt.Println()
 	fmt.Println("⏹️  Press Ctrl+C to stop the server")
 
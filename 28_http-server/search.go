@@ -0,0 +1,385 @@
+/*
+=============================================================================
+                    🔎 USER SEARCH - HTTP SERVER EXTENSION
+=============================================================================
+
+An in-process inverted index over user names and emails.
+
+  GET /users/search?q=jon&limit=10
+
+• Tokens are lower-cased and stripped of diacritics ("José" -> "jose")
+• Every query token must match a document token exactly, as a prefix,
+  or within edit distance 1 ("jonn" finds "john")
+• Results are ranked with BM25
+
+The handlers keep the index current on create/update/delete, and main
+rebuilds it from the store on startup.
+*/
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ⚙️ RANKING KNOBS
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	prefixMatchWeight = 0.8 // "jo" -> "john"
+	typoMatchWeight   = 0.5 // "jonn" -> "john"
+	minTypoTermLength = 3   // Short tokens would fuzzy-match almost anything
+
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+// 📚 INVERTED INDEX: term -> user ID -> term frequency
+type searchIndex struct {
+	mu       sync.RWMutex
+	postings map[string]map[int]int
+	docTerms map[int][]string
+	terms    []string // Sorted keys of postings, for prefix lookups
+	totalLen int
+}
+
+type searchHit struct {
+	ID    int
+	Score float64
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		postings: make(map[string]map[int]int),
+		docTerms: make(map[int][]string),
+	}
+}
+
+var userIndex = newSearchIndex()
+
+func searchableText(u User) string {
+	return u.FirstName + " " + u.LastName + " " + u.Email
+}
+
+// Rebuild replaces the whole index with the given users
+func (idx *searchIndex) Rebuild(list []User) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.postings = make(map[string]map[int]int)
+	idx.docTerms = make(map[int][]string)
+	idx.terms = nil
+	idx.totalLen = 0
+	for _, u := range list {
+		idx.add(u.ID, tokenize(searchableText(u)))
+	}
+}
+
+// Index adds a user or replaces its previous entry
+func (idx *searchIndex) Index(u User) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(u.ID)
+	idx.add(u.ID, tokenize(searchableText(u)))
+}
+
+// Remove drops a user from the index
+func (idx *searchIndex) Remove(id int) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(id)
+}
+
+func (idx *searchIndex) add(id int, tokens []string) {
+	if len(tokens) == 0 {
+		return
+	}
+	idx.docTerms[id] = tokens
+	idx.totalLen += len(tokens)
+
+	for _, term := range tokens {
+		docs, ok := idx.postings[term]
+		if !ok {
+			docs = make(map[int]int)
+			idx.postings[term] = docs
+
+			i := sort.SearchStrings(idx.terms, term)
+			idx.terms = append(idx.terms, "")
+			copy(idx.terms[i+1:], idx.terms[i:])
+			idx.terms[i] = term
+		}
+		docs[id]++
+	}
+}
+
+func (idx *searchIndex) remove(id int) {
+	tokens, ok := idx.docTerms[id]
+	if !ok {
+		return
+	}
+	delete(idx.docTerms, id)
+	idx.totalLen -= len(tokens)
+
+	for _, term := range tokens {
+		docs := idx.postings[term]
+		delete(docs, id)
+		if len(docs) == 0 {
+			delete(idx.postings, term)
+			if i := sort.SearchStrings(idx.terms, term); i < len(idx.terms) && idx.terms[i] == term {
+				idx.terms = append(idx.terms[:i], idx.terms[i+1:]...)
+			}
+		}
+	}
+}
+
+// 🔎 SEARCH: AND across query tokens, BM25 ranking
+func (idx *searchIndex) Search(query string, limit int) []searchHit {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	queryTokens := tokenize(query)
+	if len(queryTokens) == 0 || len(idx.docTerms) == 0 {
+		return nil
+	}
+
+	avgLen := float64(idx.totalLen) / float64(len(idx.docTerms))
+	scores := make(map[int]float64)
+
+	for i, token := range queryTokens {
+		// Best score per document for this token across its expansions
+		tokenScores := make(map[int]float64)
+		for term, weight := range idx.expand(token) {
+			docs := idx.postings[term]
+			idf := idx.idf(len(docs))
+			for id, tf := range docs {
+				score := weight * idf * bm25TF(tf, len(idx.docTerms[id]), avgLen)
+				if score > tokenScores[id] {
+					tokenScores[id] = score
+				}
+			}
+		}
+
+		if i == 0 {
+			scores = tokenScores
+			continue
+		}
+		for id := range scores {
+			if s, ok := tokenScores[id]; ok {
+				scores[id] += s
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	hits := make([]searchHit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, searchHit{ID: id, Score: score})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if limit > 0 && len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// expand maps a query token to the index terms it matches, with weights
+func (idx *searchIndex) expand(token string) map[string]float64 {
+	matches := make(map[string]float64)
+
+	if _, ok := idx.postings[token]; ok {
+		matches[token] = 1
+	}
+
+	// Prefix matches are a contiguous run in the sorted term list
+	for i := sort.SearchStrings(idx.terms, token); i < len(idx.terms); i++ {
+		term := idx.terms[i]
+		if !strings.HasPrefix(term, token) {
+			break
+		}
+		if term != token {
+			matches[term] = max(matches[term], prefixMatchWeight)
+		}
+	}
+
+	if len([]rune(token)) >= minTypoTermLength {
+		for _, term := range idx.terms {
+			if _, seen := matches[term]; !seen && withinOneEdit(token, term) {
+				matches[term] = typoMatchWeight
+			}
+		}
+	}
+
+	return matches
+}
+
+func (idx *searchIndex) idf(docFreq int) float64 {
+	n := float64(len(idx.docTerms))
+	df := float64(docFreq)
+	return math.Log(1 + (n-df+0.5)/(df+0.5))
+}
+
+func bm25TF(tf, docLen int, avgLen float64) float64 {
+	f := float64(tf)
+	return f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*float64(docLen)/avgLen))
+}
+
+// ✂️ TOKENIZER: Fold case and diacritics, split on anything else
+func tokenize(text string) []string {
+	var tokens []string
+	var current strings.Builder
+
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+
+	for _, r := range text {
+		r = foldRune(unicode.ToLower(r))
+		switch {
+		case r == 'ß':
+			current.WriteString("ss")
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			current.WriteRune(r)
+		case unicode.Is(unicode.Mn, r):
+			// Combining accent from decomposed input; drop it
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// 🌍 DIACRITIC FOLDING: Common Latin letters -> their ASCII base
+var diacriticFolds = map[rune]rune{}
+
+func init() {
+	groups := map[rune]string{
+		'a': "àáâãäåāăą",
+		'c': "çćĉċč",
+		'd': "ďđ",
+		'e': "èéêëēĕėęě",
+		'g': "ĝğġģ",
+		'h': "ĥħ",
+		'i': "ìíîïĩīĭįı",
+		'j': "ĵ",
+		'k': "ķ",
+		'l': "ĺļľŀł",
+		'n': "ñńņňŉ",
+		'o': "òóôõöøōŏő",
+		'r': "ŕŗř",
+		's': "śŝşš",
+		't': "ţťŧ",
+		'u': "ùúûüũūŭůűų",
+		'w': "ŵ",
+		'y': "ýÿŷ",
+		'z': "źżž",
+	}
+	for base, variants := range groups {
+		for _, r := range variants {
+			diacriticFolds[r] = base
+		}
+	}
+}
+
+func foldRune(r rune) rune {
+	if base, ok := diacriticFolds[r]; ok {
+		return base
+	}
+	return r
+}
+
+// ✏️ TYPO TOLERANCE: Levenshtein distance <= 1 without a full DP table
+func withinOneEdit(a, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) < len(rb) {
+		ra, rb = rb, ra
+	}
+	if len(ra)-len(rb) > 1 {
+		return false
+	}
+
+	i, j, edits := 0, 0, 0
+	for i < len(ra) && j < len(rb) {
+		if ra[i] == rb[j] {
+			i++
+			j++
+			continue
+		}
+		edits++
+		if edits > 1 {
+			return false
+		}
+		if len(ra) == len(rb) {
+			j++ // Substitution
+		}
+		i++ // Deletion from the longer string
+	}
+	return edits+(len(ra)-i) <= 1
+}
+
+// 🎯 SEARCH HANDLER: GET /users/search?q=
+type searchResult struct {
+	Score float64     `json:"score"`
+	User  interface{} `json:"user"`
+}
+
+func searchUsersHandler(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Missing search query ?q="})
+		return
+	}
+
+	limit := defaultSearchLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			writeJSON(w, http.StatusBadRequest, APIResponse{Success: false, Error: "Invalid limit"})
+			return
+		}
+		limit = min(n, maxSearchLimit)
+	}
+
+	version := apiVersionFrom(r)
+	results := make([]searchResult, 0)
+	hits := userIndex.Search(query, limit)
+	usersMu.RLock()
+	for _, hit := range hits {
+		index := findUserIndex(hit.ID)
+		if index < 0 {
+			continue
+		}
+		results = append(results, searchResult{
+			Score: math.Round(hit.Score*1000) / 1000,
+			User:  presentUser(version, users[index]),
+		})
+	}
+	usersMu.RUnlock()
+
+	w.Header().Set("Content-Type", mediaTypeFor(version))
+	writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    results,
+		Message: fmt.Sprintf("Found %d users matching %q", len(results), query),
+	})
+}
@@ -0,0 +1,203 @@
+/*
+=============================================================================
+                    🧪 USER SEARCH TESTS
+=============================================================================
+
+Tokenizer folding, the edit-distance check, BM25 ranking order and the
+GET /users/search handler's limits and validation.
+Run with: go test -v -run 'Search|Tokenize|WithinOneEdit' *.go
+*/
+
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"", nil},
+		{"  ", nil},
+		{"John Doe", []string{"john", "doe"}},
+		{"john.doe+tag@Example.COM", []string{"john", "doe", "tag", "example", "com"}},
+		{"José Núñez", []string{"jose", "nunez"}},
+		{"José", []string{"jose"}}, // Decomposed accent
+		{"Straße", []string{"strasse"}},
+		{"Łukasz Dąbrowski", []string{"lukasz", "dabrowski"}},
+		{"agent007", []string{"agent007"}},
+	}
+	for _, tt := range tests {
+		if got := tokenize(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("tokenize(%q) = %q; want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestWithinOneEdit(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"", "", true},
+		{"", "a", true},
+		{"a", "", true},
+		{"", "ab", false},
+		{"john", "john", true},
+		{"jonn", "john", true},  // Substitution
+		{"jon", "john", true},   // Insertion
+		{"johnn", "john", true}, // Deletion
+		{"xjohn", "john", true}, // Edit at the start
+		{"johx", "john", true},  // Edit at the end
+		{"jhon", "john", false}, // Transposition is two edits in Levenshtein
+		{"jo", "john", false},   // Length differs by 2
+		{"john", "jo", false},
+		{"jose", "josé", true}, // Runes, not bytes
+		{"abc", "xyz", false},
+	}
+	for _, tt := range tests {
+		if got := withinOneEdit(tt.a, tt.b); got != tt.want {
+			t.Errorf("withinOneEdit(%q, %q) = %v; want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func rankingIndex() *searchIndex {
+	idx := newSearchIndex()
+	idx.Rebuild([]User{
+		{ID: 1, FirstName: "John", LastName: "Doe", Email: "john@example.com"},
+		{ID: 2, FirstName: "Johnny", LastName: "Smith", Email: "jsmith@example.com"},
+		{ID: 3, FirstName: "Jon", LastName: "Stewart", Email: "jstewart@example.com"},
+		{ID: 4, FirstName: "Jane", LastName: "Doe", Email: "jane.work.account@example.com"},
+		{ID: 5, FirstName: "Zoë", LastName: "Müller", Email: "zm@example.com"},
+	})
+	return idx
+}
+
+func hitIDs(hits []searchHit) []int {
+	ids := make([]int, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ID
+	}
+	return ids
+}
+
+func TestSearchRanking(t *testing.T) {
+	idx := rankingIndex()
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"john", []int{1, 2, 3}}, // Exact (twice), then prefix, then typo
+		{"JOHN", []int{1, 2, 3}},
+		{"doe", []int{1, 4, 5}},  // Same term frequency: the shorter document wins; "zoe" is a typo
+		{"john doe", []int{1}},   // Every query token must match
+		{"jo", []int{1, 2, 3}},   // Prefix only: too short for typos
+		{"smiht", nil},           // Transposition: not within one edit
+		{"smith", []int{2}},      // Exact
+		{"smit", []int{2}},       // Prefix
+		{"muller zoe", []int{5}}, // Diacritics folded on both sides
+		{"", nil},
+		{"kate", nil},
+	}
+	for _, tt := range tests {
+		if got := hitIDs(idx.Search(tt.query, 0)); !slices.Equal(got, tt.want) && !(len(got) == 0 && len(tt.want) == 0) {
+			t.Errorf("Search(%q) = %v; want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSearchScoresDescend(t *testing.T) {
+	hits := rankingIndex().Search("john", 0)
+	for i := 1; i < len(hits); i++ {
+		if hits[i].Score > hits[i-1].Score {
+			t.Errorf("hit %d scores %.3f, above hit %d's %.3f", i, hits[i].Score, i-1, hits[i-1].Score)
+		}
+	}
+	if hits[0].Score <= 0 {
+		t.Errorf("top score = %v; want > 0", hits[0].Score)
+	}
+}
+
+func TestSearchIncrementalUpdates(t *testing.T) {
+	idx := rankingIndex()
+	idx.Index(User{ID: 1, FirstName: "Johann", LastName: "Bach", Email: "jsb@example.com"})
+	if got := hitIDs(idx.Search("doe", 0)); !slices.Equal(got, []int{4, 5}) {
+		t.Errorf("after update, doe = %v; want [4 5]", got)
+	}
+	if got := hitIDs(idx.Search("bach", 0)); !slices.Equal(got, []int{1}) {
+		t.Errorf("after update, bach = %v; want [1]", got)
+	}
+
+	idx.Remove(2)
+	idx.Remove(2) // Removing twice is harmless
+	if got := hitIDs(idx.Search("smith", 0)); len(got) != 0 {
+		t.Errorf("after remove, smith = %v", got)
+	}
+	if slices.Contains(idx.terms, "johnny") {
+		t.Error("removed user's terms left in the prefix list")
+	}
+}
+
+func TestSearchHandler(t *testing.T) {
+	list := append([]User(nil), demoUsers...)
+	for i := len(list) + 1; i <= 130; i++ {
+		list = append(list, User{ID: i, FirstName: "Test", LastName: fmt.Sprint("User", i), Email: fmt.Sprintf("test%d@example.com", i)})
+	}
+	withUsers(t, list)
+	server := httptest.NewServer(setupRoutes())
+	defer server.Close()
+
+	tests := []struct {
+		path   string
+		status int
+		hits   int
+	}{
+		{"/users/search?q=test", http.StatusOK, defaultSearchLimit},
+		{"/users/search?q=test&limit=5", http.StatusOK, 5},
+		{"/users/search?q=test&limit=1000", http.StatusOK, maxSearchLimit},
+		{"/users/search?q=john", http.StatusOK, 2}, // John Doe and Bob Johnson (prefix)
+		{"/users/search?q=nobody", http.StatusOK, 0},
+		{"/users/search", http.StatusBadRequest, 0},
+		{"/users/search?q=%20%20", http.StatusBadRequest, 0},
+		{"/users/search?q=test&limit=0", http.StatusBadRequest, 0},
+		{"/users/search?q=test&limit=-3", http.StatusBadRequest, 0},
+		{"/users/search?q=test&limit=ten", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		resp, body, err := apiRequest(server, http.MethodGet, tt.path, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("GET %s = %d; want %d (%s)", tt.path, resp.StatusCode, tt.status, body)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		var results []struct {
+			Score float64 `json:"score"`
+		}
+		decodeData(t, body, &results)
+		if len(results) != tt.hits {
+			t.Errorf("GET %s: %d results; want %d", tt.path, len(results), tt.hits)
+		}
+	}
+
+	// Versioned routes present results in that version's shape
+	_, body, _ := apiRequest(server, http.MethodGet, "/v2/users/search?q=jane", "", nil)
+	var v2 []struct {
+		User UserV2 `json:"user"`
+	}
+	decodeData(t, body, &v2)
+	if len(v2) != 1 || v2[0].User.FirstName != "Jane" {
+		t.Errorf("v2 search = %s", body)
+	}
+}
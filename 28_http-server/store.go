@@ -0,0 +1,315 @@
+/*
+=============================================================================
+                    💾 USER STORE - HTTP SERVER EXTENSION
+=============================================================================
+
+Users are persisted to an append-only JSON-lines log:
+
+  {"op":"meta","next_id":4}
+  {"op":"put","user":{"id":1,"first_name":"John",...}}
+  {"op":"delete","id":3}
+
+Every change appends one line and fsyncs. Load replays the log, and
+Compact rewrites it as a single snapshot. The server and usersctl open
+the same file, but never at once: OpenUserStore takes <path>.lock and
+fails with ErrStoreLocked while another process holds it, so stop the
+server before running usersctl against its store.
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	opMeta   = "meta"
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// ErrStoreLocked: another server or usersctl has the store open
+var ErrStoreLocked = errors.New("user store is in use by another process")
+
+type storeRecord struct {
+	Op     string `json:"op"`
+	User   *User  `json:"user,omitempty"`
+	ID     int    `json:"id,omitempty"`
+	NextID int    `json:"next_id,omitempty"`
+}
+
+// 💾 USER STORE: Append-only log on disk. Not safe for concurrent use on
+// its own: the server writes to it only while holding usersMu, together
+// with the in-memory users it mirrors.
+type UserStore struct {
+	path    string
+	file    *os.File
+	records int    // Lines in the log, a hint for when to compact
+	unlock  func() // Releases <path>.lock; nil once closed
+}
+
+// 🌍 ACTIVE STORE: nil keeps the server purely in-memory (as in tests)
+var userStore *UserStore
+
+func OpenUserStore(path string) (*UserStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	unlock, err := lockStore(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	return &UserStore{path: path, file: file, unlock: unlock}, nil
+}
+
+// lockStore creates path.lock holding our PID. A lock file works without
+// build tags (flock is Unix-only); one left by a process that is no
+// longer running is taken over.
+func lockStore(path string) (unlock func(), err error) {
+	lock := path + ".lock"
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+		data, _ := os.ReadFile(lock)
+		pid, convErr := strconv.Atoi(strings.TrimSpace(string(data)))
+		if convErr != nil || processRunning(pid) {
+			return nil, fmt.Errorf("%w: %s (pid %s)", ErrStoreLocked, lock, strings.TrimSpace(string(data)))
+		}
+		if err := os.Remove(lock); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+}
+
+// processRunning: signal 0 checks for a process without touching it
+func processRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = proc.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, os.ErrPermission)
+}
+
+func (s *UserStore) Path() string {
+	return s.path
+}
+
+// Records reports how many log lines Load saw plus those appended since
+func (s *UserStore) Records() int {
+	return s.records
+}
+
+// Load replays the log and returns the users (sorted by ID) and next free ID.
+// A torn final line from a crash mid-append is cut off; anything else that
+// fails to parse is reported as corruption.
+func (s *UserStore) Load() ([]User, int, error) {
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+
+	byID := make(map[int]User)
+	nextID := 1
+	s.records = 0
+
+	var offset int64 // End of the last complete record
+	reader := bufio.NewReader(s.file)
+	for line := 1; ; line++ {
+		raw, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(raw)) > 0 {
+			var rec storeRecord
+			if jsonErr := json.Unmarshal(raw, &rec); jsonErr != nil {
+				if err == io.EOF {
+					// Torn write at the tail: drop it so the next append starts clean
+					if err := s.file.Truncate(offset); err != nil {
+						return nil, 0, err
+					}
+					break
+				}
+				return nil, 0, fmt.Errorf("%s:%d: corrupt record: %w", s.path, line, jsonErr)
+			}
+			if err == io.EOF {
+				// Complete record that lost only its newline
+				if _, err := s.file.Write([]byte("\n")); err != nil {
+					return nil, 0, err
+				}
+			}
+			s.records++
+
+			switch rec.Op {
+			case opMeta:
+				nextID = max(nextID, rec.NextID)
+			case opPut:
+				if rec.User == nil {
+					return nil, 0, fmt.Errorf("%s:%d: put without user", s.path, line)
+				}
+				byID[rec.User.ID] = *rec.User
+				nextID = max(nextID, rec.User.ID+1)
+			case opDelete:
+				delete(byID, rec.ID)
+			default:
+				return nil, 0, fmt.Errorf("%s:%d: unknown op %q", s.path, line, rec.Op)
+			}
+		}
+		offset += int64(len(raw))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	list := make([]User, 0, len(byID))
+	for _, u := range byID {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list, nextID, nil
+}
+
+func (s *UserStore) Put(u User) error {
+	return s.append(storeRecord{Op: opPut, User: &u})
+}
+
+func (s *UserStore) Delete(id int) error {
+	return s.append(storeRecord{Op: opDelete, ID: id})
+}
+
+func (s *UserStore) append(rec storeRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := s.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	s.records++
+	return s.file.Sync()
+}
+
+// Compact rewrites the log as one snapshot of the given state
+func (s *UserStore) Compact(list []User, nextID int) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".users-*.db")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+	records := []storeRecord{{Op: opMeta, NextID: nextID}}
+	for i := range list {
+		records = append(records, storeRecord{Op: opPut, User: &list[i]})
+	}
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	// Open the new log before it replaces the old one: once the rename
+	// is done, s.file must not be left on the unlinked file
+	file, err := os.OpenFile(tmp.Name(), os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		file.Close()
+		return err
+	}
+	s.file.Close()
+	s.file = file
+	s.records = len(records)
+	return nil
+}
+
+func (s *UserStore) Close() error {
+	if s.unlock == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.unlock()
+	s.unlock = nil
+	return err
+}
+
+// 🔧 HANDLER HOOKS: No-ops when the server runs without a store. Callers
+// hold usersMu.
+func persistUser(u User) error {
+	if userStore == nil {
+		return nil
+	}
+	return userStore.Put(u)
+}
+
+func persistDelete(id int) error {
+	if userStore == nil {
+		return nil
+	}
+	return userStore.Delete(id)
+}
+
+// loadUserStore opens path and loads it into the global users slice,
+// seeding an empty store with the demo users
+func loadUserStore(path string) (*UserStore, error) {
+	store, err := OpenUserStore(path)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded, nextID, err := store.Load()
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	if store.Records() == 0 {
+		if err := store.Compact(users, nextUserID); err != nil {
+			store.Close()
+			return nil, fmt.Errorf("seed store: %w", err)
+		}
+		return store, nil
+	}
+
+	users, nextUserID = loaded, nextID
+	return store, nil
+}
@@ -0,0 +1,336 @@
+/*
+=============================================================================
+                    🧪 USER STORE TESTS
+=============================================================================
+
+Torn-tail recovery, corruption, compaction, the lock file, and concurrent
+creates through the HTTP handlers against one store file.
+Run with: go test -v -race -run 'Store|Concurrent' *.go
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// demoUsers is the seed data, captured before any test changes users
+var demoUsers = append([]User(nil), users...)
+
+// withUsers installs a copy of list as the server's users, with no store,
+// and restores the previous state when the test ends
+func withUsers(t *testing.T, list []User) {
+	t.Helper()
+	savedUsers, savedNext, savedStore := users, nextUserID, userStore
+	users = append([]User(nil), list...)
+	nextUserID = 1
+	for _, u := range users {
+		nextUserID = max(nextUserID, u.ID+1)
+	}
+	userStore = nil
+	userIndex.Rebuild(users)
+	t.Cleanup(func() {
+		users, nextUserID, userStore = savedUsers, savedNext, savedStore
+		userIndex.Rebuild(users)
+	})
+}
+
+// apiRequest sends an authenticated request; safe to call from any goroutine
+func apiRequest(server *httptest.Server, method, path, body string, header http.Header) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("X-API-Key", "demo-api-key")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	return resp, data, err
+}
+
+func openTestStore(t *testing.T, path string) *UserStore {
+	t.Helper()
+	store, err := OpenUserStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestStoreReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.db")
+	store := openTestStore(t, path)
+	store.Put(User{ID: 1, FirstName: "Ada", Email: "ada@example.com"})
+	store.Put(User{ID: 2, FirstName: "Alan", Email: "alan@example.com"})
+	store.Put(User{ID: 1, FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"})
+	store.Delete(2)
+	store.Close()
+
+	list, nextID, err := openTestStore(t, path).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].LastName != "Lovelace" || nextID != 3 {
+		t.Errorf("Load = %+v, next %d; want Ada Lovelace, next 3", list, nextID)
+	}
+}
+
+func TestStoreTornTailRecovery(t *testing.T) {
+	tests := []struct {
+		name string
+		tail string
+	}{
+		{"half a record", `{"op":"put","user":{"id":3,"first_na`},
+		{"lone brace", `{`},
+		{"garbage", "\x00\x00\x00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "users.db")
+			store := openTestStore(t, path)
+			store.Put(User{ID: 1, FirstName: "Ada", Email: "ada@example.com"})
+			store.Put(User{ID: 2, FirstName: "Alan", Email: "alan@example.com"})
+			good, _ := os.ReadFile(path)
+			appendRaw(t, path, tt.tail) // Crash mid-append
+			store.Close()
+
+			reopened := openTestStore(t, path)
+			list, nextID, err := reopened.Load()
+			if err != nil {
+				t.Fatalf("Load: %v", err)
+			}
+			if len(list) != 2 || nextID != 3 || reopened.Records() != 2 {
+				t.Fatalf("Load = %d users, next %d, %d records; want 2, 3, 2", len(list), nextID, reopened.Records())
+			}
+			if data, _ := os.ReadFile(path); !bytes.Equal(data, good) {
+				t.Errorf("torn tail not cut off:\n%q", data)
+			}
+
+			// The next append starts on a clean line
+			if err := reopened.Put(User{ID: 3, FirstName: "Grace", Email: "grace@example.com"}); err != nil {
+				t.Fatal(err)
+			}
+			reopened.Close()
+			if list, _, err := openTestStore(t, path).Load(); err != nil || len(list) != 3 {
+				t.Errorf("after append: %d users, %v", len(list), err)
+			}
+		})
+	}
+}
+
+func TestStoreRecordMissingNewline(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.db")
+	first := openTestStore(t, path)
+	first.Put(User{ID: 1, FirstName: "Ada", Email: "ada@example.com"})
+	first.Close()
+	appendRaw(t, path, `{"op":"put","user":{"id":2,"first_name":"Alan","email":"alan@example.com"}}`)
+
+	store := openTestStore(t, path)
+	if list, _, err := store.Load(); err != nil || len(list) != 2 {
+		t.Fatalf("Load = %d users, %v; the complete last record should count", len(list), err)
+	}
+	store.Put(User{ID: 3, FirstName: "Grace", Email: "grace@example.com"})
+	store.Close()
+	if list, _, err := openTestStore(t, path).Load(); err != nil || len(list) != 3 {
+		t.Errorf("after append: %d users, %v", len(list), err)
+	}
+}
+
+func TestStoreCorruptionInTheMiddle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.db")
+	store := openTestStore(t, path)
+	store.Put(User{ID: 1, FirstName: "Ada", Email: "ada@example.com"})
+	appendRaw(t, path, "not json\n")
+	store.Put(User{ID: 2, FirstName: "Alan", Email: "alan@example.com"})
+	before, _ := os.ReadFile(path)
+	store.Close()
+
+	_, _, err := openTestStore(t, path).Load()
+	if err == nil || !strings.Contains(err.Error(), ":2: corrupt record") {
+		t.Fatalf("Load = %v; want a corrupt record error for line 2", err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Error("Load changed a corrupt file; later records must survive")
+	}
+}
+
+func TestStoreCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.db")
+	store := openTestStore(t, path)
+	for i := 1; i <= 5; i++ {
+		store.Put(User{ID: i, FirstName: fmt.Sprint("User", i), Email: fmt.Sprintf("u%d@example.com", i)})
+	}
+	for i := 0; i < 10; i++ {
+		store.Put(User{ID: 1, FirstName: fmt.Sprint("Renamed", i), Email: "u1@example.com"})
+	}
+	store.Delete(5) // The highest ID: its number must not be reused
+	list, nextID, _ := store.Load()
+	if store.Records() != 16 {
+		t.Fatalf("%d records before compaction; want 16", store.Records())
+	}
+
+	if err := store.Compact(list, nextID); err != nil {
+		t.Fatal(err)
+	}
+	if store.Records() != 5 { // meta + 4 users
+		t.Errorf("%d records after compaction; want 5", store.Records())
+	}
+	if err := store.Put(User{ID: 6, FirstName: "After", Email: "after@example.com"}); err != nil {
+		t.Fatalf("append after compaction: %v", err)
+	}
+	open, _ := store.file.Stat()
+	if info, _ := os.Stat(path); !os.SameFile(open, info) {
+		t.Error("appends still go to the replaced log")
+	}
+	store.Close()
+
+	reloaded, reloadedNext, err := openTestStore(t, path).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded) != 5 || reloaded[0].FirstName != "Renamed9" || reloadedNext != 7 {
+		t.Errorf("reloaded %+v, next %d", reloaded, reloadedNext)
+	}
+	if temps, _ := filepath.Glob(filepath.Join(filepath.Dir(path), ".users-*")); len(temps) != 0 {
+		t.Errorf("compaction left temp files behind: %v", temps)
+	}
+}
+
+func TestConcurrentCreates(t *testing.T) {
+	withUsers(t, demoUsers)
+	path := filepath.Join(t.TempDir(), "users.db")
+	store, err := loadUserStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	userStore = store
+	server := httptest.NewServer(setupRoutes())
+	defer server.Close()
+
+	const unique, duplicates = 40, 20
+	var wg sync.WaitGroup
+	statuses := make(chan int, unique+duplicates)
+	create := func(name, email string) {
+		defer wg.Done()
+		body := fmt.Sprintf(`{"name":%q,"email":%q}`, name, email)
+		resp, _, err := apiRequest(server, http.MethodPost, "/users", body, nil)
+		if err != nil {
+			t.Errorf("POST /users: %v", err)
+			return
+		}
+		statuses <- resp.StatusCode
+	}
+	for i := 0; i < unique; i++ {
+		wg.Add(1)
+		go create(fmt.Sprint("User ", i), fmt.Sprintf("user%d@example.com", i))
+	}
+	for i := 0; i < duplicates; i++ {
+		wg.Add(1)
+		go create("Dup", "dup@example.com")
+	}
+	wg.Wait()
+	close(statuses)
+
+	created := 0
+	for status := range statuses {
+		if status == http.StatusCreated {
+			created++
+		}
+	}
+	if created != unique+1 {
+		t.Errorf("%d creates succeeded; want %d (one per unique email)", created, unique+1)
+	}
+
+	// Every create got its own ID, in memory and on disk. Two creates
+	// sharing an ID would leave one of them only in memory.
+	store.Close()
+	onDisk, nextID, err := openTestStore(t, path).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := len(demoUsers) + created
+	if len(users) != want || len(onDisk) != want || nextID != nextUserID {
+		t.Errorf("memory has %d users (next %d), disk %d (next %d); want %d", len(users), nextUserID, len(onDisk), nextID, want)
+	}
+	ids, emails := map[int]bool{}, map[string]int{}
+	for _, u := range users {
+		if ids[u.ID] {
+			t.Errorf("ID %d handed out twice", u.ID)
+		}
+		ids[u.ID] = true
+		emails[u.Email]++
+	}
+	if emails["dup@example.com"] != 1 {
+		t.Errorf("dup@example.com stored %d times", emails["dup@example.com"])
+	}
+}
+
+func TestStoreLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.db")
+	store := openTestStore(t, path)
+
+	if _, err := OpenUserStore(path); !errors.Is(err, ErrStoreLocked) {
+		t.Fatalf("second OpenUserStore = %v; want ErrStoreLocked", err)
+	}
+	if code, _, errOut := ctl(t, path, "list"); code != exitError || !strings.Contains(errOut, "in use") {
+		t.Errorf("usersctl against an open store = %d, %q", code, errOut)
+	}
+
+	store.Close()
+	store.Close() // Closing twice must not drop someone else's lock
+	second := openTestStore(t, path)
+	store.Close()
+	if _, err := OpenUserStore(path); !errors.Is(err, ErrStoreLocked) {
+		t.Errorf("a stale Close released the new holder's lock: %v", err)
+	}
+	second.Close()
+
+	// A lock left by a crashed process is taken over
+	os.WriteFile(path+".lock", []byte("2147483646\n"), 0644)
+	openTestStore(t, path).Close()
+}
+
+func appendRaw(t *testing.T, path, data string) {
+	t.Helper()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// decodeData unmarshals an APIResponse's data field into v
+func decodeData(t *testing.T, body []byte, v interface{}) {
+	t.Helper()
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		t.Fatalf("response %s: %v", body, err)
+	}
+	if err := json.Unmarshal(envelope.Data, v); err != nil {
+		t.Fatalf("data %s: %v", envelope.Data, err)
+	}
+}
@@ -0,0 +1,592 @@
+/*
+=============================================================================
+                    🛠️ USERSCTL - OFFLINE ADMIN COMMAND
+=============================================================================
+
+usersctl works directly on the user store file, no server required (and
+refused while a server has the store open). It is built into the server
+binary and selected by name or first argument:
+
+  go build -o usersctl $(ls *.go | grep -v _test.go)
+  ./usersctl list
+  go run $(ls *.go | grep -v _test.go) usersctl -o yaml get 1
+
+Commands:
+  list                                     - all users
+  get <id>                                 - one user
+  create -first F [-last L] -email E       - add a user (-name "F L" also works)
+  update <id> [-first F] [-last L] [-email E] [-name N]
+  delete <id>
+  import [-format json|csv] [-dry-run] <file|->
+  export [-format json|csv] [file|-]
+  compact                                  - rewrite the log as a snapshot
+
+Global flags: -store <path> (default data/users.db), -o table|json|yaml.
+Writes go through validateUser, the same rules the HTTP handlers use.
+*/
+
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+var defaultStorePath = filepath.Join("data", "users.db")
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+var errUsage = errors.New("run usersctl -h for usage")
+
+// 🔀 DISPATCH: "usersctl ..." as program name or first argument
+func isUsersctl(args []string) bool {
+	if strings.TrimSuffix(filepath.Base(args[0]), ".exe") == "usersctl" {
+		return true
+	}
+	return len(args) > 1 && args[1] == "usersctl"
+}
+
+func usersctlArgs(args []string) []string {
+	if strings.TrimSuffix(filepath.Base(args[0]), ".exe") == "usersctl" {
+		return args[1:]
+	}
+	return args[2:]
+}
+
+// 🛠️ COMMAND STATE
+type usersctl struct {
+	store  *UserStore
+	out    io.Writer
+	format string
+}
+
+func runUsersctl(args []string, stdout, stderr io.Writer) int {
+	global := flag.NewFlagSet("usersctl", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	storePath := global.String("store", defaultStorePath, "user store file")
+	format := global.String("o", "table", "output format: table, json or yaml")
+	global.Usage = func() {
+		fmt.Fprintln(stderr, "usage: usersctl [-store path] [-o table|json|yaml] <list|get|create|update|delete|import|export|compact> [args]")
+		global.PrintDefaults()
+	}
+	if err := global.Parse(args); err != nil {
+		return exitUsage
+	}
+	if global.NArg() == 0 {
+		global.Usage()
+		return exitUsage
+	}
+	switch *format {
+	case "table", "json", "yaml":
+	default:
+		fmt.Fprintf(stderr, "usersctl: unknown output format %q\n", *format)
+		return exitUsage
+	}
+
+	commands := map[string]func(*usersctl, []string) error{
+		"list":    (*usersctl).list,
+		"get":     (*usersctl).get,
+		"create":  (*usersctl).create,
+		"update":  (*usersctl).update,
+		"delete":  (*usersctl).remove,
+		"import":  (*usersctl).importUsers,
+		"export":  (*usersctl).exportUsers,
+		"compact": (*usersctl).compact,
+	}
+	name := global.Arg(0)
+	command, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(stderr, "usersctl: unknown command %q\n", name)
+		global.Usage()
+		return exitUsage
+	}
+
+	store, err := loadUserStore(*storePath)
+	if err != nil {
+		fmt.Fprintf(stderr, "usersctl: %v\n", err)
+		return exitError
+	}
+	defer store.Close()
+
+	ctl := &usersctl{store: store, out: stdout, format: *format}
+	if err := command(ctl, global.Args()[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitUsage
+		}
+		fmt.Fprintf(stderr, "usersctl %s: %v\n", name, err)
+		if errors.Is(err, errUsage) {
+			return exitUsage
+		}
+		return exitError
+	}
+	return exitOK
+}
+
+// 📋 READ COMMANDS
+func (c *usersctl) list(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("list takes no arguments: %w", errUsage)
+	}
+	return c.print(users, false)
+}
+
+func (c *usersctl) get(args []string) error {
+	index, err := c.lookup(args)
+	if err != nil {
+		return err
+	}
+	return c.print(users[index:index+1], true)
+}
+
+// ✍️ WRITE COMMANDS
+func (c *usersctl) create(args []string) error {
+	fs, fields := userFlagSet("create")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("unexpected arguments %v: %w", fs.Args(), errUsage)
+	}
+
+	u := fields.apply(fs, User{})
+	u = normalizeUser(u)
+	u.ID = nextUserID
+	u.CreatedAt = time.Now().UTC().Truncate(time.Second)
+	if err := validateUser(u, users); err != nil {
+		return err
+	}
+	if err := c.store.Put(u); err != nil {
+		return err
+	}
+	users = append(users, u)
+	nextUserID++
+
+	return c.print([]User{u}, true)
+}
+
+func (c *usersctl) update(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("update needs a user ID: %w", errUsage)
+	}
+	index, err := c.lookup(args[:1])
+	if err != nil {
+		return err
+	}
+
+	fs, fields := userFlagSet("update")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("unexpected arguments %v: %w", fs.Args(), errUsage)
+	}
+
+	u := normalizeUser(fields.apply(fs, users[index]))
+	if err := validateUser(u, users); err != nil {
+		return err
+	}
+	if err := c.store.Put(u); err != nil {
+		return err
+	}
+	users[index] = u
+
+	return c.print([]User{u}, true)
+}
+
+func (c *usersctl) remove(args []string) error {
+	index, err := c.lookup(args)
+	if err != nil {
+		return err
+	}
+	id := users[index].ID
+	if err := c.store.Delete(id); err != nil {
+		return err
+	}
+	users = append(users[:index], users[index+1:]...)
+
+	fmt.Fprintf(c.out, "deleted user %d\n", id)
+	return nil
+}
+
+func (c *usersctl) compact(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("compact takes no arguments: %w", errUsage)
+	}
+	before := c.store.Records()
+	if err := c.store.Compact(users, nextUserID); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "compacted %s: %d records -> %d\n", c.store.Path(), before, c.store.Records())
+	return nil
+}
+
+// 📥 IMPORT: All-or-nothing; every record is validated before any write
+func (c *usersctl) importUsers(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "", "json or csv (default: from file extension, else json)")
+	dryRun := fs.Bool("dry-run", false, "validate only, write nothing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("import needs exactly one file (or -): %w", errUsage)
+	}
+
+	data, err := readInput(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	records, err := decodeImport(data, pickFormat(*format, fs.Arg(0)))
+	if err != nil {
+		return err
+	}
+
+	// Apply to a working copy so one bad record leaves the store untouched
+	working := append([]User(nil), users...)
+	next := nextUserID
+	now := time.Now().UTC().Truncate(time.Second)
+	var changed []User
+	created, updated := 0, 0
+
+	for i, u := range records {
+		u = normalizeUser(u)
+		existing := -1
+		for j := range working {
+			if u.ID != 0 && working[j].ID == u.ID {
+				existing = j
+				break
+			}
+		}
+
+		switch {
+		case existing >= 0:
+			u.Avatar = working[existing].Avatar
+			if u.CreatedAt.IsZero() {
+				u.CreatedAt = working[existing].CreatedAt
+			}
+		case u.ID == 0:
+			u.ID = next
+		}
+		if u.CreatedAt.IsZero() {
+			u.CreatedAt = now
+		}
+
+		if err := validateUser(u, working); err != nil {
+			return fmt.Errorf("record %d: %w", i+1, err)
+		}
+
+		if existing >= 0 {
+			working[existing] = u
+			updated++
+		} else {
+			working = append(working, u)
+			next = max(next, u.ID+1)
+			created++
+		}
+		changed = append(changed, u)
+	}
+
+	if *dryRun {
+		fmt.Fprintf(c.out, "dry run: %d records valid (%d new, %d updates)\n", len(records), created, updated)
+		return nil
+	}
+
+	for _, u := range changed {
+		if err := c.store.Put(u); err != nil {
+			return err
+		}
+	}
+	users, nextUserID = working, next
+
+	fmt.Fprintf(c.out, "imported %d users (%d new, %d updated)\n", len(changed), created, updated)
+	return nil
+}
+
+// 📤 EXPORT: JSON (v2 API shape) or CSV
+func (c *usersctl) exportUsers(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", "", "json or csv (default: from file extension, else json)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("export takes at most one file: %w", errUsage)
+	}
+	target := fs.Arg(0)
+
+	var buf bytes.Buffer
+	switch pickFormat(*format, target) {
+	case "csv":
+		w := csv.NewWriter(&buf)
+		w.Write(csvHeader)
+		for _, u := range users {
+			w.Write([]string{strconv.Itoa(u.ID), u.FirstName, u.LastName, u.Email, u.CreatedAt.Format(time.RFC3339)})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+	case "json":
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(presentUsers(apiV2, users)); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown format %q: %w", *format, errUsage)
+	}
+
+	if target == "" || target == "-" {
+		_, err := c.out.Write(buf.Bytes())
+		return err
+	}
+	if err := os.WriteFile(target, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "exported %d users to %s\n", len(users), target)
+	return nil
+}
+
+// 🔧 HELPERS
+func (c *usersctl) lookup(args []string) (int, error) {
+	if len(args) != 1 {
+		return -1, fmt.Errorf("expected exactly one user ID: %w", errUsage)
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		return -1, fmt.Errorf("invalid user ID %q", args[0])
+	}
+	index := findUserIndex(id)
+	if index < 0 {
+		return -1, fmt.Errorf("user %d not found", id)
+	}
+	return index, nil
+}
+
+// userFields collects -first/-last/-email/-name; only flags actually given
+// are applied, so update can change one field at a time
+type userFields struct {
+	first, last, email, name *string
+}
+
+func userFlagSet(name string) (*flag.FlagSet, userFields) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	return fs, userFields{
+		first: fs.String("first", "", "first name"),
+		last:  fs.String("last", "", "last name"),
+		email: fs.String("email", "", "email address"),
+		name:  fs.String("name", "", `full name, split like the v1 API ("First Last")`),
+	}
+}
+
+func (f userFields) apply(fs *flag.FlagSet, u User) User {
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "name":
+			u.FirstName, u.LastName = splitName(*f.name)
+		case "first":
+			u.FirstName = *f.first
+		case "last":
+			u.LastName = *f.last
+		case "email":
+			u.Email = *f.email
+		}
+	})
+	return u
+}
+
+func pickFormat(format, path string) string {
+	if format != "" {
+		return format
+	}
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return "csv"
+	}
+	return "json"
+}
+
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+var csvHeader = []string{"id", "first_name", "last_name", "email", "created_at"}
+
+// importRecord accepts the v2 shape plus the v1 "name" field
+type importRecord struct {
+	UserV2
+	Name string `json:"name"`
+}
+
+func (rec importRecord) toUser() User {
+	u := rec.UserV2.toUser()
+	u.ID = rec.ID
+	u.CreatedAt = rec.CreatedAt
+	if u.FirstName == "" && u.LastName == "" && rec.Name != "" {
+		u.FirstName, u.LastName = splitName(rec.Name)
+	}
+	return u
+}
+
+func decodeImport(data []byte, format string) ([]User, error) {
+	switch format {
+	case "json":
+		var records []importRecord
+		if err := json.Unmarshal(data, &records); err != nil {
+			return nil, fmt.Errorf("parse JSON: %w", err)
+		}
+		list := make([]User, 0, len(records))
+		for _, rec := range records {
+			list = append(list, rec.toUser())
+		}
+		return list, nil
+	case "csv":
+		return decodeCSV(data)
+	default:
+		return nil, fmt.Errorf("unknown format %q: %w", format, errUsage)
+	}
+}
+
+func decodeCSV(data []byte) ([]User, error) {
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse CSV: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, errors.New("CSV header must include an email column")
+	}
+	cell := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var list []User
+	for n, row := range rows[1:] {
+		line := n + 2
+		u := User{
+			FirstName: cell(row, "first_name"),
+			LastName:  cell(row, "last_name"),
+			Email:     cell(row, "email"),
+		}
+		if u.FirstName == "" && u.LastName == "" {
+			u.FirstName, u.LastName = splitName(cell(row, "name"))
+		}
+		if raw := cell(row, "id"); raw != "" {
+			if u.ID, err = strconv.Atoi(raw); err != nil || u.ID < 1 {
+				return nil, fmt.Errorf("line %d: invalid id %q", line, raw)
+			}
+		}
+		if raw := cell(row, "created_at"); raw != "" {
+			if u.CreatedAt, err = time.Parse(time.RFC3339, raw); err != nil {
+				return nil, fmt.Errorf("line %d: invalid created_at %q", line, raw)
+			}
+		}
+		list = append(list, u)
+	}
+	return list, nil
+}
+
+// 🖨️ OUTPUT: table, JSON (v2 API shape) or YAML
+func (c *usersctl) print(list []User, single bool) error {
+	switch c.format {
+	case "json":
+		enc := json.NewEncoder(c.out)
+		enc.SetIndent("", "  ")
+		if single {
+			return enc.Encode(presentUser(apiV2, list[0]))
+		}
+		return enc.Encode(presentUsers(apiV2, list))
+	case "yaml":
+		return writeUsersYAML(c.out, list, single)
+	default:
+		tw := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tFIRST NAME\tLAST NAME\tEMAIL\tCREATED\tAVATAR")
+		for _, u := range list {
+			avatar := "-"
+			if u.Avatar != nil {
+				avatar = u.Avatar.URL
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n",
+				u.ID, u.FirstName, u.LastName, u.Email, u.CreatedAt.Format("2006-01-02"), avatar)
+		}
+		return tw.Flush()
+	}
+}
+
+// writeUsersYAML emits the v2 fields in a fixed order; strings that YAML
+// could misread are written as double-quoted (JSON-style) scalars
+func writeUsersYAML(w io.Writer, list []User, single bool) error {
+	var b strings.Builder
+	if !single && len(list) == 0 {
+		b.WriteString("[]\n")
+	}
+	for _, u := range list {
+		fields := [][2]string{
+			{"id", strconv.Itoa(u.ID)},
+			{"first_name", yamlString(u.FirstName)},
+			{"last_name", yamlString(u.LastName)},
+			{"email", yamlString(u.Email)},
+			{"created_at", u.CreatedAt.Format(time.RFC3339)},
+		}
+		if u.Avatar != nil {
+			fields = append(fields, [2]string{"avatar_url", yamlString(u.Avatar.URL)})
+		}
+
+		for i, f := range fields {
+			switch {
+			case single:
+			case i == 0:
+				b.WriteString("- ")
+			default:
+				b.WriteString("  ")
+			}
+			b.WriteString(f[0] + ": " + f[1] + "\n")
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func yamlString(s string) string {
+	if s == "" || strings.TrimSpace(s) != s ||
+		strings.ContainsAny(s, "\"'\\\n\t") ||
+		strings.Contains(s, ": ") || strings.Contains(s, " #") ||
+		strings.ContainsAny(s[:1], "-?:,[]{}#&*!|>%@`") {
+		return strconv.Quote(s)
+	}
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "null", "~":
+		return strconv.Quote(s)
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,145 @@
+/*
+=============================================================================
+                    🧪 USERSCTL TESTS
+=============================================================================
+
+Runs usersctl commands against temporary store files: CSV and JSON import
+and export, all-or-nothing imports, dry runs and compaction.
+Run with: go test -v -run Usersctl *.go
+*/
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ctl runs one usersctl command, as a fresh process would, against store
+func ctl(t *testing.T, store string, args ...string) (int, string, string) {
+	t.Helper()
+	withUsers(t, demoUsers) // Each run loads the store into the globals
+	var stdout, stderr bytes.Buffer
+	code := runUsersctl(append([]string{"-store", store}, args...), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestUsersctlImportExportRoundTrip(t *testing.T) {
+	first := filepath.Join(t.TempDir(), "first.db")
+	csvFile := writeFile(t, "new.csv", "name,email\nAda Lovelace,ada@example.com\n"+
+		"Alan Turing,alan@example.com\n")
+	if code, out, errOut := ctl(t, first, "import", csvFile); code != exitOK || out != "imported 2 users (2 new, 0 updated)\n" {
+		t.Fatalf("import CSV = %d, %q, %q", code, out, errOut)
+	}
+
+	// JSON export from one store, import into an empty one
+	exported := filepath.Join(t.TempDir(), "users.json")
+	if code, out, _ := ctl(t, first, "export", exported); code != exitOK || !strings.Contains(out, "exported 5 users") {
+		t.Fatalf("export = %d, %q", code, out)
+	}
+	second := filepath.Join(t.TempDir(), "second.db")
+	if code, out, errOut := ctl(t, second, "import", exported); code != exitOK || out != "imported 5 users (2 new, 3 updated)\n" {
+		t.Fatalf("import JSON = %d, %q, %q", code, out, errOut)
+	}
+
+	// Both stores now export the same CSV, IDs and timestamps included
+	_, firstCSV, _ := ctl(t, first, "export", "-format", "csv")
+	_, secondCSV, _ := ctl(t, second, "export", "-format", "csv")
+	if firstCSV != secondCSV {
+		t.Errorf("stores differ after round trip:\n%s\nvs\n%s", firstCSV, secondCSV)
+	}
+	lines := strings.Split(strings.TrimSpace(firstCSV), "\n")
+	if len(lines) != 6 || lines[0] != strings.Join(csvHeader, ",") || !strings.HasPrefix(lines[5], "5,Alan,Turing,alan@example.com,") {
+		t.Errorf("CSV export:\n%s", firstCSV)
+	}
+}
+
+func TestUsersctlImportIsAllOrNothing(t *testing.T) {
+	store := filepath.Join(t.TempDir(), "users.db")
+	ctl(t, store, "list") // Seed the demo users
+	before, _ := os.ReadFile(store)
+
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		wantErr string
+	}{
+		{"duplicate email", "dup.csv", "name,email\nAda,ada@example.com\nJohn Again,JOHN@example.com\n", "record 2: invalid user: email is already used by user 1"},
+		{"invalid email", "bad.json", `[{"first_name":"Ada","email":"ada@example.com"},{"first_name":"X","email":"nope"}]`, "record 2: invalid user: email must be a valid address"},
+		{"bad CSV id", "id.csv", "id,name,email\nabc,Ada,ada@example.com\n", "line 2: invalid id"},
+		{"no email column", "cols.csv", "name\nAda\n", "must include an email column"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, errOut := ctl(t, store, "import", writeFile(t, tt.file, tt.data))
+			if code != exitError || !strings.Contains(errOut, tt.wantErr) {
+				t.Errorf("import = %d, %q; want error containing %q", code, errOut, tt.wantErr)
+			}
+			if after, _ := os.ReadFile(store); !bytes.Equal(before, after) {
+				t.Error("a failed import wrote to the store")
+			}
+		})
+	}
+}
+
+func TestUsersctlImportDryRun(t *testing.T) {
+	store := filepath.Join(t.TempDir(), "users.db")
+	ctl(t, store, "list")
+	before, _ := os.ReadFile(store)
+
+	file := writeFile(t, "new.json", `[{"id":1,"name":"John Updated","email":"john@example.com"},{"name":"Ada","email":"ada@example.com"}]`)
+	code, out, _ := ctl(t, store, "import", "-dry-run", file)
+	if code != exitOK || out != "dry run: 2 records valid (1 new, 1 updates)\n" {
+		t.Errorf("dry run = %d, %q", code, out)
+	}
+	if after, _ := os.ReadFile(store); !bytes.Equal(before, after) {
+		t.Error("dry run wrote to the store")
+	}
+}
+
+func TestUsersctlCompact(t *testing.T) {
+	store := filepath.Join(t.TempDir(), "users.db")
+	ctl(t, store, "list")
+	for _, name := range []string{"A", "B", "C"} {
+		if code, _, errOut := ctl(t, store, "update", "1", "-first", name); code != exitOK {
+			t.Fatalf("update: %s", errOut)
+		}
+	}
+	ctl(t, store, "delete", "3")
+
+	code, out, _ := ctl(t, store, "compact")
+	if code != exitOK || !strings.HasSuffix(out, ": 8 records -> 3\n") {
+		t.Errorf("compact = %d, %q", code, out)
+	}
+	if _, out, _ := ctl(t, store, "-o", "json", "get", "1"); !strings.Contains(out, `"first_name": "C"`) {
+		t.Errorf("after compaction: %s", out)
+	}
+}
+
+func TestUsersctlUsageErrors(t *testing.T) {
+	store := filepath.Join(t.TempDir(), "users.db")
+	tests := [][]string{
+		{"export", "-format", "xml"},
+		{"import"},
+		{"frobnicate"},
+		{"-o", "toml", "list"},
+	}
+	for _, args := range tests {
+		if code, _, _ := ctl(t, store, args...); code != exitUsage {
+			t.Errorf("usersctl %v = %d; want %d", args, code, exitUsage)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+/*
+=============================================================================
+                    ✅ USER VALIDATION - HTTP SERVER EXTENSION
+=============================================================================
+
+One set of rules for every write path: the HTTP handlers (any API version)
+and the usersctl admin command both call validateUser, so data entered
+offline is always valid online.
+*/
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	maxNameLength  = 50
+	maxEmailLength = 254
+)
+
+// 🚨 VALIDATION ERRORS: Every problem, not just the first one
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.Field+" "+f.Message)
+	}
+	return "invalid user: " + strings.Join(parts, "; ")
+}
+
+func (e *ValidationError) add(field, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// normalizeUser trims the fields users tend to pad by accident
+func normalizeUser(u User) User {
+	u.FirstName = strings.TrimSpace(u.FirstName)
+	u.LastName = strings.TrimSpace(u.LastName)
+	u.Email = strings.TrimSpace(u.Email)
+	return u
+}
+
+// validateUser checks u against the rules and against existing users
+// (emails are unique, ignoring case; u's own ID is skipped)
+func validateUser(u User, existing []User) error {
+	verr := &ValidationError{}
+
+	switch {
+	case u.FirstName == "":
+		verr.add("first_name", "is required")
+	case utf8.RuneCountInString(u.FirstName) > maxNameLength:
+		verr.add("first_name", "must be at most %d characters", maxNameLength)
+	}
+	if utf8.RuneCountInString(u.LastName) > maxNameLength {
+		verr.add("last_name", "must be at most %d characters", maxNameLength)
+	}
+
+	switch {
+	case u.Email == "":
+		verr.add("email", "is required")
+	case len(u.Email) > maxEmailLength:
+		verr.add("email", "must be at most %d characters", maxEmailLength)
+	default:
+		addr, err := mail.ParseAddress(u.Email)
+		if err != nil || addr.Address != u.Email || !strings.Contains(u.Email[strings.LastIndex(u.Email, "@"):], ".") {
+			verr.add("email", "must be a valid address like name@example.com")
+			break
+		}
+		for _, other := range existing {
+			if other.ID != u.ID && strings.EqualFold(other.Email, u.Email) {
+				verr.add("email", "is already used by user %d", other.ID)
+				break
+			}
+		}
+	}
+
+	if len(verr.Fields) > 0 {
+		return verr
+	}
+	return nil
+}
+
+// writeUserError maps validation failures to 400 and anything else to 500
+func writeUserError(w http.ResponseWriter, err error) {
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		writeJSON(w, http.StatusBadRequest, APIResponse{
+			Success: false,
+			Data:    verr.Fields,
+			Error:   verr.Error(),
+		})
+		return
+	}
+
+	log.Printf("💾 store error: %v", err)
+	writeJSON(w, http.StatusInternalServerError, APIResponse{
+		Success: false,
+		Error:   "Could not save user",
+	})
+}
@@ -0,0 +1,268 @@
+/*
+=============================================================================
+                    🏷️ API VERSIONING - HTTP SERVER EXTENSION
+=============================================================================
+
+Two versions of the users API share one internal User model:
+
+  v1: {"id", "name", "email"}                                 (deprecated)
+  v2: {"id", "first_name", "last_name", "email", "created_at"}
+
+A version is chosen by URL prefix (/v1/users, /v2/users) or, on the
+unprefixed routes, by the Accept header:
+
+  Accept: application/vnd.tutorial.v2+json
+
+Accept q-values are honored. Clients that send no vendor type (plain
+JSON, wildcards, even text/plain) get the default version; only a request
+for an unknown vendor version, with nothing else acceptable, gets a 406.
+
+The URL prefix wins when both are present. Deprecated versions answer with
+Deprecation, Sunset and Link (successor-version) headers.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 🏷️ VERSION ENUM
+type apiVersion int
+
+const (
+	apiV1 apiVersion = iota + 1
+	apiV2
+)
+
+const (
+	defaultAPIVersion = apiV1 // Unversioned clients keep getting v1
+	latestAPIVersion  = apiV2
+	vendorMediaPrefix = "application/vnd.tutorial."
+)
+
+func (v apiVersion) String() string {
+	return "v" + strconv.Itoa(int(v))
+}
+
+// 📅 VERSION LIFECYCLE: Zero dates mean "not deprecated"
+type versionInfo struct {
+	Deprecated time.Time
+	Sunset     time.Time
+}
+
+var apiVersions = map[apiVersion]versionInfo{
+	apiV1: {
+		Deprecated: time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:     time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC),
+	},
+	apiV2: {},
+}
+
+// 📊 VERSIONED DTOs: What goes over the wire
+type UserV1 struct {
+	ID     int     `json:"id"`
+	Name   string  `json:"name"`
+	Email  string  `json:"email"`
+	Avatar *Avatar `json:"avatar,omitempty"`
+}
+
+type UserV2 struct {
+	ID        int       `json:"id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Email     string    `json:"email"`
+	Avatar    *Avatar   `json:"avatar,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 🔄 MAPPERS: DTO <-> internal model
+func toUserV1(u User) UserV1 {
+	return UserV1{ID: u.ID, Name: u.FullName(), Email: u.Email, Avatar: u.Avatar}
+}
+
+func toUserV2(u User) UserV2 {
+	return UserV2{
+		ID:        u.ID,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Email:     u.Email,
+		Avatar:    u.Avatar,
+		CreatedAt: u.CreatedAt,
+	}
+}
+
+func (dto UserV1) toUser() User {
+	first, last := splitName(dto.Name)
+	return User{FirstName: first, LastName: last, Email: dto.Email}
+}
+
+func (dto UserV2) toUser() User {
+	return User{FirstName: dto.FirstName, LastName: dto.LastName, Email: dto.Email}
+}
+
+// splitName treats the first word as the first name and the rest as the last
+func splitName(name string) (first, last string) {
+	name = strings.TrimSpace(name)
+	first, last, _ = strings.Cut(name, " ")
+	return first, strings.TrimSpace(last)
+}
+
+// 📤 PRESENTERS: Pick the DTO for the negotiated version
+func presentUser(v apiVersion, u User) interface{} {
+	if v == apiV2 {
+		return toUserV2(u)
+	}
+	return toUserV1(u)
+}
+
+func presentUsers(v apiVersion, list []User) interface{} {
+	if v == apiV2 {
+		out := make([]UserV2, 0, len(list))
+		for _, u := range list {
+			out = append(out, toUserV2(u))
+		}
+		return out
+	}
+	out := make([]UserV1, 0, len(list))
+	for _, u := range list {
+		out = append(out, toUserV1(u))
+	}
+	return out
+}
+
+// 📥 DECODER: Read a versioned request body into the internal model
+func decodeUser(v apiVersion, body io.Reader) (User, error) {
+	if v == apiV2 {
+		var dto UserV2
+		if err := json.NewDecoder(body).Decode(&dto); err != nil {
+			return User{}, err
+		}
+		return dto.toUser(), nil
+	}
+	var dto UserV1
+	if err := json.NewDecoder(body).Decode(&dto); err != nil {
+		return User{}, err
+	}
+	return dto.toUser(), nil
+}
+
+// 🤝 NEGOTIATION: Read the version from the Accept header
+type versionContextKey struct{}
+
+// negotiateVersion picks the known version with the highest q-value. Generic
+// JSON types, other media types and an empty header all get the default
+// version; ok is false only when the client asks for vendor versions this
+// server doesn't have and accepts nothing else.
+func negotiateVersion(accept string) (version apiVersion, ok bool) {
+	bestVendor, vendorQ := apiVersion(0), 0.0
+	genericQ := -1.0 // No generic JSON type listed
+	askedUnknown := false
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if raw, found := params["q"]; found {
+			if q, err = strconv.ParseFloat(raw, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+
+		switch {
+		case strings.HasPrefix(mediaType, vendorMediaPrefix):
+			v, known := parseVendorVersion(mediaType)
+			if !known {
+				askedUnknown = askedUnknown || q > 0
+				continue
+			}
+			// Equal q-values favor the newer version
+			if q > 0 && (q > vendorQ || q == vendorQ && v > bestVendor) {
+				bestVendor, vendorQ = v, q
+			}
+		case mediaType == "application/json", mediaType == "application/*", mediaType == "*/*":
+			genericQ = max(genericQ, q)
+		}
+	}
+
+	switch {
+	case bestVendor != 0 && vendorQ >= genericQ:
+		return bestVendor, true
+	case genericQ > 0:
+		return defaultAPIVersion, true
+	case askedUnknown:
+		return 0, false
+	}
+	return defaultAPIVersion, true
+}
+
+// parseVendorVersion reads application/vnd.tutorial.v2+json as v2
+func parseVendorVersion(mediaType string) (apiVersion, bool) {
+	name := strings.TrimSuffix(strings.TrimPrefix(mediaType, vendorMediaPrefix), "+json")
+	if !strings.HasPrefix(name, "v") {
+		return 0, false
+	}
+	n, err := strconv.Atoi(name[1:])
+	if err != nil {
+		return 0, false
+	}
+	_, known := apiVersions[apiVersion(n)]
+	return apiVersion(n), known
+}
+
+// 🔧 VERSION MIDDLEWARE: fixed > 0 pins a version (URL prefix), 0 negotiates
+func versionMiddleware(fixed apiVersion, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version := fixed
+		if version == 0 {
+			negotiated, ok := negotiateVersion(r.Header.Get("Accept"))
+			if !ok {
+				writeJSON(w, http.StatusNotAcceptable, APIResponse{
+					Success: false,
+					Error:   fmt.Sprintf("Unsupported media type in Accept; use %sv%d+json", vendorMediaPrefix, int(latestAPIVersion)),
+				})
+				return
+			}
+			version = negotiated
+			w.Header().Add("Vary", "Accept")
+		}
+
+		w.Header().Set("API-Version", version.String())
+		if info := apiVersions[version]; !info.Deprecated.IsZero() {
+			// RFC 9745 structured date and RFC 8594 HTTP-date
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(info.Deprecated.Unix(), 10))
+			if !info.Sunset.IsZero() {
+				w.Header().Set("Sunset", info.Sunset.Format(http.TimeFormat))
+			}
+			w.Header().Add("Link", fmt.Sprintf(`</%s/users>; rel="successor-version"`, latestAPIVersion))
+		}
+
+		ctx := context.WithValue(r.Context(), versionContextKey{}, version)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func apiVersionFrom(r *http.Request) apiVersion {
+	if v, ok := r.Context().Value(versionContextKey{}).(apiVersion); ok {
+		return v
+	}
+	return defaultAPIVersion
+}
+
+// mediaTypeFor is the Content-Type a versioned response is sent with
+func mediaTypeFor(v apiVersion) string {
+	if v == defaultAPIVersion {
+		return "application/json"
+	}
+	return fmt.Sprintf("%s%s+json", vendorMediaPrefix, v)
+}
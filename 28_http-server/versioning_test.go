@@ -0,0 +1,137 @@
+/*
+=============================================================================
+                    🧪 API VERSIONING TESTS
+=============================================================================
+
+Accept header negotiation with q-values, URL-prefixed versions, and the
+deprecation headers on v1.
+Run with: go test -v -run Version *.go
+*/
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	const v1, v2, v9 = "application/vnd.tutorial.v1+json", "application/vnd.tutorial.v2+json", "application/vnd.tutorial.v9+json"
+	tests := []struct {
+		accept string
+		want   apiVersion
+		ok     bool
+	}{
+		{"", defaultAPIVersion, true},
+		{"application/json", defaultAPIVersion, true},
+		{"*/*", defaultAPIVersion, true},
+		{"text/plain", defaultAPIVersion, true}, // Not ours to refuse: fall back
+		{"text/html, application/xhtml+xml;q=0.9", defaultAPIVersion, true},
+		{"not a media type", defaultAPIVersion, true},
+		{v2, apiV2, true},
+		{v1, apiV1, true},
+		{v1 + ", " + v2, apiV2, true}, // Same q: the newer version
+		{v1 + ";q=0.9, " + v2 + ";q=0.5", apiV1, true},
+		{v2 + ";q=0, application/json", defaultAPIVersion, true},
+		{v2 + ";q=0.5, application/json", defaultAPIVersion, true},
+		{v2 + ", application/json;q=0.5", apiV2, true},
+		{v2 + ";q=0.8, */*;q=0.8", apiV2, true}, // A tie goes to the specific type
+		{v2 + ";q=abc, " + v1, apiV1, true},     // Malformed q drops the entry
+		{v9, 0, false},
+		{v9 + ", " + v2 + ";q=0.1", apiV2, true},
+		{v9 + ", application/json;q=0.1", defaultAPIVersion, true},
+		{v9 + ";q=0", defaultAPIVersion, true},
+		{"application/vnd.tutorial.latest+json", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := negotiateVersion(tt.accept)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("negotiateVersion(%q) = %v, %v; want %v, %v", tt.accept, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestVersionedRoutes(t *testing.T) {
+	withUsers(t, demoUsers)
+	server := httptest.NewServer(setupRoutes())
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		path        string
+		accept      string
+		status      int
+		version     string
+		contentType string
+	}{
+		{"default", "/users", "", http.StatusOK, "v1", "application/json"},
+		{"text/plain falls back", "/users", "text/plain", http.StatusOK, "v1", "application/json"},
+		{"negotiated v2", "/users", "application/vnd.tutorial.v2+json", http.StatusOK, "v2", "application/vnd.tutorial.v2+json"},
+		{"unknown version", "/users", "application/vnd.tutorial.v9+json", http.StatusNotAcceptable, "", ""},
+		{"prefix wins", "/v1/users/1", "application/vnd.tutorial.v2+json", http.StatusOK, "v1", "application/json"},
+		{"prefix ignores Accept", "/v2/users", "application/vnd.tutorial.v9+json", http.StatusOK, "v2", "application/vnd.tutorial.v2+json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body, err := apiRequest(server, http.MethodGet, tt.path, "", http.Header{"Accept": {tt.accept}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d; want %d (%s)", resp.StatusCode, tt.status, body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			if got := resp.Header.Get("API-Version"); got != tt.version {
+				t.Errorf("API-Version = %q; want %q", got, tt.version)
+			}
+			if got := resp.Header.Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q; want %q", got, tt.contentType)
+			}
+		})
+	}
+}
+
+func TestVersionDeprecationHeaders(t *testing.T) {
+	withUsers(t, demoUsers)
+	server := httptest.NewServer(setupRoutes())
+	defer server.Close()
+	info := apiVersions[apiV1]
+
+	for _, path := range []string{"/v1/users", "/users"} {
+		resp, _, err := apiRequest(server, http.MethodGet, path, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := resp.Header.Get("Deprecation"), "@"+strconv.FormatInt(info.Deprecated.Unix(), 10); got != want {
+			t.Errorf("%s Deprecation = %q; want %q", path, got, want)
+		}
+		if got, want := resp.Header.Get("Sunset"), info.Sunset.Format(http.TimeFormat); got != want {
+			t.Errorf("%s Sunset = %q; want %q", path, got, want)
+		}
+		if got := resp.Header.Get("Link"); got != `</v2/users>; rel="successor-version"` {
+			t.Errorf("%s Link = %q", path, got)
+		}
+	}
+
+	resp, _, _ := apiRequest(server, http.MethodGet, "/users", "", nil)
+	if resp.Header.Get("Vary") != "Accept" {
+		t.Errorf("negotiated response Vary = %q; want Accept", resp.Header.Get("Vary"))
+	}
+
+	for _, accept := range []string{"", "application/vnd.tutorial.v2+json"} {
+		path := "/v2/users"
+		if accept != "" {
+			path = "/users"
+		}
+		resp, _, _ := apiRequest(server, http.MethodGet, path, "", http.Header{"Accept": {accept}})
+		for _, h := range []string{"Deprecation", "Sunset", "Link"} {
+			if v := resp.Header.Get(h); v != "" {
+				t.Errorf("v2 via %s sent %s: %q", path, h, v)
+			}
+		}
+	}
+}
@@ -0,0 +1,123 @@
+/*
+=============================================================================
+                    📈 AUTOSCALING - WORKER POOLS EXTENSION
+=============================================================================
+
+With MaxWorkers set, the pool resizes itself between MinWorkers and
+MaxWorkers based on its own Stats:
+
+  pool := NewWorkerPool(handler, PoolOptions{
+      MinWorkers: 2,
+      MaxWorkers: 32,
+      TargetWait: 200 * time.Millisecond,
+  })
+
+• Grow: the queue would take longer than TargetWait to clear
+  (queued × p50 latency ÷ workers) for two checks in a row; the pool
+  then grows by half its size
+• Shrink: the queue stayed empty with idle workers for IdleTimeout;
+  the pool then drops half of the idle workers
+• Hysteresis: growing needs a sustained backlog, and shrinking also
+  waits IdleTimeout after the last resize, so bursts don't cause flapping
+
+Resize(n) still works by hand; the autoscaler just picks up from there.
+*/
+
+package main
+
+import "time"
+
+func (o PoolOptions) withScaleDefaults() PoolOptions {
+	if o.MinWorkers <= 0 {
+		o.MinWorkers = 1
+	}
+	if o.MaxWorkers < o.MinWorkers {
+		o.MaxWorkers = o.MinWorkers
+	}
+	if o.Workers <= 0 {
+		o.Workers = o.MinWorkers
+	}
+	o.Workers = max(o.MinWorkers, min(o.Workers, o.MaxWorkers))
+	if o.ScaleInterval <= 0 {
+		o.ScaleInterval = 100 * time.Millisecond
+	}
+	if o.TargetWait <= 0 {
+		o.TargetWait = 500 * time.Millisecond
+	}
+	if o.IdleTimeout <= 0 {
+		o.IdleTimeout = 2 * time.Second
+	}
+	return o
+}
+
+// 🧮 SCALING DECISIONS: Kept apart from the pool so tests can feed it stats
+type autoscaler struct {
+	opts      PoolOptions
+	busyTicks int       // Consecutive checks with too much backlog
+	idleSince time.Time // Zero while there is work to do
+	lastScale time.Time
+}
+
+// decide returns the worker count the pool should have now
+func (a *autoscaler) decide(s PoolStats, now time.Time) int {
+	cur := s.Workers
+	if cur == 0 {
+		return cur
+	}
+
+	if a.overloaded(s) {
+		a.idleSince = time.Time{}
+		a.busyTicks++
+		if a.busyTicks >= 2 && cur < a.opts.MaxWorkers {
+			a.busyTicks = 0
+			a.lastScale = now
+			return min(a.opts.MaxWorkers, cur+max(1, cur/2))
+		}
+		return cur
+	}
+	a.busyTicks = 0
+
+	if s.Queued > 0 || s.Idle == 0 {
+		a.idleSince = time.Time{}
+		return cur
+	}
+	if a.idleSince.IsZero() {
+		a.idleSince = now
+	}
+	if now.Sub(a.idleSince) >= a.opts.IdleTimeout && now.Sub(a.lastScale) >= a.opts.IdleTimeout && cur > a.opts.MinWorkers {
+		a.lastScale = now
+		a.idleSince = now // Each further step needs another idle period
+		return max(a.opts.MinWorkers, cur-max(1, s.Idle/2))
+	}
+	return cur
+}
+
+func (a *autoscaler) overloaded(s PoolStats) bool {
+	if s.Queued == 0 {
+		return false
+	}
+	if s.LatencyP50 == 0 {
+		return s.Queued >= s.Workers // No samples yet: judge by queue length
+	}
+	wait := time.Duration(s.Queued) * s.LatencyP50 / time.Duration(s.Workers)
+	return wait > a.opts.TargetWait
+}
+
+func (p *WorkerPool[In, Out]) autoscale() {
+	a := &autoscaler{opts: p.opts}
+	ticker := time.NewTicker(p.opts.ScaleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s := p.Stats()
+			if n := a.decide(s, now); n != s.Workers {
+				p.Resize(n)
+			}
+		case <-p.finished:
+			return
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,193 @@
+/*
+=============================================================================
+                    🧪 AUTOSCALING TESTS
+=============================================================================
+
+Feeds the scaling rules hand-made stats on a fake timeline, then checks
+Resize, Stats and a real pool growing under a burst and shrinking after.
+Run with: go test -v -race -run 'Autoscale|Resize|Stats' *.go
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestScaler() *autoscaler {
+	return &autoscaler{opts: PoolOptions{
+		MinWorkers: 2, MaxWorkers: 10, TargetWait: 100 * time.Millisecond, IdleTimeout: time.Second,
+	}}
+}
+
+func TestAutoscaleGrowsOnSustainedBacklog(t *testing.T) {
+	a := newTestScaler()
+	now := time.Unix(0, 0)
+	// 40 queued × 20ms ÷ 4 workers = 200ms of waiting, over TargetWait
+	busy := PoolStats{Workers: 4, Active: 4, Queued: 40, LatencyP50: 20 * time.Millisecond}
+
+	if n := a.decide(busy, now); n != 4 {
+		t.Errorf("first busy check -> %d; want to wait for a second one", n)
+	}
+	if n := a.decide(busy, now.Add(100*time.Millisecond)); n != 6 {
+		t.Errorf("second busy check -> %d; want 6", n)
+	}
+
+	// A backlog that clears within TargetWait is fine
+	light := PoolStats{Workers: 6, Active: 6, Queued: 20, LatencyP50: 20 * time.Millisecond}
+	for i := 0; i < 5; i++ {
+		if n := a.decide(light, now); n != 6 {
+			t.Fatalf("light backlog -> %d; want 6", n)
+		}
+	}
+
+	// Growth stops at MaxWorkers
+	huge := PoolStats{Workers: 9, Active: 9, Queued: 500, LatencyP50: 20 * time.Millisecond}
+	a.decide(huge, now)
+	if n := a.decide(huge, now); n != 10 {
+		t.Errorf("capped growth -> %d; want 10", n)
+	}
+}
+
+func TestAutoscaleIgnoresShortBursts(t *testing.T) {
+	a := newTestScaler()
+	now := time.Unix(0, 0)
+	busy := PoolStats{Workers: 4, Active: 4, Queued: 40, LatencyP50: 20 * time.Millisecond}
+	calm := PoolStats{Workers: 4, Active: 4, Queued: 0, LatencyP50: 20 * time.Millisecond}
+
+	// Alternating busy and calm checks never add up to two in a row
+	for i := 0; i < 10; i++ {
+		stats := busy
+		if i%2 == 1 {
+			stats = calm
+		}
+		if n := a.decide(stats, now.Add(time.Duration(i)*100*time.Millisecond)); n != 4 {
+			t.Fatalf("check %d -> %d; want 4", i, n)
+		}
+	}
+}
+
+func TestAutoscaleShrinksAfterIdleTimeout(t *testing.T) {
+	a := newTestScaler()
+	now := time.Unix(0, 0)
+	idle := PoolStats{Workers: 8, Active: 2, Idle: 6}
+
+	if n := a.decide(idle, now); n != 8 {
+		t.Errorf("idle just now -> %d; want 8", n)
+	}
+	if n := a.decide(idle, now.Add(500*time.Millisecond)); n != 8 {
+		t.Errorf("idle for 500ms -> %d; want 8", n)
+	}
+	if n := a.decide(idle, now.Add(time.Second)); n != 5 {
+		t.Errorf("idle for 1s -> %d; want 5 (half the idle workers gone)", n)
+	}
+
+	// The next step needs another full idle period
+	idle = PoolStats{Workers: 5, Idle: 5}
+	if n := a.decide(idle, now.Add(1500*time.Millisecond)); n != 5 {
+		t.Errorf("right after shrinking -> %d; want 5", n)
+	}
+	if n := a.decide(idle, now.Add(2*time.Second)); n != 3 {
+		t.Errorf("second idle period -> %d; want 3", n)
+	}
+	idle = PoolStats{Workers: 3, Idle: 3}
+	if n := a.decide(idle, now.Add(5*time.Second)); n != 2 {
+		t.Errorf("near MinWorkers -> %d; want 2", n)
+	}
+
+	// Work arriving resets the idle clock
+	a.decide(PoolStats{Workers: 2, Active: 2, Queued: 1, LatencyP50: time.Millisecond}, now.Add(6*time.Second))
+	if n := a.decide(PoolStats{Workers: 4, Idle: 4}, now.Add(6500*time.Millisecond)); n != 4 {
+		t.Errorf("idle again after work -> %d; want 4", n)
+	}
+}
+
+func TestResizeAndStats(t *testing.T) {
+	release := make(chan struct{})
+	pool := NewWorkerPool(func(ctx context.Context, n int) (int, error) {
+		<-release
+		if n < 0 {
+			return 0, errors.New("negative")
+		}
+		return n, nil
+	}, PoolOptions{Workers: 2, QueueSize: 20})
+	pool.Start(context.Background())
+
+	for i := 0; i < 10; i++ {
+		pool.Submit(context.Background(), i-2) // Two of them fail
+	}
+	waitFor(t, "2 busy workers", func() bool {
+		s := pool.Stats()
+		return s.Workers == 2 && s.Active == 2 && s.Queued == 8
+	})
+
+	pool.Resize(5)
+	waitFor(t, "5 busy workers", func() bool {
+		s := pool.Stats()
+		return s.Workers == 5 && s.Active == 5 && s.Queued == 5
+	})
+
+	close(release)
+	go collect(pool)
+	waitFor(t, "all jobs done", func() bool {
+		s := pool.Stats()
+		return s.Completed == 8 && s.Failed == 2 && s.Idle == 5
+	})
+
+	// Idle workers retire straight away
+	pool.Resize(1)
+	waitFor(t, "one worker left", func() bool { return pool.Stats().Workers == 1 })
+	if s := pool.Stats(); s.Active != 0 || s.Queued != 0 {
+		t.Errorf("stats = %+v", s)
+	}
+	pool.Shutdown(context.Background())
+
+	// A finished pool doesn't start new workers
+	pool.Resize(4)
+	if s := pool.Stats(); s.Workers != 0 {
+		t.Errorf("Resize after Shutdown started %d workers", s.Workers)
+	}
+}
+
+func TestAutoscaleFollowsBurstyLoad(t *testing.T) {
+	pool := NewWorkerPool(func(ctx context.Context, n int) (int, error) {
+		time.Sleep(10 * time.Millisecond)
+		return n, nil
+	}, PoolOptions{
+		MinWorkers:    1,
+		MaxWorkers:    8,
+		QueueSize:     200,
+		ScaleInterval: 10 * time.Millisecond,
+		TargetWait:    20 * time.Millisecond,
+		IdleTimeout:   50 * time.Millisecond,
+	})
+	pool.Start(context.Background())
+	defer pool.Abort()
+	go func() {
+		for range pool.Results() {
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		pool.Submit(context.Background(), i)
+	}
+	waitFor(t, "growth to MaxWorkers", func() bool { return pool.Stats().Workers == 8 })
+	waitFor(t, "shrink back to MinWorkers", func() bool {
+		s := pool.Stats()
+		return s.Workers == 1 && s.Completed == 200
+	})
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+}
@@ -0,0 +1,267 @@
+/*
+=============================================================================
+                    📊 STREAMING BATCH PROCESSOR - WORKER POOLS EXTENSION
+=============================================================================
+
+Collects items as they arrive and hands them on in batches, e.g. for
+bulk database inserts:
+
+  batcher := NewBatchProcessor(func(ctx context.Context, rows []Row) error {
+      return db.BulkInsert(ctx, rows)
+  }, BatchOptions{BatchSize: 500, MaxWait: time.Second, Flushers: 2})
+  batcher.Start(ctx)
+
+  for row := range rows {
+      batcher.Add(ctx, row) // Blocks when flushes fall behind
+  }
+  err := batcher.Shutdown(ctx) // Flushes the last partial batch
+
+• A batch is flushed when it holds BatchSize items or its first item
+  has waited MaxWait, whichever comes first
+• Up to Flushers batches are flushed at once and Pending more may wait;
+  beyond that Add blocks, so a slow sink slows producers down instead of
+  growing memory
+• A failed flush doesn't stop the processor; Shutdown reports how many
+  failed, and Stats counts them as they happen
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrBatcherClosed is returned by Add once Shutdown was called
+var ErrBatcherClosed = errors.New("batch processor is closed")
+
+// BatchFunc handles one batch; the slice is not reused afterwards
+type BatchFunc[T any] func(ctx context.Context, batch []T) error
+
+// ⚙️ BATCH OPTIONS: A batch flushes when it is full or MaxWait runs out
+type BatchOptions struct {
+	BatchSize int           // Default: 100
+	MaxWait   time.Duration // Longest an item waits for its batch to fill (default: 1s)
+	Flushers  int           // Concurrent flushes (default: 1)
+	Pending   int           // Full batches waiting for a flusher (default: Flushers)
+}
+
+// 📈 BATCH STATS
+type BatchStats struct {
+	Items    int // Items flushed
+	Batches  int
+	BySize   int // Batches flushed because they were full...
+	ByTime   int // ...because MaxWait passed...
+	Final    int // ...or on Shutdown
+	Failed   int
+	Pending  int // Batches waiting for a flusher
+	LastErr  error
+	Buffered int // Items in the batch being filled
+}
+
+type flushReason int
+
+const (
+	flushBySize flushReason = iota
+	flushByTime
+	flushFinal
+)
+
+type pendingBatch[T any] struct {
+	items  []T
+	reason flushReason
+}
+
+// 📊 BATCH PROCESSOR
+type BatchProcessor[T any] struct {
+	flush BatchFunc[T]
+	opts  BatchOptions
+
+	in       chan T // Unbuffered: an accepted item is always in the collector's hands
+	batches  chan pendingBatch[T]
+	closing  chan struct{}
+	finished chan struct{}
+
+	ctx       context.Context // Passed to flushes; cancelled if Shutdown gives up
+	cancel    context.CancelFunc
+	startOnce sync.Once
+	closeOnce sync.Once
+
+	mu    sync.Mutex
+	stats BatchStats
+}
+
+func NewBatchProcessor[T any](flush BatchFunc[T], opts BatchOptions) *BatchProcessor[T] {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	if opts.MaxWait <= 0 {
+		opts.MaxWait = time.Second
+	}
+	if opts.Flushers <= 0 {
+		opts.Flushers = 1
+	}
+	if opts.Pending <= 0 {
+		opts.Pending = opts.Flushers
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &BatchProcessor[T]{
+		flush:    flush,
+		opts:     opts,
+		in:       make(chan T),
+		batches:  make(chan pendingBatch[T], opts.Pending),
+		closing:  make(chan struct{}),
+		finished: make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start launches the collector and flushers; cancelling ctx aborts them
+func (b *BatchProcessor[T]) Start(ctx context.Context) {
+	b.startOnce.Do(func() {
+		context.AfterFunc(ctx, b.cancel)
+		var wg sync.WaitGroup
+		for i := 0; i < b.opts.Flushers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				b.flusher()
+			}()
+		}
+		go b.collect()
+		go func() {
+			wg.Wait()
+			close(b.finished)
+		}()
+	})
+}
+
+// Add queues item for the next batch, blocking while flushes are behind
+func (b *BatchProcessor[T]) Add(ctx context.Context, item T) error {
+	select {
+	case <-b.closing:
+		return ErrBatcherClosed // Checked first so a closed batcher never accepts
+	default:
+	}
+	select {
+	case b.in <- item:
+		return nil
+	case <-b.closing:
+		return ErrBatcherClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-b.ctx.Done():
+		return b.ctx.Err()
+	}
+}
+
+// collect fills batches and passes them to the flushers
+func (b *BatchProcessor[T]) collect() {
+	defer close(b.batches)
+	var batch []T
+	timer := time.NewTimer(b.opts.MaxWait)
+	timer.Stop()
+
+	send := func(reason flushReason) bool {
+		if len(batch) == 0 {
+			return true
+		}
+		timer.Stop()
+		full := pendingBatch[T]{items: batch, reason: reason}
+		batch = nil
+		b.setBuffered(0)
+		select {
+		case b.batches <- full: // Blocks while flushers are behind: backpressure
+			return true
+		case <-b.ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		select {
+		case item := <-b.in:
+			if len(batch) == 0 {
+				timer.Reset(b.opts.MaxWait) // The clock starts with the first item
+				batch = make([]T, 0, b.opts.BatchSize)
+			}
+			batch = append(batch, item)
+			b.setBuffered(len(batch))
+			if len(batch) >= b.opts.BatchSize && !send(flushBySize) {
+				return
+			}
+		case <-timer.C:
+			if !send(flushByTime) {
+				return
+			}
+		case <-b.closing:
+			send(flushFinal)
+			return
+		case <-b.ctx.Done():
+			return
+		}
+	}
+}
+
+func (b *BatchProcessor[T]) flusher() {
+	for batch := range b.batches {
+		if b.ctx.Err() != nil {
+			continue // Aborted: drain without flushing
+		}
+		err := b.flush(b.ctx, batch.items)
+
+		b.mu.Lock()
+		b.stats.Batches++
+		b.stats.Items += len(batch.items)
+		switch batch.reason {
+		case flushBySize:
+			b.stats.BySize++
+		case flushByTime:
+			b.stats.ByTime++
+		case flushFinal:
+			b.stats.Final++
+		}
+		if err != nil {
+			b.stats.Failed++
+			b.stats.LastErr = err
+		}
+		b.mu.Unlock()
+	}
+}
+
+func (b *BatchProcessor[T]) setBuffered(n int) {
+	b.mu.Lock()
+	b.stats.Buffered = n
+	b.mu.Unlock()
+}
+
+func (b *BatchProcessor[T]) Stats() BatchStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.stats
+	s.Pending = len(b.batches)
+	return s
+}
+
+// Shutdown stops intake, flushes what is buffered and waits for every
+// flush to finish. If ctx ends first, running flushes are cancelled and
+// ctx's error returned.
+func (b *BatchProcessor[T]) Shutdown(ctx context.Context) error {
+	b.Start(context.Background()) // So a never-started processor still finishes
+	b.closeOnce.Do(func() { close(b.closing) })
+
+	select {
+	case <-b.finished:
+	case <-ctx.Done():
+		b.cancel() // Buffered and pending batches are dropped
+		return ctx.Err()
+	}
+	if s := b.Stats(); s.Failed > 0 {
+		return fmt.Errorf("%d of %d batches failed, last: %w", s.Failed, s.Batches, s.LastErr)
+	}
+	return nil
+}
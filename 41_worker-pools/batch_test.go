@@ -0,0 +1,222 @@
+/*
+=============================================================================
+                    🧪 STREAMING BATCH PROCESSOR TESTS
+=============================================================================
+
+Size and time-window flushing, the final flush on Shutdown, backpressure
+from a stalled sink, flush errors and many concurrent producers.
+Run with: go test -v -race -run Batch *.go
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// batchRecorder is a BatchFunc that remembers every batch
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]int
+}
+
+func (r *batchRecorder) flush(ctx context.Context, batch []int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, batch)
+	return nil
+}
+
+func (r *batchRecorder) sizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sizes []int
+	for _, b := range r.batches {
+		sizes = append(sizes, len(b))
+	}
+	return sizes
+}
+
+func addAll(t *testing.T, b *BatchProcessor[int], from, to int) {
+	t.Helper()
+	for i := from; i <= to; i++ {
+		if err := b.Add(context.Background(), i); err != nil {
+			t.Fatalf("Add(%d): %v", i, err)
+		}
+	}
+}
+
+func TestBatchFlushBySize(t *testing.T) {
+	rec := &batchRecorder{}
+	b := NewBatchProcessor(rec.flush, BatchOptions{BatchSize: 3, MaxWait: time.Hour})
+	b.Start(context.Background())
+	addAll(t, b, 1, 9)
+	if err := b.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]int{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}
+	for i, batch := range rec.batches {
+		for j := range batch {
+			if batch[j] != want[i][j] {
+				t.Fatalf("batches = %v; want %v", rec.batches, want)
+			}
+		}
+	}
+	if s := b.Stats(); s.BySize != 3 || s.ByTime != 0 || s.Final != 0 || s.Items != 9 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestBatchFlushByTime(t *testing.T) {
+	rec := &batchRecorder{}
+	b := NewBatchProcessor(rec.flush, BatchOptions{BatchSize: 100, MaxWait: 30 * time.Millisecond})
+	b.Start(context.Background())
+	defer b.Shutdown(context.Background())
+
+	start := time.Now()
+	addAll(t, b, 1, 2)
+	waitFor(t, "the time-window flush", func() bool { return b.Stats().ByTime == 1 })
+	if waited := time.Since(start); waited < 30*time.Millisecond {
+		t.Errorf("flushed after %v; want MaxWait first", waited)
+	}
+	if sizes := rec.sizes(); len(sizes) != 1 || sizes[0] != 2 {
+		t.Errorf("batch sizes = %v; want [2]", sizes)
+	}
+
+	// The next batch gets its own full window
+	addAll(t, b, 3, 3)
+	waitFor(t, "the second window", func() bool { return b.Stats().ByTime == 2 })
+}
+
+func TestBatchFinalFlushOnShutdown(t *testing.T) {
+	rec := &batchRecorder{}
+	b := NewBatchProcessor(rec.flush, BatchOptions{BatchSize: 10, MaxWait: time.Hour})
+	b.Start(context.Background())
+	addAll(t, b, 1, 4)
+	waitFor(t, "all four items buffered", func() bool { return b.Stats().Buffered == 4 })
+	if s := b.Stats(); s.Batches != 0 {
+		t.Errorf("before Shutdown: %+v", s)
+	}
+	if err := b.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.Stats(); s.Final != 1 || s.Items != 4 {
+		t.Errorf("after Shutdown: %+v", s)
+	}
+	if err := b.Add(context.Background(), 5); !errors.Is(err, ErrBatcherClosed) {
+		t.Errorf("Add after Shutdown = %v; want ErrBatcherClosed", err)
+	}
+}
+
+func TestBatchBackpressure(t *testing.T) {
+	gate := make(chan struct{})
+	var flushed atomic.Int64
+	b := NewBatchProcessor(func(ctx context.Context, batch []int) error {
+		<-gate
+		flushed.Add(int64(len(batch)))
+		return nil
+	}, BatchOptions{BatchSize: 2, MaxWait: time.Hour, Flushers: 1, Pending: 1})
+	b.Start(context.Background())
+
+	// One batch flushing, one pending, one full in the collector's hands
+	addAll(t, b, 1, 6)
+	waitFor(t, "a full pipeline", func() bool { return b.Stats().Pending == 1 })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := b.Add(ctx, 7); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Add into a stalled batcher = %v; want it to block", err)
+	}
+
+	close(gate)
+	addAll(t, b, 7, 8)
+	if err := b.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if flushed.Load() != 8 {
+		t.Errorf("flushed %d items; want 8", flushed.Load())
+	}
+}
+
+func TestBatchFlushErrors(t *testing.T) {
+	errSink := errors.New("sink rejected batch")
+	b := NewBatchProcessor(func(ctx context.Context, batch []int) error {
+		if batch[0] == 1 {
+			return errSink
+		}
+		return nil
+	}, BatchOptions{BatchSize: 2, MaxWait: time.Hour})
+	b.Start(context.Background())
+	addAll(t, b, 1, 6)
+
+	err := b.Shutdown(context.Background())
+	if !errors.Is(err, errSink) || err.Error() != "1 of 3 batches failed, last: sink rejected batch" {
+		t.Errorf("Shutdown = %v", err)
+	}
+}
+
+func TestBatchShutdownTimeoutCancelsFlush(t *testing.T) {
+	cancelled := make(chan struct{})
+	b := NewBatchProcessor(func(ctx context.Context, batch []int) error {
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	}, BatchOptions{BatchSize: 1})
+	b.Start(context.Background())
+	addAll(t, b, 1, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := b.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown = %v; want DeadlineExceeded", err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the running flush was not cancelled")
+	}
+}
+
+func TestBatchConcurrentProducers(t *testing.T) {
+	var total, batches atomic.Int64
+	b := NewBatchProcessor(func(ctx context.Context, batch []int) error {
+		if len(batch) > 16 {
+			t.Errorf("batch of %d; BatchSize is 16", len(batch))
+		}
+		total.Add(int64(len(batch)))
+		batches.Add(1)
+		return nil
+	}, BatchOptions{BatchSize: 16, MaxWait: time.Millisecond, Flushers: 4})
+	b.Start(context.Background())
+
+	var accepted atomic.Int64
+	var wg sync.WaitGroup
+	for p := 0; p < 8; p++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				if b.Add(context.Background(), i) == nil {
+					accepted.Add(1)
+				}
+			}
+		}()
+	}
+	time.Sleep(5 * time.Millisecond)
+	shutdownErr := b.Shutdown(context.Background()) // Races the producers on purpose
+	wg.Wait()
+
+	if shutdownErr != nil {
+		t.Fatal(shutdownErr)
+	}
+	if total.Load() != accepted.Load() {
+		t.Errorf("flushed %d items; %d were accepted", total.Load(), accepted.Load())
+	}
+	t.Logf("%d items accepted before Shutdown, in %d batches", accepted.Load(), batches.Load())
+}
@@ -0,0 +1,259 @@
+/*
+=============================================================================
+                    ☠️ DEAD-LETTER QUEUE - WORKER POOLS EXTENSION
+=============================================================================
+
+Jobs that fail for good (out of retries, or not retryable) can be parked
+instead of only showing up as a failed Result:
+
+  dlq := NewFileDeadLetters[Job]("failed-jobs.jsonl") // Or &MemoryDeadLetters[Job]{}
+  pool.SetDeadLetters(dlq)
+  ...
+  // Later, once the downstream is fixed, in this or a new process:
+  n, err := pool.Redrive(ctx) // Resubmits every parked job
+
+The file store keeps one JSON object per line, so In must survive a
+round trip through encoding/json. Errors are kept as their message.
+Jobs cut short by Abort are never dead-lettered.
+
+Redrive removes jobs from the store only after they were resubmitted, so
+a crash in between redrives them twice rather than losing them.
+*/
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// 📮 DEAD LETTER
+type DeadLetter[In any] struct {
+	Input    In
+	Priority Priority
+	Attempts int
+	Err      error
+	FailedAt time.Time
+}
+
+// DeadLetterStore keeps failed jobs, oldest first, until Redrive removes them
+type DeadLetterStore[In any] interface {
+	Add(letter DeadLetter[In]) error
+	Peek() ([]DeadLetter[In], error) // Returns everything, leaving it in place
+	Remove(n int) error              // Drops the n oldest
+}
+
+// 🧠 MEMORY STORE
+type MemoryDeadLetters[In any] struct {
+	mu      sync.Mutex
+	letters []DeadLetter[In]
+}
+
+func (m *MemoryDeadLetters[In]) Add(letter DeadLetter[In]) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.letters = append(m.letters, letter)
+	return nil
+}
+
+func (m *MemoryDeadLetters[In]) Peek() ([]DeadLetter[In], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]DeadLetter[In](nil), m.letters...), nil
+}
+
+func (m *MemoryDeadLetters[In]) Remove(n int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.letters = m.letters[min(n, len(m.letters)):]
+	return nil
+}
+
+// Len reports how many jobs are parked
+func (m *MemoryDeadLetters[In]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.letters)
+}
+
+// 📁 FILE STORE: Append-only JSON lines
+type FileDeadLetters[In any] struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileDeadLetters[In any](path string) *FileDeadLetters[In] {
+	return &FileDeadLetters[In]{path: path}
+}
+
+type deadLetterRecord[In any] struct {
+	Input    In        `json:"input"`
+	Priority Priority  `json:"priority"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+}
+
+func (f *FileDeadLetters[In]) Add(letter DeadLetter[In]) error {
+	line, err := json.Marshal(deadLetterRecord[In]{
+		Input:    letter.Input,
+		Priority: letter.Priority,
+		Attempts: letter.Attempts,
+		Error:    letter.Err.Error(),
+		FailedAt: letter.FailedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("encode dead letter: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (f *FileDeadLetters[In]) Peek() ([]DeadLetter[In], error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lines, err := f.readLines()
+	if err != nil {
+		return nil, err
+	}
+
+	letters := make([]DeadLetter[In], 0, len(lines))
+	for i, line := range lines {
+		var rec deadLetterRecord[In]
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", f.path, i+1, err)
+		}
+		letters = append(letters, DeadLetter[In]{
+			Input:    rec.Input,
+			Priority: rec.Priority,
+			Attempts: rec.Attempts,
+			Err:      errors.New(rec.Error),
+			FailedAt: rec.FailedAt,
+		})
+	}
+	return letters, nil
+}
+
+// Remove rewrites the file without its first n lines. Letters added since
+// Peek are after those, so they stay.
+func (f *FileDeadLetters[In]) Remove(n int) error {
+	if n <= 0 {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	lines, err := f.readLines()
+	if err != nil {
+		return err
+	}
+
+	var rest []byte
+	for _, line := range lines[min(n, len(lines)):] {
+		rest = append(append(rest, line...), '\n')
+	}
+	tmpPath := f.path + ".tmp"
+	if err := os.WriteFile(tmpPath, rest, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, f.path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// readLines returns the file's non-empty lines; f.mu must be held
+func (f *FileDeadLetters[In]) readLines() ([][]byte, error) {
+	file, err := os.Open(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines [][]byte
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) > 0 {
+			lines = append(lines, append([]byte(nil), scanner.Bytes()...))
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// SetDeadLetters turns on dead-lettering; call it before Start
+func (p *WorkerPool[In, Out]) SetDeadLetters(store DeadLetterStore[In]) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.deadLetters = store
+}
+
+// deadLetter parks a finally failed job. A store failure is added to the
+// job's error so it isn't lost silently.
+func (p *WorkerPool[In, Out]) deadLetter(t task[In], err error) error {
+	p.mu.Lock()
+	store := p.deadLetters
+	p.mu.Unlock()
+	if store == nil || p.ctx.Err() != nil {
+		return err
+	}
+	letter := DeadLetter[In]{Input: t.in, Priority: t.priority, Attempts: t.attempt, Err: err, FailedAt: time.Now()}
+	if addErr := store.Add(letter); addErr != nil {
+		return errors.Join(err, fmt.Errorf("dead letter: %w", addErr))
+	}
+	p.mu.Lock()
+	p.deadLettered++
+	p.mu.Unlock()
+	return err
+}
+
+// Redrive resubmits every dead-lettered job with fresh attempts and then
+// removes them from the store. If a Submit fails, the jobs not yet
+// resubmitted stay parked.
+func (p *WorkerPool[In, Out]) Redrive(ctx context.Context) (int, error) {
+	p.mu.Lock()
+	store := p.deadLetters
+	p.mu.Unlock()
+	if store == nil {
+		return 0, errors.New("redrive: no dead-letter store set")
+	}
+
+	letters, err := store.Peek()
+	if err != nil {
+		return 0, err
+	}
+	for i, letter := range letters {
+		if err := p.SubmitPriority(ctx, letter.Input, letter.Priority); err != nil {
+			if i == 0 {
+				return 0, err
+			}
+			if removeErr := store.Remove(i); removeErr != nil {
+				err = errors.Join(err, fmt.Errorf("dead letter: %w", removeErr))
+			}
+			return i, err
+		}
+	}
+	if err := store.Remove(len(letters)); err != nil {
+		return len(letters), fmt.Errorf("dead letter: %w", err)
+	}
+	return len(letters), nil
+}
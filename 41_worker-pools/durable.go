@@ -0,0 +1,674 @@
+/*
+=============================================================================
+                    💾 DURABLE QUEUE - WORKER POOLS EXTENSION
+=============================================================================
+
+A job queue that survives restarts, kept in an append-only log file:
+
+  q, err := OpenDurableQueue[Job]("jobs.log", DurableOptions{
+      Sync:              SyncAlways,
+      VisibilityTimeout: 30 * time.Second,
+  })
+  defer q.Close()
+
+  q.Enqueue(Job{ID: 1, Data: "resize image"}, High)
+
+  // Consumer side: ack on success, nack (redeliver) on failure
+  pool := NewWorkerPool(DurableHandler(q, handler), PoolOptions{Workers: 8})
+  pool.Start(ctx)
+  go FeedPool(ctx, q, pool)
+
+Delivery is at-least-once: a job stays in the log until it is acked, so a
+crash between finishing a job and acking it runs that job again. Make
+handlers idempotent.
+
+📜 LOG FORMAT: one record per operation
+  [length uint32][crc32c uint32][JSON {"op":"put"|"ack"|"seq", ...}]
+• A torn tail (a last record cut short or garbled by a crash, or zeroes
+  past it) is truncated back to the last good record. A bad record with
+  good data after it is corruption: OpenDurableQueue fails with
+  ErrLogCorrupt rather than throw later records away
+• Sync picks durability vs speed: fsync every write, every
+  SyncInterval, or leave it to the OS
+• Compaction rewrites only the unacked jobs to a new file and renames it
+  into place once acked records outweigh live ones
+
+⏳ VISIBILITY TIMEOUT: A dequeued job is leased, not removed. If it isn't
+acked within VisibilityTimeout it becomes ready again, so jobs held by a
+crashed or stuck consumer aren't lost. Keep it longer than a job takes,
+including time spent waiting in the pool's own queue.
+
+☠️ DEAD LETTERS: A job that can't be decoded into In, or that was
+delivered MaxDeliveries times, is moved to <path>.dead in the
+FileDeadLetters format, so NewFileDeadLetters[In](path + ".dead") can
+look at it and redrive it once fixed.
+
+One process owns the file at a time.
+*/
+
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueClosed = errors.New("durable queue is closed")
+	ErrLeaseLost   = errors.New("lease expired or job already acked")
+	ErrLogCorrupt  = errors.New("durable queue log is corrupt")
+)
+
+// 💽 SYNC POLICY
+type SyncPolicy int
+
+const (
+	SyncAlways   SyncPolicy = iota // fsync before Enqueue/Ack return
+	SyncInterval                   // fsync in the background every SyncInterval
+	SyncNever                      // Leave flushing to the OS
+)
+
+// ⚙️ DURABLE OPTIONS: fsync policy, leases, compaction and dead-lettering
+type DurableOptions struct {
+	Sync              SyncPolicy
+	SyncInterval      time.Duration // Default: 100ms
+	VisibilityTimeout time.Duration // Default: 30s
+	NackDelay         time.Duration // Wait before a nacked job is ready again (DurableHandler)
+	CompactInterval   time.Duration // How often to consider compacting (default: 1m)
+	CompactMinGarbage int           // Dead records needed before compacting (default: 1000)
+	MaxDeliveries     int           // Dead-letter a job after this many deliveries since open (0: no limit)
+}
+
+func (o DurableOptions) withDefaults() DurableOptions {
+	if o.SyncInterval <= 0 {
+		o.SyncInterval = 100 * time.Millisecond
+	}
+	if o.VisibilityTimeout <= 0 {
+		o.VisibilityTimeout = 30 * time.Second
+	}
+	if o.CompactInterval <= 0 {
+		o.CompactInterval = time.Minute
+	}
+	if o.CompactMinGarbage <= 0 {
+		o.CompactMinGarbage = 1000
+	}
+	return o
+}
+
+// 📨 DELIVERY: One leased job
+type Delivery[In any] struct {
+	ID       uint64
+	Input    In
+	Priority Priority
+	Attempt  int // 1 on first delivery; counts since the queue was opened
+	lease    uint64
+}
+
+type logRecord struct {
+	Op       string          `json:"op"`
+	ID       uint64          `json:"id"`
+	Priority Priority        `json:"pri,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+type durableJob struct {
+	id         uint64
+	priority   Priority
+	data       json.RawMessage
+	deliveries int
+	lease      uint64    // Token of the current delivery; 0 while ready
+	deadline   time.Time // Lease expiry, or when a nacked job is ready again
+}
+
+// 🏔️ READY HEAP: Highest priority first, then oldest
+type readyHeap []*durableJob
+
+func (h readyHeap) Len() int      { return len(h) }
+func (h readyHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h readyHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].id < h[j].id
+}
+func (h *readyHeap) Push(x any) { *h = append(*h, x.(*durableJob)) }
+func (h *readyHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+// 💾 DURABLE QUEUE
+type DurableQueue[In any] struct {
+	path string
+	opts DurableOptions
+
+	mu        sync.Mutex
+	file      *os.File
+	jobs      map[uint64]*durableJob // Every unacked job
+	ready     readyHeap
+	inflight  map[uint64]*durableJob // Leased or waiting out a nack delay
+	nextID    uint64
+	nextLease uint64
+	garbage   int   // Records compaction would drop
+	dirty     bool  // Written since the last fsync
+	syncErr   error // A failed background fsync; every later write fails
+	closed    bool
+	wake      chan struct{} // Closed and replaced when a job becomes ready
+
+	deadLetters *FileDeadLetters[json.RawMessage]
+
+	stop     chan struct{}
+	loopDone chan struct{}
+}
+
+// OpenDurableQueue replays the log at path (creating it if needed)
+func OpenDurableQueue[In any](path string, opts DurableOptions) (*DurableQueue[In], error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	q := &DurableQueue[In]{
+		path:     path,
+		opts:     opts.withDefaults(),
+		file:     file,
+		jobs:     make(map[uint64]*durableJob),
+		inflight: make(map[uint64]*durableJob),
+		nextID:   1,
+		wake:     make(chan struct{}),
+		stop:     make(chan struct{}),
+		loopDone: make(chan struct{}),
+
+		deadLetters: NewFileDeadLetters[json.RawMessage](path + ".dead"),
+	}
+	if err := q.replay(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	go q.loop()
+	return q, nil
+}
+
+// replay rebuilds the queue from the log, cutting off a torn tail
+func (q *DurableQueue[In]) replay() error {
+	info, err := q.file.Stat()
+	if err != nil {
+		return err
+	}
+	reader := bufio.NewReader(q.file)
+	var good int64
+	records := 0
+	for {
+		rec, size, err := readRecord(reader)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Only the end of the file can be torn by a crash; a bad record
+			// followed by real data means the log itself is damaged
+			end := good + size
+			torn := errors.Is(err, io.ErrUnexpectedEOF) || end >= info.Size() || zeroesFrom(q.file, end, info.Size())
+			if !torn {
+				return fmt.Errorf("%w: %s at offset %d: %v", ErrLogCorrupt, q.path, good, err)
+			}
+			if err := q.file.Truncate(good); err != nil {
+				return err
+			}
+			break
+		}
+		good += size
+		records++
+
+		switch rec.Op {
+		case "put":
+			q.jobs[rec.ID] = &durableJob{id: rec.ID, priority: rec.Priority, data: rec.Data}
+			q.nextID = max(q.nextID, rec.ID+1)
+		case "ack":
+			delete(q.jobs, rec.ID)
+		case "seq":
+			q.nextID = max(q.nextID, rec.ID)
+		}
+	}
+	if _, err := q.file.Seek(good, io.SeekStart); err != nil {
+		return err
+	}
+	for _, job := range q.jobs {
+		q.ready = append(q.ready, job)
+	}
+	heap.Init(&q.ready)
+	q.garbage = records - len(q.jobs)
+	return nil
+}
+
+// zeroesFrom reports whether f holds only zero bytes in [from, to), as a
+// file system leaves it when a crash extends a file before writing it
+func zeroesFrom(f *os.File, from, to int64) bool {
+	buf := make([]byte, 32<<10)
+	for from < to {
+		n, err := f.ReadAt(buf[:min(int64(len(buf)), to-from)], from)
+		for _, b := range buf[:n] {
+			if b != 0 {
+				return false
+			}
+		}
+		if err != nil && !(err == io.EOF && from+int64(n) >= to) {
+			return false
+		}
+		from += int64(n)
+	}
+	return true
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// readRecord returns the record's size on disk, also for bad records whose
+// header could be read
+func readRecord(r io.Reader) (logRecord, int64, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return logRecord{}, 0, err // io.EOF on a clean end, else a torn header
+	}
+	length := binary.LittleEndian.Uint32(header[0:4])
+	sum := binary.LittleEndian.Uint32(header[4:8])
+	size := int64(len(header)) + int64(length)
+	if length > 64<<20 {
+		return logRecord{}, size, fmt.Errorf("record length %d out of range", length)
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return logRecord{}, size, io.ErrUnexpectedEOF
+	}
+	if crc32.Checksum(payload, castagnoli) != sum {
+		return logRecord{}, size, errors.New("record checksum mismatch")
+	}
+	var rec logRecord
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return logRecord{}, size, err
+	}
+	return rec, size, nil
+}
+
+func encodeRecord(rec logRecord) ([]byte, error) {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 8, 8+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.Checksum(payload, castagnoli))
+	return append(buf, payload...), nil
+}
+
+// appendLocked writes one record, syncing if the policy says so
+func (q *DurableQueue[In]) appendLocked(rec logRecord) error {
+	if q.syncErr != nil {
+		return fmt.Errorf("log sync failed earlier: %w", q.syncErr)
+	}
+	data, err := encodeRecord(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := q.file.Write(data); err != nil {
+		return err
+	}
+	if q.opts.Sync == SyncAlways {
+		return q.file.Sync()
+	}
+	q.dirty = true
+	return nil
+}
+
+// Enqueue stores in; with SyncAlways it is on disk when this returns
+func (q *DurableQueue[In]) Enqueue(in In, priority Priority) (uint64, error) {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return 0, fmt.Errorf("encode job: %w", err)
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return 0, ErrQueueClosed
+	}
+	id := q.nextID
+	if err := q.appendLocked(logRecord{Op: "put", ID: id, Priority: priority, Data: data}); err != nil {
+		return 0, err
+	}
+	q.nextID++
+	job := &durableJob{id: id, priority: priority, data: data}
+	q.jobs[id] = job
+	heap.Push(&q.ready, job)
+	q.wakeLocked()
+	return id, nil
+}
+
+func (q *DurableQueue[In]) wakeLocked() {
+	close(q.wake)
+	q.wake = make(chan struct{})
+}
+
+// Dequeue leases the most urgent ready job, blocking until there is one
+func (q *DurableQueue[In]) Dequeue(ctx context.Context) (Delivery[In], error) {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return Delivery[In]{}, ErrQueueClosed
+		}
+		if q.ready.Len() > 0 {
+			job := heap.Pop(&q.ready).(*durableJob)
+			if q.opts.MaxDeliveries > 0 && job.deliveries >= q.opts.MaxDeliveries {
+				err := q.deadLetterLocked(job, fmt.Errorf("gave up after %d deliveries", job.deliveries))
+				if err != nil {
+					heap.Push(&q.ready, job) // Not moved: keep it queued
+				}
+				q.mu.Unlock()
+				if err != nil {
+					return Delivery[In]{}, err
+				}
+				continue
+			}
+			q.nextLease++
+			job.lease = q.nextLease
+			job.deliveries++
+			job.deadline = time.Now().Add(q.opts.VisibilityTimeout)
+			q.inflight[job.id] = job
+			d := Delivery[In]{ID: job.id, Priority: job.priority, Attempt: job.deliveries, lease: job.lease}
+			data := job.data
+			q.mu.Unlock()
+
+			if err := json.Unmarshal(data, &d.Input); err != nil {
+				// Redelivering can't fix it, so park it with its raw data
+				err = fmt.Errorf("decode job %d: %w", d.ID, err)
+				q.mu.Lock()
+				if job.lease == d.lease {
+					if dlErr := q.deadLetterLocked(job, err); dlErr != nil {
+						err = errors.Join(err, dlErr) // Still leased: it comes back after the timeout
+					}
+				}
+				q.mu.Unlock()
+				return d, err
+			}
+			return d, nil
+		}
+		wake := q.wake
+		q.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return Delivery[In]{}, ctx.Err()
+		}
+	}
+}
+
+// Ack removes a finished job for good. ErrLeaseLost means the lease ran
+// out first, so the job may run (or have run) again elsewhere.
+func (q *DurableQueue[In]) Ack(d Delivery[In]) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	job, ok := q.inflight[d.ID]
+	if !ok || job.lease != d.lease {
+		return ErrLeaseLost
+	}
+	if err := q.appendLocked(logRecord{Op: "ack", ID: d.ID}); err != nil {
+		return err
+	}
+	delete(q.inflight, d.ID)
+	delete(q.jobs, d.ID)
+	q.garbage += 2 // The put and this ack
+	return nil
+}
+
+// deadLetterLocked moves job to the dead-letter file and acks it. The
+// letter is written first, so a crash in between duplicates it rather than
+// losing it.
+func (q *DurableQueue[In]) deadLetterLocked(job *durableJob, reason error) error {
+	letter := DeadLetter[json.RawMessage]{
+		Input:    job.data,
+		Priority: job.priority,
+		Attempts: job.deliveries,
+		Err:      reason,
+		FailedAt: time.Now(),
+	}
+	if err := q.deadLetters.Add(letter); err != nil {
+		return fmt.Errorf("dead-letter job %d: %w", job.id, err)
+	}
+	if err := q.appendLocked(logRecord{Op: "ack", ID: job.id}); err != nil {
+		return fmt.Errorf("dead-letter job %d: %w", job.id, err)
+	}
+	delete(q.inflight, job.id)
+	delete(q.jobs, job.id)
+	q.garbage += 2
+	return nil
+}
+
+// Nack hands a job back; it is ready again after delay
+func (q *DurableQueue[In]) Nack(d Delivery[In], delay time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	job, ok := q.inflight[d.ID]
+	if !ok || job.lease != d.lease {
+		return ErrLeaseLost
+	}
+	job.lease = 0
+	if delay <= 0 {
+		delete(q.inflight, d.ID)
+		heap.Push(&q.ready, job)
+		q.wakeLocked()
+		return nil
+	}
+	job.deadline = time.Now().Add(delay)
+	return nil
+}
+
+// 📈 DURABLE STATS
+type DurableStats struct {
+	Ready    int
+	InFlight int // Leased, or waiting out a nack delay
+	Garbage  int // Log records the next compaction drops
+}
+
+func (q *DurableQueue[In]) Stats() DurableStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return DurableStats{Ready: q.ready.Len(), InFlight: len(q.inflight), Garbage: q.garbage}
+}
+
+// loop expires leases, releases nacked jobs, syncs and compacts
+func (q *DurableQueue[In]) loop() {
+	defer close(q.loopDone)
+	tick := min(max(q.opts.VisibilityTimeout/10, 5*time.Millisecond), 50*time.Millisecond)
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	lastSync, lastCompact := time.Now(), time.Now()
+
+	for {
+		select {
+		case <-q.stop:
+			return
+		case now := <-ticker.C:
+			q.mu.Lock()
+			released := false
+			for id, job := range q.inflight {
+				if now.After(job.deadline) {
+					job.lease = 0
+					delete(q.inflight, id)
+					heap.Push(&q.ready, job)
+					released = true
+				}
+			}
+			if released {
+				q.wakeLocked()
+			}
+			if q.opts.Sync == SyncInterval && q.dirty && q.syncErr == nil && now.Sub(lastSync) >= q.opts.SyncInterval {
+				// After a failed fsync the kernel may have dropped the dirty
+				// pages and a retry proves nothing, so stop writing
+				if err := q.file.Sync(); err != nil {
+					q.syncErr = err
+				}
+				q.dirty = false
+				lastSync = now
+			}
+			compact := now.Sub(lastCompact) >= q.opts.CompactInterval &&
+				q.garbage >= q.opts.CompactMinGarbage && q.garbage >= len(q.jobs)
+			q.mu.Unlock()
+
+			if compact {
+				q.Compact()
+				lastCompact = now
+			}
+		}
+	}
+}
+
+// Compact rewrites the log with only the unacked jobs
+func (q *DurableQueue[In]) Compact() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	// The new log is opened before the rename and kept open, so there is
+	// no window where q.file points at the unlinked old one
+	tmpPath := q.path + ".compact"
+	tmp, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath) // No-op after the rename
+
+	ids := make([]uint64, 0, len(q.jobs))
+	for id := range q.jobs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	w := bufio.NewWriter(tmp)
+	// The seq record keeps IDs increasing even if the newest jobs were acked
+	records := []logRecord{{Op: "seq", ID: q.nextID}}
+	for _, id := range ids {
+		job := q.jobs[id]
+		records = append(records, logRecord{Op: "put", ID: id, Priority: job.priority, Data: job.data})
+	}
+	for _, rec := range records {
+		data, err := encodeRecord(rec)
+		if err == nil {
+			_, err = w.Write(data)
+		}
+		if err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := os.Rename(tmpPath, q.path); err != nil {
+		tmp.Close()
+		return err
+	}
+	syncDir(filepath.Dir(q.path))
+
+	q.file.Close()
+	q.file = tmp
+	q.garbage = 1 // The seq record
+	q.dirty = false
+	return nil
+}
+
+// syncDir makes a rename durable; not every platform supports it
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// Close syncs and closes the log; leased jobs are redelivered after reopen
+func (q *DurableQueue[In]) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	q.wakeLocked()
+	q.mu.Unlock()
+
+	close(q.stop)
+	<-q.loopDone
+	if err := q.file.Sync(); err != nil {
+		q.file.Close()
+		return err
+	}
+	if err := q.file.Close(); err != nil {
+		return err
+	}
+	if q.syncErr != nil {
+		return fmt.Errorf("log sync failed earlier: %w", q.syncErr)
+	}
+	return nil
+}
+
+// 🔌 POOL GLUE
+
+// DurableHandler acks jobs whose handler succeeds and nacks the rest, so
+// they are retried by redelivery (leave PoolOptions.Retry off)
+func DurableHandler[In, Out any](q *DurableQueue[In], handler Handler[In, Out]) Handler[Delivery[In], Out] {
+	return func(ctx context.Context, d Delivery[In]) (Out, error) {
+		out, err := handler(ctx, d.Input)
+		if err != nil {
+			if nackErr := q.Nack(d, q.opts.NackDelay); nackErr != nil {
+				return out, errors.Join(err, nackErr)
+			}
+			return out, err
+		}
+		if err := q.Ack(d); err != nil {
+			return out, fmt.Errorf("job %d done but not acked, it may run again: %w", d.ID, err)
+		}
+		return out, nil
+	}
+}
+
+// FeedPool moves jobs from q into pool until ctx ends, q closes or the
+// pool stops taking jobs. A job the pool refuses is handed back.
+func FeedPool[In, Out any](ctx context.Context, q *DurableQueue[In], pool *WorkerPool[Delivery[In], Out]) error {
+	for {
+		d, err := q.Dequeue(ctx)
+		if err != nil {
+			if errors.Is(err, ErrQueueClosed) || ctx.Err() != nil {
+				return err
+			}
+			continue // Undecodable job: dead-lettered, or back after its lease
+		}
+		if err := pool.SubmitPriority(ctx, d, d.Priority); err != nil {
+			q.Nack(d, 0)
+			return err
+		}
+	}
+}
@@ -0,0 +1,517 @@
+/*
+=============================================================================
+                    🧪 DURABLE QUEUE TESTS
+=============================================================================
+
+Reopens, torn tails vs corruption mid-log, leases, nacks, compaction,
+dead-lettered jobs and background fsync failures, plus a
+crash test: the test binary re-runs itself as a worker process, gets
+SIGKILLed mid-run, and a fresh pool must finish every job.
+Run with: go test -v -race -run Durable *.go
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestQueue(t *testing.T, path string, opts DurableOptions) *DurableQueue[Job] {
+	t.Helper()
+	q, err := OpenDurableQueue[Job](path, opts)
+	if err != nil {
+		t.Fatalf("OpenDurableQueue: %v", err)
+	}
+	t.Cleanup(func() { q.Close() })
+	return q
+}
+
+func dequeueNow(t *testing.T, q *DurableQueue[Job]) Delivery[Job] {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	d, err := q.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue: %v", err)
+	}
+	return d
+}
+
+func TestDurableSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	q := openTestQueue(t, path, DurableOptions{})
+	q.Enqueue(Job{1, "low"}, Low)
+	q.Enqueue(Job{2, "critical"}, Critical)
+	q.Enqueue(Job{3, "medium"}, Medium)
+	q.Close()
+
+	q = openTestQueue(t, path, DurableOptions{})
+	var got []string
+	for i := 0; i < 3; i++ {
+		d := dequeueNow(t, q)
+		got = append(got, d.Input.Data)
+		if d.Input.Data != "medium" {
+			if err := q.Ack(d); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if strings.Join(got, ",") != "critical,medium,low" {
+		t.Errorf("dequeue order = %v", got)
+	}
+	q.Close()
+
+	// The unacked (leased) job is back after a restart
+	q = openTestQueue(t, path, DurableOptions{})
+	if s := q.Stats(); s.Ready != 1 {
+		t.Fatalf("stats after reopen = %+v; want 1 ready", s)
+	}
+	if d := dequeueNow(t, q); d.Input.Data != "medium" || d.ID != 3 {
+		t.Errorf("redelivered %+v", d)
+	}
+}
+
+func TestDurableTornAndCorruptTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	q := openTestQueue(t, path, DurableOptions{})
+	for i := 1; i <= 5; i++ {
+		q.Enqueue(Job{i, "payload"}, Medium)
+	}
+	q.Close()
+	info, _ := os.Stat(path)
+	goodSize := info.Size()
+
+	// A crash mid-write leaves half a record behind
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.Write([]byte{200, 0, 0, 0, 1, 2, 3, 4, '{', '"'})
+	f.Close()
+
+	q = openTestQueue(t, path, DurableOptions{})
+	if s := q.Stats(); s.Ready != 5 {
+		t.Errorf("after torn write: %+v; want 5 ready", s)
+	}
+	if info, _ := os.Stat(path); info.Size() != goodSize {
+		t.Errorf("size = %d; want the torn tail cut back to %d", info.Size(), goodSize)
+	}
+	if id, err := q.Enqueue(Job{6, "after repair"}, Medium); err != nil || id != 6 {
+		t.Errorf("Enqueue after repair = %d, %v", id, err)
+	}
+	q.Close()
+
+	// Flip a byte inside the last record: its checksum no longer matches
+	data, _ := os.ReadFile(path)
+	data[len(data)-3] ^= 0xff
+	os.WriteFile(path, data, 0o600)
+
+	q = openTestQueue(t, path, DurableOptions{})
+	if s := q.Stats(); s.Ready != 5 {
+		t.Errorf("after corruption: %+v; want the 5 intact jobs", s)
+	}
+}
+
+func TestDurableZeroFilledTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	q := openTestQueue(t, path, DurableOptions{})
+	for i := 1; i <= 3; i++ {
+		q.Enqueue(Job{i, "payload"}, Medium)
+	}
+	q.Close()
+	info, _ := os.Stat(path)
+
+	// The file grew, but the crash came before the data reached it
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.Write(make([]byte, 4096))
+	f.Close()
+
+	q = openTestQueue(t, path, DurableOptions{})
+	if s := q.Stats(); s.Ready != 3 {
+		t.Errorf("after zero tail: %+v; want 3 ready", s)
+	}
+	if after, _ := os.Stat(path); after.Size() != info.Size() {
+		t.Errorf("size = %d; want the zeroes cut back to %d", after.Size(), info.Size())
+	}
+}
+
+func TestDurableCorruptionMidLogFailsOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	q := openTestQueue(t, path, DurableOptions{})
+	for i := 1; i <= 5; i++ {
+		q.Enqueue(Job{i, "payload"}, Medium)
+	}
+	q.Close()
+
+	// Flip a byte in the second record: three good records follow it
+	data, _ := os.ReadFile(path)
+	first := 8 + int(binary.LittleEndian.Uint32(data[0:4]))
+	data[first+10] ^= 0xff
+	os.WriteFile(path, data, 0o600)
+
+	_, err := OpenDurableQueue[Job](path, DurableOptions{})
+	if !errors.Is(err, ErrLogCorrupt) || !strings.Contains(err.Error(), fmt.Sprintf("offset %d", first)) {
+		t.Fatalf("OpenDurableQueue = %v; want ErrLogCorrupt at offset %d", err, first)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, data) {
+		t.Error("a corrupt log was truncated; the records after the damage are gone")
+	}
+}
+
+func TestDurableUndecodableJobIsDeadLettered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	old, err := OpenDurableQueue[string](path, DurableOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old.Enqueue("written by an older version", High)
+	old.Close()
+
+	q := openTestQueue(t, path, DurableOptions{})
+	q.Enqueue(Job{2, "fine"}, Low)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, err := q.Dequeue(ctx); err == nil || !strings.Contains(err.Error(), "decode job 1") {
+		t.Fatalf("Dequeue = %v; want a decode error for job 1", err)
+	}
+	if s := q.Stats(); s.Ready != 1 || s.InFlight != 0 {
+		t.Errorf("stats = %+v; the bad job should be gone, not leased", s)
+	}
+	if d := dequeueNow(t, q); d.ID != 2 {
+		t.Errorf("next delivery = job %d; want 2", d.ID)
+	}
+
+	letters, err := NewFileDeadLetters[string](path + ".dead").Peek()
+	if err != nil || len(letters) != 1 {
+		t.Fatalf("dead letters = %+v, %v", letters, err)
+	}
+	if l := letters[0]; l.Input != "written by an older version" || l.Priority != High || !strings.Contains(l.Err.Error(), "decode job 1") {
+		t.Errorf("dead letter = %+v", l)
+	}
+}
+
+func TestDurableMaxDeliveries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	q := openTestQueue(t, path, DurableOptions{MaxDeliveries: 2})
+	q.Enqueue(Job{1, "poison"}, Medium)
+	for i := 0; i < 2; i++ {
+		q.Nack(dequeueNow(t, q), 0)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if d, err := q.Dequeue(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("third Dequeue = %+v, %v; want nothing left", d, err)
+	}
+	letters, _ := NewFileDeadLetters[Job](path + ".dead").Peek()
+	if len(letters) != 1 || letters[0].Input.Data != "poison" || letters[0].Attempts != 2 {
+		t.Errorf("dead letters = %+v", letters)
+	}
+	q.Close()
+	if s := openTestQueue(t, path, DurableOptions{}).Stats(); s.Ready != 0 {
+		t.Errorf("dead-lettered job came back after reopen: %+v", s)
+	}
+}
+
+func TestDurableMaxDeliveriesKeepsJobWhenDeadLetteringFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	q := openTestQueue(t, path, DurableOptions{MaxDeliveries: 1})
+	q.Enqueue(Job{1, "poison"}, Medium)
+	q.Nack(dequeueNow(t, q), 0)
+
+	q.mu.Lock()
+	good := q.deadLetters
+	q.deadLetters = NewFileDeadLetters[json.RawMessage](filepath.Join(path, "missing", "dead"))
+	q.mu.Unlock()
+	if _, err := q.Dequeue(context.Background()); err == nil {
+		t.Fatal("Dequeue = nil error with an unwritable dead-letter file")
+	}
+	if s := q.Stats(); s.Ready != 1 || s.InFlight != 0 {
+		t.Fatalf("stats = %+v; the job should still be ready", s)
+	}
+
+	q.mu.Lock()
+	q.deadLetters = good
+	q.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := q.Dequeue(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Dequeue after recovery = %v; want the job dead-lettered", err)
+	}
+	if letters, _ := good.Peek(); len(letters) != 1 {
+		t.Errorf("dead letters = %+v; want the poison job", letters)
+	}
+}
+
+func TestDurableBackgroundSyncFailure(t *testing.T) {
+	q := openTestQueue(t, filepath.Join(t.TempDir(), "jobs.log"), DurableOptions{
+		Sync:         SyncInterval,
+		SyncInterval: time.Millisecond,
+	})
+
+	// Writes to a pipe succeed, but fsync on one always fails
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	q.mu.Lock()
+	q.file.Close()
+	q.file = w
+	q.mu.Unlock()
+
+	if _, err := q.Enqueue(Job{1, "buffered"}, Medium); err != nil {
+		t.Fatalf("Enqueue before the sync = %v", err)
+	}
+	var enqueueErr error
+	waitFor(t, "a write to fail after the background sync", func() bool {
+		_, enqueueErr = q.Enqueue(Job{2, "after"}, Medium)
+		return enqueueErr != nil
+	})
+	if !strings.Contains(enqueueErr.Error(), "log sync failed earlier") {
+		t.Errorf("Enqueue = %v", enqueueErr)
+	}
+	if err := q.Close(); err == nil {
+		t.Error("Close = nil after a failed sync")
+	}
+}
+
+func TestDurableVisibilityTimeout(t *testing.T) {
+	q := openTestQueue(t, filepath.Join(t.TempDir(), "jobs.log"), DurableOptions{VisibilityTimeout: 30 * time.Millisecond})
+	q.Enqueue(Job{1, "slow"}, Medium)
+
+	first := dequeueNow(t, q)
+	if s := q.Stats(); s.Ready != 0 || s.InFlight != 1 {
+		t.Errorf("while leased: %+v", s)
+	}
+
+	// Never acked: it comes back once the lease runs out
+	second := dequeueNow(t, q)
+	if second.ID != first.ID || second.Attempt != 2 {
+		t.Errorf("redelivery = %+v; want job 1, attempt 2", second)
+	}
+	if err := q.Ack(first); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("late Ack = %v; want ErrLeaseLost", err)
+	}
+	if err := q.Ack(second); err != nil {
+		t.Errorf("Ack: %v", err)
+	}
+	if err := q.Ack(second); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("double Ack = %v; want ErrLeaseLost", err)
+	}
+}
+
+func TestDurableNackDelay(t *testing.T) {
+	q := openTestQueue(t, filepath.Join(t.TempDir(), "jobs.log"), DurableOptions{VisibilityTimeout: time.Minute})
+	q.Enqueue(Job{1, "retry me"}, Medium)
+
+	d := dequeueNow(t, q)
+	start := time.Now()
+	if err := q.Nack(d, 50*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	again := dequeueNow(t, q)
+	if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Errorf("nacked job back after %v; want the 50ms delay", waited)
+	}
+	if again.Attempt != 2 {
+		t.Errorf("attempt = %d; want 2", again.Attempt)
+	}
+
+	// Without a delay it is ready straight away
+	q.Nack(again, 0)
+	if s := q.Stats(); s.Ready != 1 {
+		t.Errorf("after Nack(0): %+v", s)
+	}
+}
+
+func TestDurableCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	q := openTestQueue(t, path, DurableOptions{})
+	for i := 1; i <= 100; i++ {
+		q.Enqueue(Job{i, strings.Repeat("x", 100)}, Medium)
+	}
+	for i := 0; i < 100; i++ {
+		d := dequeueNow(t, q)
+		if d.ID > 90 {
+			q.Nack(d, time.Hour) // Keep 91-100 around, leased
+			continue
+		}
+		q.Ack(d)
+	}
+	before, _ := os.Stat(path)
+	if err := q.Compact(); err != nil {
+		t.Fatalf("Compact: %v", err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size()*5 > before.Size() {
+		t.Errorf("compaction shrank %d -> %d bytes", before.Size(), after.Size())
+	}
+	// The log stays usable after the swap, and writes go to the new file
+	q.mu.Lock()
+	open, _ := q.file.Stat()
+	q.mu.Unlock()
+	if !os.SameFile(open, after) {
+		t.Error("queue is still writing to the replaced log")
+	}
+	q.Enqueue(Job{101, "new"}, Medium)
+	q.Close()
+
+	q = openTestQueue(t, path, DurableOptions{})
+	if s := q.Stats(); s.Ready != 11 {
+		t.Errorf("after reopen: %+v; want 10 kept + 1 new", s)
+	}
+	if id, _ := q.Enqueue(Job{102, "newer"}, Medium); id != 102 {
+		t.Errorf("next id = %d; want 102 (ids never go back)", id)
+	}
+}
+
+func TestDurableAutoCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.log")
+	q := openTestQueue(t, path, DurableOptions{
+		Sync:              SyncInterval,
+		SyncInterval:      5 * time.Millisecond,
+		CompactInterval:   10 * time.Millisecond,
+		CompactMinGarbage: 20,
+	})
+	for i := 1; i <= 30; i++ {
+		q.Enqueue(Job{i, "x"}, Low)
+		q.Ack(dequeueNow(t, q))
+	}
+	waitFor(t, "background compaction", func() bool { return q.Stats().Garbage <= 1 })
+	if info, _ := os.Stat(path); info.Size() > 100 {
+		t.Errorf("log is %d bytes after compacting everything away", info.Size())
+	}
+}
+
+// 💥 CRASH RECOVERY
+
+const crashHelperEnv = "DURABLE_CRASH_HELPER_DIR"
+
+// TestDurableCrashHelper is the worker process for the crash test
+func TestDurableCrashHelper(t *testing.T) {
+	dir := os.Getenv(crashHelperEnv)
+	if dir == "" {
+		t.Skip("only runs as the crash test's child process")
+	}
+	runDurableWorkers(t, dir, context.Background())
+}
+
+// runDurableWorkers works through the queue, logging each finished job
+// to done.log before acking it, until ctx ends or the queue is empty
+func runDurableWorkers(t *testing.T, dir string, ctx context.Context) {
+	q, err := OpenDurableQueue[Job](filepath.Join(dir, "jobs.log"), DurableOptions{
+		Sync:              SyncInterval, // Lost acks only cause duplicates
+		SyncInterval:      5 * time.Millisecond,
+		VisibilityTimeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	done, err := os.OpenFile(filepath.Join(dir, "done.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer done.Close()
+
+	pool := NewWorkerPool(DurableHandler(q, func(ctx context.Context, job Job) (int, error) {
+		time.Sleep(2 * time.Millisecond)
+		if _, err := fmt.Fprintf(done, "%d\n", job.ID); err != nil {
+			return 0, err
+		}
+		return job.ID, done.Sync()
+	}), PoolOptions{Workers: 4, QueueSize: 4})
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pool.Start(ctx)
+	go FeedPool(ctx, q, pool)
+	for range pool.Results() {
+		if s := q.Stats(); s.Ready == 0 && s.InFlight == 0 {
+			cancel()
+		}
+	}
+}
+
+func TestDurableCrashRecovery(t *testing.T) {
+	if testing.Short() {
+		t.Skip("spawns a child process")
+	}
+	dir := t.TempDir()
+	const jobs = 300
+	q := openTestQueue(t, filepath.Join(dir, "jobs.log"), DurableOptions{})
+	for i := 1; i <= jobs; i++ {
+		q.Enqueue(Job{i, "work"}, Priority(i%4))
+	}
+	q.Close()
+
+	child := exec.Command(os.Args[0], "-test.run=^TestDurableCrashHelper$")
+	child.Env = append(os.Environ(), crashHelperEnv+"="+dir)
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the child to get going", func() bool { return len(readDoneLog(t, dir)) >= 50 })
+	child.Process.Kill() // SIGKILL: no cleanup, no final sync
+	child.Wait()
+
+	finishedByChild := len(readDoneLog(t, dir))
+	if finishedByChild >= jobs {
+		t.Fatalf("child finished all %d jobs before the kill; slow it down", jobs)
+	}
+
+	// A new "process" picks up where the dead one stopped
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	runDurableWorkers(t, dir, ctx)
+
+	counts := make(map[int]int)
+	for _, id := range readDoneLog(t, dir) {
+		counts[id]++
+	}
+	duplicates := 0
+	for id := 1; id <= jobs; id++ {
+		switch {
+		case counts[id] == 0:
+			t.Errorf("job %d was lost", id)
+		case counts[id] > 1:
+			duplicates++
+		}
+	}
+	t.Logf("child finished %d jobs before SIGKILL; %d ran twice", finishedByChild, duplicates)
+
+	q = openTestQueue(t, filepath.Join(dir, "jobs.log"), DurableOptions{})
+	if s := q.Stats(); s.Ready != 0 {
+		t.Errorf("%d jobs left in the queue", s.Ready)
+	}
+}
+
+func readDoneLog(t *testing.T, dir string) []int {
+	t.Helper()
+	f, err := os.Open(filepath.Join(dir, "done.log"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var ids []int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if id, err := strconv.Atoi(scanner.Text()); err == nil {
+			ids = append(ids, id) // A torn last line is skipped
+		}
+	}
+	return ids
+}
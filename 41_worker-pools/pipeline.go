@@ -0,0 +1,407 @@
+/*
+=============================================================================
+                    🔗 PIPELINES - WORKER POOLS EXTENSION
+=============================================================================
+
+Chains stages with typed channels instead of wiring pools by hand:
+
+  p := NewPipeline(ctx)
+  lines := Source(p, "read", readLines)
+  words := FlatMap(lines, "split", StageOptions{Workers: 2}, splitWords)
+  kept := Filter(words, "stopwords", StageOptions{}, notStopword)
+  rows := Map(kept, "enrich", StageOptions{Workers: 8, Buffer: 64}, enrich)
+  ForEach(Batch(rows, "batch", BatchOptions{BatchSize: 500}), "insert", insertRows)
+  err := p.Wait()
+
+• Map, Filter and FlatMap run on a WorkerPool, so Workers, Ordered,
+  JobTimeout and Retry behave as in pool.go and retry.go
+• Buffer is how many items may wait at a stage's input and output;
+  beyond that the upstream stage blocks
+• The first error (after retries) cancels the pipeline's context, every
+  stage stops, and Wait returns it as "stage <name>: <err>"; cancelling
+  the context passed to NewPipeline does the same
+• Every Stream must be read by exactly one stage, ending in ForEach,
+  or Wait never returns
+• Stats reports items in and out, throughput and latency per stage
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 🔗 PIPELINE: Owns the context and metrics of its stages
+type Pipeline struct {
+	ctx      context.Context // Cancelled with the first stage error as cause
+	cancel   context.CancelCauseFunc
+	wg       sync.WaitGroup // One per running stage goroutine
+	waitOnce sync.Once
+	err      error
+
+	mu     sync.Mutex
+	stages []*stageMetrics
+}
+
+// Stream is a typed channel between two stages
+type Stream[T any] struct {
+	p  *Pipeline
+	ch <-chan T
+}
+
+// ⚙️ STAGE OPTIONS: Each stage runs its own WorkerPool built from these
+type StageOptions struct {
+	Workers    int  // Default: runtime.NumCPU()
+	Buffer     int  // Items queued at the input and output (default: 2 * Workers)
+	Ordered    bool // Emit in input order
+	JobTimeout time.Duration
+	Retry      RetryPolicy
+}
+
+func NewPipeline(ctx context.Context) *Pipeline {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return &Pipeline{ctx: ctx, cancel: cancel}
+}
+
+// Wait blocks until every stage has finished and returns the error that
+// stopped the pipeline, if any
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	p.waitOnce.Do(func() {
+		p.err = context.Cause(p.ctx)
+		p.cancel(nil)
+	})
+	return p.err
+}
+
+// fail cancels the pipeline; errors after that are fallout and ignored
+func (p *Pipeline) fail(stage string, err error) {
+	if p.ctx.Err() == nil {
+		p.cancel(fmt.Errorf("stage %s: %w", stage, err))
+	}
+}
+
+// emit hands v to the next stage unless the pipeline is cancelled first
+func emit[T any](ctx context.Context, ch chan<- T, v T) bool {
+	select {
+	case ch <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// 🚰 SOURCES
+
+// Source runs gen in its own goroutine; emit returns an error once the
+// pipeline is cancelled, and gen should stop then
+func Source[T any](p *Pipeline, name string, gen func(ctx context.Context, emit func(T) error) error) Stream[T] {
+	m := p.addStage(name, 1)
+	out := make(chan T)
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer m.finish()
+		defer close(out)
+		last := time.Now()
+		err := gen(p.ctx, func(v T) error {
+			m.received()
+			m.observe(time.Since(last)) // Time spent producing v
+			if !emit(p.ctx, out, v) {
+				return context.Cause(p.ctx)
+			}
+			m.emitted()
+			last = time.Now()
+			return nil
+		})
+		if err != nil && p.ctx.Err() == nil {
+			m.failed()
+			p.fail(name, err)
+		}
+	}()
+	return Stream[T]{p: p, ch: out}
+}
+
+// Values is a Source that emits items in order
+func Values[T any](p *Pipeline, name string, items ...T) Stream[T] {
+	return Source(p, name, func(ctx context.Context, emit func(T) error) error {
+		for _, v := range items {
+			if err := emit(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Merge fans several streams into one, in arrival order. The first
+// stream is a separate parameter so there is always one to merge.
+func Merge[T any](name string, first Stream[T], rest ...Stream[T]) Stream[T] {
+	ins := append([]Stream[T]{first}, rest...)
+	p := first.p
+	m := p.addStage(name, len(ins))
+	out := make(chan T)
+	var wg sync.WaitGroup
+	for _, in := range ins {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for v := range in.ch {
+				m.received()
+				if !emit(p.ctx, out, v) {
+					return
+				}
+				m.emitted()
+			}
+		}()
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		wg.Wait()
+		m.finish()
+		close(out)
+	}()
+	return Stream[T]{p: p, ch: out}
+}
+
+// ⚙️ POOL STAGES
+
+func Map[In, Out any](in Stream[In], name string, opts StageOptions, fn Handler[In, Out]) Stream[Out] {
+	return poolStage(in, name, opts, func(ctx context.Context, v In) ([]Out, error) {
+		out, err := fn(ctx, v)
+		if err != nil {
+			return nil, err
+		}
+		return []Out{out}, nil
+	})
+}
+
+// Filter passes on the items keep returns true for
+func Filter[T any](in Stream[T], name string, opts StageOptions, keep func(ctx context.Context, v T) (bool, error)) Stream[T] {
+	return poolStage(in, name, opts, func(ctx context.Context, v T) ([]T, error) {
+		ok, err := keep(ctx, v)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return []T{v}, nil
+	})
+}
+
+// FlatMap emits every item fn returns, zero or more per input
+func FlatMap[In, Out any](in Stream[In], name string, opts StageOptions, fn Handler[In, []Out]) Stream[Out] {
+	return poolStage(in, name, opts, fn)
+}
+
+// poolStage feeds a WorkerPool from in and passes its outputs on
+func poolStage[In, Out any](in Stream[In], name string, opts StageOptions, fn Handler[In, []Out]) Stream[Out] {
+	p := in.p
+	var m *stageMetrics
+	pool := NewWorkerPool(func(ctx context.Context, v In) ([]Out, error) {
+		start := time.Now()
+		defer func() { m.observe(time.Since(start)) }()
+		return fn(ctx, v)
+	}, PoolOptions{
+		Workers:    opts.Workers,
+		QueueSize:  opts.Buffer,
+		Ordered:    opts.Ordered,
+		JobTimeout: opts.JobTimeout,
+		Retry:      opts.Retry,
+	})
+	m = p.addStage(name, pool.opts.Workers)
+	out := make(chan Out, pool.opts.QueueSize)
+	pool.Start(p.ctx)
+
+	p.wg.Add(2)
+	go func() {
+		defer p.wg.Done()
+		defer pool.Shutdown(context.Background()) // Already aborted if p.ctx ended
+		for v := range in.ch {
+			m.received()
+			if pool.Submit(p.ctx, v) != nil {
+				return
+			}
+		}
+	}()
+	go func() {
+		defer p.wg.Done()
+		defer m.finish()
+		defer close(out)
+		// Results is read to the end even once cancelled, so the pool can finish
+		for r := range pool.Results() {
+			if p.ctx.Err() != nil {
+				continue
+			}
+			if r.Err != nil {
+				m.failed()
+				p.fail(name, r.Err)
+				continue
+			}
+			for _, v := range r.Output {
+				if !emit(p.ctx, out, v) {
+					break
+				}
+				m.emitted()
+			}
+		}
+	}()
+	return Stream[Out]{p: p, ch: out}
+}
+
+// 📦 BATCH STAGE
+
+type stamped[T any] struct {
+	v  T
+	at time.Time
+}
+
+// Batch groups items with a BatchProcessor (see batch.go); its latency
+// is how long the oldest item in each batch waited
+func Batch[T any](in Stream[T], name string, opts BatchOptions) Stream[[]T] {
+	p := in.p
+	m := p.addStage(name, 1)
+	out := make(chan []T)
+	batcher := NewBatchProcessor(func(ctx context.Context, batch []stamped[T]) error {
+		m.observe(time.Since(batch[0].at))
+		items := make([]T, len(batch))
+		for i, s := range batch {
+			items[i] = s.v
+		}
+		if !emit(ctx, out, items) {
+			return ctx.Err()
+		}
+		m.emitted()
+		return nil
+	}, opts)
+	batcher.Start(p.ctx)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer m.finish()
+		defer close(out)
+		for v := range in.ch {
+			m.received()
+			if batcher.Add(p.ctx, stamped[T]{v: v, at: time.Now()}) != nil {
+				break
+			}
+		}
+		batcher.Shutdown(context.Background()) // Can only fail by cancellation
+	}()
+	return Stream[[]T]{p: p, ch: out}
+}
+
+// 🏁 SINK
+
+// ForEach ends a pipeline, calling fn for each item in turn
+func ForEach[T any](in Stream[T], name string, fn func(ctx context.Context, v T) error) {
+	p := in.p
+	m := p.addStage(name, 1)
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer m.finish()
+		for v := range in.ch {
+			m.received()
+			if p.ctx.Err() != nil {
+				continue
+			}
+			start := time.Now()
+			err := fn(p.ctx, v)
+			m.observe(time.Since(start))
+			if err != nil {
+				m.failed()
+				p.fail(name, err)
+			}
+		}
+	}()
+}
+
+// 📈 STAGE METRICS
+
+type StageStats struct {
+	Name       string
+	Workers    int
+	In         int // Items read from the upstream stage
+	Out        int // Items (or batches) passed downstream
+	Failed     int
+	Elapsed    time.Duration // Until the stage finished, or so far
+	Throughput float64       // In per second of Elapsed
+	LatencyP50 time.Duration
+	LatencyP95 time.Duration // Over the last 256 items
+}
+
+type stageMetrics struct {
+	mu        sync.Mutex
+	stats     StageStats
+	started   time.Time
+	done      bool
+	latencies [256]time.Duration
+	latCount  int
+}
+
+func (p *Pipeline) addStage(name string, workers int) *stageMetrics {
+	m := &stageMetrics{stats: StageStats{Name: name, Workers: workers}, started: time.Now()}
+	p.mu.Lock()
+	p.stages = append(p.stages, m)
+	p.mu.Unlock()
+	return m
+}
+
+func (m *stageMetrics) received() { m.update(func(s *StageStats) { s.In++ }) }
+func (m *stageMetrics) emitted()  { m.update(func(s *StageStats) { s.Out++ }) }
+func (m *stageMetrics) failed()   { m.update(func(s *StageStats) { s.Failed++ }) }
+
+func (m *stageMetrics) update(f func(s *StageStats)) {
+	m.mu.Lock()
+	f(&m.stats)
+	m.mu.Unlock()
+}
+
+func (m *stageMetrics) observe(took time.Duration) {
+	m.mu.Lock()
+	m.latencies[m.latCount%len(m.latencies)] = took
+	m.latCount++
+	m.mu.Unlock()
+}
+
+func (m *stageMetrics) finish() {
+	m.mu.Lock()
+	m.stats.Elapsed = time.Since(m.started)
+	m.done = true
+	m.mu.Unlock()
+}
+
+func (m *stageMetrics) snapshot() StageStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.stats
+	if !m.done {
+		s.Elapsed = time.Since(m.started)
+	}
+	if s.Elapsed > 0 {
+		s.Throughput = float64(s.In) / s.Elapsed.Seconds()
+	}
+	n := min(m.latCount, len(m.latencies))
+	recent := make([]time.Duration, n)
+	copy(recent, m.latencies[:n])
+	sort.Slice(recent, func(i, j int) bool { return recent[i] < recent[j] })
+	s.LatencyP50 = percentile(recent, 50)
+	s.LatencyP95 = percentile(recent, 95)
+	return s
+}
+
+// Stats returns one entry per stage, in the order they were added
+func (p *Pipeline) Stats() []StageStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make([]StageStats, len(p.stages))
+	for i, m := range p.stages {
+		stats[i] = m.snapshot()
+	}
+	return stats
+}
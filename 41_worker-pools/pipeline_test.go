@@ -0,0 +1,250 @@
+/*
+=============================================================================
+                    🧪 PIPELINE TESTS
+=============================================================================
+
+Map, Filter, FlatMap and Batch chained end to end, ordering, fan-in,
+error and cancellation propagation, and per-stage metrics.
+Run with: go test -v -race -run Pipeline *.go
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// drainTo ends the pipeline by appending to got; read it after Wait
+func drainTo[T any](in Stream[T], got *[]T) {
+	ForEach(in, "collect", func(ctx context.Context, v T) error {
+		*got = append(*got, v)
+		return nil
+	})
+}
+
+// counting is an endless Source; it only stops when the pipeline does
+func counting(p *Pipeline, emitted *atomic.Int64) Stream[int] {
+	return Source(p, "count", func(ctx context.Context, emit func(int) error) error {
+		for i := 0; ; i++ {
+			if err := emit(i); err != nil {
+				return err
+			}
+			emitted.Add(1)
+		}
+	})
+}
+
+func TestPipelineStagesEndToEnd(t *testing.T) {
+	p := NewPipeline(context.Background())
+	nums := Values(p, "nums", 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	pairs := FlatMap(nums, "pair", StageOptions{Workers: 3}, func(ctx context.Context, n int) ([]int, error) {
+		return []int{n, -n}, nil
+	})
+	positive := Filter(pairs, "positive", StageOptions{Workers: 2}, func(ctx context.Context, n int) (bool, error) {
+		return n > 0, nil
+	})
+	squares := Map(positive, "square", StageOptions{Workers: 4, Buffer: 2}, func(ctx context.Context, n int) (int, error) {
+		return n * n, nil
+	})
+	var batches [][]int
+	drainTo(Batch(squares, "batch", BatchOptions{BatchSize: 4, MaxWait: time.Hour}), &batches)
+
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	for _, b := range batches {
+		if len(b) > 4 {
+			t.Errorf("batch of %d; BatchSize is 4", len(b))
+		}
+		got = append(got, b...)
+	}
+	slices.Sort(got)
+	if want := []int{1, 4, 9, 16, 25, 36, 49, 64, 81, 100}; !slices.Equal(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+
+	// In and Out per stage: 10 → 20 → 10 → 10 → 3 batches
+	flow := map[string][2]int{"nums": {10, 10}, "pair": {10, 20}, "positive": {20, 10}, "square": {10, 10}, "batch": {10, 3}, "collect": {3, 0}}
+	for _, s := range p.Stats() {
+		if want := flow[s.Name]; s.In != want[0] || s.Out != want[1] {
+			t.Errorf("%s: in %d, out %d; want %v", s.Name, s.In, s.Out, want)
+		}
+	}
+}
+
+func TestPipelineOrderedStage(t *testing.T) {
+	p := NewPipeline(context.Background())
+	words := Values(p, "words", "a", "bb", "ccc", "dddd", "eeeee", "ffffff")
+	upper := Map(words, "upper", StageOptions{Workers: 4, Ordered: true}, func(ctx context.Context, w string) (string, error) {
+		time.Sleep(time.Duration(10-len(w)) * time.Millisecond) // Later words finish first
+		return strings.ToUpper(w), nil
+	})
+	var got []string
+	drainTo(upper, &got)
+
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"A", "BB", "CCC", "DDDD", "EEEEE", "FFFFFF"}; !slices.Equal(got, want) {
+		t.Errorf("got %v; want %v", got, want)
+	}
+}
+
+func TestPipelineErrorStopsEveryStage(t *testing.T) {
+	errBad := errors.New("bad record")
+	var emitted atomic.Int64
+	p := NewPipeline(context.Background())
+	checked := Map(counting(p, &emitted), "check", StageOptions{Workers: 2}, func(ctx context.Context, n int) (int, error) {
+		if n == 50 {
+			return 0, errBad
+		}
+		return n, nil
+	})
+	var got []int
+	drainTo(checked, &got)
+
+	err := p.Wait() // Returning at all means the endless source was stopped
+	if !errors.Is(err, errBad) || !strings.HasPrefix(err.Error(), "stage check: ") {
+		t.Fatalf("Wait = %v; want the check stage's error", err)
+	}
+	if slices.Contains(got, 50) {
+		t.Error("the failed item was passed on")
+	}
+	for _, s := range p.Stats() {
+		if s.Name == "check" && s.Failed != 1 {
+			t.Errorf("check stage failed = %d; want 1", s.Failed)
+		}
+	}
+	t.Logf("source emitted %d items before it was stopped", emitted.Load())
+}
+
+func TestPipelineSinkErrorReachesSource(t *testing.T) {
+	var emitted atomic.Int64
+	p := NewPipeline(context.Background())
+	ForEach(counting(p, &emitted), "store", func(ctx context.Context, n int) error {
+		if n == 10 {
+			return errors.New("disk full")
+		}
+		return nil
+	})
+	if err := p.Wait(); err == nil || err.Error() != "stage store: disk full" {
+		t.Fatalf("Wait = %v", err)
+	}
+	// A handoff or two may already be under way when the sink fails
+	if n := emitted.Load(); n > 15 {
+		t.Errorf("source kept going after the sink failed: %d items", n)
+	}
+}
+
+func TestPipelineCancelledByParentContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var emitted atomic.Int64
+	p := NewPipeline(ctx)
+	slow := Map(counting(p, &emitted), "slow", StageOptions{Workers: 2}, func(ctx context.Context, n int) (int, error) {
+		select {
+		case <-time.After(time.Millisecond):
+			return n, nil
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	})
+	var got []int
+	drainTo(slow, &got)
+
+	time.AfterFunc(30*time.Millisecond, cancel)
+	if err := p.Wait(); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Wait = %v; want context.Canceled", err)
+	}
+	if len(got) == 0 {
+		t.Error("nothing got through before the cancel")
+	}
+}
+
+func TestPipelineStageRetries(t *testing.T) {
+	var calls atomic.Int64
+	p := NewPipeline(context.Background())
+	fetched := Map(Values(p, "ids", 1, 2, 3), "fetch", StageOptions{Workers: 1, Retry: fastRetry}, func(ctx context.Context, id int) (int, error) {
+		if calls.Add(1)%2 == 1 {
+			return 0, Retryable(errors.New("connection reset"))
+		}
+		return id, nil
+	})
+	var got []int
+	drainTo(fetched, &got)
+
+	if err := p.Wait(); err != nil {
+		t.Fatalf("Wait = %v; retries should have hidden the flaky errors", err)
+	}
+	if len(got) != 3 || calls.Load() != 6 {
+		t.Errorf("got %v after %d calls; want 3 items after 6", got, calls.Load())
+	}
+}
+
+func TestPipelineMergeFanIn(t *testing.T) {
+	p := NewPipeline(context.Background())
+	worker := func(name string, delay time.Duration) Stream[string] {
+		return Source(p, name, func(ctx context.Context, emit func(string) error) error {
+			time.Sleep(delay)
+			return emit(name + " finished")
+		})
+	}
+	merged := Merge("fan-in", worker("w1", 40*time.Millisecond), worker("w2", 10*time.Millisecond), worker("w3", 70*time.Millisecond))
+	var got []string
+	drainTo(merged, &got)
+
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"w2 finished", "w1 finished", "w3 finished"}; !slices.Equal(got, want) {
+		t.Errorf("got %v; want arrival order %v", got, want)
+	}
+}
+
+func TestPipelineMergeSingleStream(t *testing.T) {
+	p := NewPipeline(context.Background())
+	var got []int
+	drainTo(Merge("only", Values(p, "items", 1, 2, 3)), &got)
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []int{1, 2, 3}) {
+		t.Errorf("got %v; want the one stream passed through", got)
+	}
+}
+
+func TestPipelineStats(t *testing.T) {
+	p := NewPipeline(context.Background())
+	items := make([]int, 40)
+	slept := Map(Values(p, "items", items...), "sleep", StageOptions{Workers: 4}, func(ctx context.Context, n int) (int, error) {
+		time.Sleep(5 * time.Millisecond)
+		return n, nil
+	})
+	var got []int
+	drainTo(slept, &got)
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := p.Stats()
+	if len(stats) != 3 || stats[1].Name != "sleep" {
+		t.Fatalf("stats = %+v; want items, sleep, collect", stats)
+	}
+	s := stats[1]
+	if s.Workers != 4 || s.In != 40 || s.Out != 40 {
+		t.Errorf("sleep stage = %+v", s)
+	}
+	if s.LatencyP50 < 5*time.Millisecond || s.LatencyP95 < s.LatencyP50 {
+		t.Errorf("latency p50 %v, p95 %v; want at least the 5ms sleep", s.LatencyP50, s.LatencyP95)
+	}
+	// 4 workers × 5ms each: about 800 items/s, well above a single worker's 200
+	if s.Throughput < 300 || s.Elapsed <= 0 {
+		t.Errorf("throughput %.0f/s over %v", s.Throughput, s.Elapsed)
+	}
+}
@@ -0,0 +1,447 @@
+/*
+=============================================================================
+                    🏭 GENERIC WORKER POOL - WORKER POOLS EXTENSION
+=============================================================================
+
+A reusable pool for any input and output type. The work itself is a
+plain function, so the pool only deals with concurrency:
+
+  pool := NewWorkerPool(func(ctx context.Context, url string) (int, error) {
+      return fetchStatus(ctx, url)
+  }, PoolOptions{Workers: 8, Ordered: true})
+  pool.Start(ctx)
+
+  go func() {
+      for _, u := range urls {
+          pool.Submit(ctx, u)
+      }
+      pool.Shutdown(ctx) // No more jobs: drain the queue, then close Results
+  }()
+
+  for r := range pool.Results() {
+      fmt.Println(r.Seq, r.Input, r.Output, r.Err)
+  }
+
+• Each job's error (or recovered panic) comes back in its Result
+• Shutdown(ctx) stops intake and drains queued jobs; Abort() (or
+  cancelling the Start context) stops the workers and cancels the
+  context every running handler sees
+• Submit after either returns ErrPoolClosed instead of panicking
+• Ordered: true emits results in submission order; the default is
+  completion order, which never holds back a finished result
+• Jobs run most urgent first (see priority.go); Submit uses Medium
+• JobTimeout, Retry and dead-lettering are covered in retry.go and
+  deadletter.go
+*/
+
+package main
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned by Submit once Shutdown or Abort was called
+var ErrPoolClosed = errors.New("worker pool is closed")
+
+// 🧩 HANDLER AND RESULT
+type Handler[In, Out any] func(ctx context.Context, in In) (Out, error)
+
+type Result[In, Out any] struct {
+	Seq      int // Submission order, starting at 0
+	Priority Priority
+	Attempts int
+	Input    In
+	Output   Out
+	Err      error
+}
+
+// ⚙️ POOL OPTIONS: Workers, queue depth, ordering, scaling, priority, failures
+type PoolOptions struct {
+	Workers   int  // Default: runtime.NumCPU(), or MinWorkers when autoscaling
+	QueueSize int  // Buffered jobs before Submit blocks (default: 2 * max workers)
+	Ordered   bool // Emit results in submission order
+
+	// Autoscaling (see autoscale.go) is on when MaxWorkers > 0
+	MinWorkers    int           // Default: 1
+	MaxWorkers    int           // Upper bound for the worker count
+	ScaleInterval time.Duration // How often to look at Stats (default: 100ms)
+	TargetWait    time.Duration // Grow when queued jobs would wait longer (default: 500ms)
+	IdleTimeout   time.Duration // Shrink after workers sat idle this long (default: 2s)
+
+	// Priority scheduling (see priority.go)
+	Aging   time.Duration    // Queued this long = one level more urgent (0: off)
+	Reserve map[Priority]int // Workers kept free for jobs at or above a level
+
+	// Failure handling (see retry.go)
+	JobTimeout time.Duration // Deadline for each attempt (0: none)
+	Retry      RetryPolicy
+}
+
+// 🏭 WORKER POOL
+type WorkerPool[In, Out any] struct {
+	handler Handler[In, Out]
+	opts    PoolOptions
+
+	done     chan Result[In, Out] // Completion order, straight from workers
+	results  chan Result[In, Out] // What callers read
+	finished chan struct{}        // Closed once Results is closed
+
+	ctx       context.Context // Cancelled by Abort or the Start context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	startOnce sync.Once
+
+	mu           sync.Mutex // Guards everything below
+	queue        taskQueue[In]
+	nextSeq      int
+	closed       bool // Shutdown or Abort called: no more Submits
+	started      bool
+	target       int           // Worker count asked for by Resize
+	live         int           // Workers currently running
+	active       int           // Workers inside the handler
+	wake         chan struct{} // Closed and replaced whenever the queue or workers change
+	retrying     int           // Jobs waiting out a retry backoff
+	completed    int
+	failed       int
+	deadLettered int
+	deadLetters  DeadLetterStore[In]
+	latencies    [256]time.Duration // Ring of recent handler durations
+	latCount     int
+}
+
+func NewWorkerPool[In, Out any](handler Handler[In, Out], opts PoolOptions) *WorkerPool[In, Out] {
+	maxWorkers := opts.Workers
+	if opts.MaxWorkers > 0 {
+		opts = opts.withScaleDefaults()
+		maxWorkers = opts.MaxWorkers
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+		maxWorkers = opts.Workers
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 2 * maxWorkers
+	}
+	opts.Retry = opts.Retry.withDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WorkerPool[In, Out]{
+		handler:  handler,
+		opts:     opts,
+		done:     make(chan Result[In, Out], opts.QueueSize),
+		results:  make(chan Result[In, Out], opts.QueueSize),
+		finished: make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+		queue:    taskQueue[In]{aging: opts.Aging},
+		target:   opts.Workers,
+		wake:     make(chan struct{}),
+	}
+}
+
+// Start launches the workers; cancelling ctx stops them as Abort does
+func (p *WorkerPool[In, Out]) Start(ctx context.Context) {
+	p.startOnce.Do(func() {
+		context.AfterFunc(ctx, p.cancel)
+		p.mu.Lock()
+		p.started = true
+		for p.live < p.target {
+			p.spawnLocked()
+		}
+		p.mu.Unlock()
+		go func() {
+			p.wg.Wait()
+			close(p.done)
+		}()
+		if p.opts.Ordered {
+			go p.reorder()
+		} else {
+			go p.forward()
+		}
+		if p.opts.MaxWorkers > 0 {
+			go p.autoscale()
+		}
+	})
+}
+
+func (p *WorkerPool[In, Out]) spawnLocked() {
+	p.live++
+	p.wg.Add(1)
+	go p.worker()
+}
+
+func (p *WorkerPool[In, Out]) worker() {
+	retired := false
+	defer func() {
+		if !retired {
+			p.mu.Lock()
+			p.live--
+			p.mu.Unlock()
+		}
+		p.wg.Done() // After live--, so Resize never Adds to a finished group
+	}()
+	for {
+		// Check cancellation first so a full queue can't starve it
+		if p.ctx.Err() != nil {
+			return
+		}
+		p.mu.Lock()
+		if p.live > p.target {
+			p.live--
+			p.mu.Unlock()
+			retired = true
+			return
+		}
+		t, ok := p.queue.take(p.minPriorityLocked())
+		if !ok {
+			if p.closed && p.queue.Len() == 0 && p.retrying == 0 {
+				p.mu.Unlock()
+				return // Drained
+			}
+			wake := p.wake
+			p.mu.Unlock()
+			select {
+			case <-wake: // New job, finished job or resize: look again
+			case <-p.ctx.Done():
+				return
+			}
+			continue
+		}
+		p.active++
+		p.wakeLocked() // A queue slot opened up
+		p.mu.Unlock()
+
+		start := time.Now()
+		out, err := p.runAttempt(t.in)
+		if !p.record(t, time.Since(start), err) {
+			continue // Retry scheduled
+		}
+		if err != nil {
+			err = p.deadLetter(t, err)
+		}
+		select {
+		case p.done <- Result[In, Out]{Seq: t.seq, Priority: t.priority, Attempts: t.attempt, Input: t.in, Output: out, Err: err}:
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// wakeLocked wakes every waiting worker and Submit. The caller holds p.mu.
+func (p *WorkerPool[In, Out]) wakeLocked() {
+	close(p.wake)
+	p.wake = make(chan struct{})
+}
+
+// record books an attempt and schedules a retry if one is due. It
+// returns false when the job will run again.
+func (p *WorkerPool[In, Out]) record(t task[In], took time.Duration, err error) (final bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.active--
+	switch {
+	case p.ctx.Err() == nil && p.opts.Retry.shouldRetry(t.attempt, err):
+		p.retryLaterLocked(t)
+	case err != nil:
+		p.failed++
+		final = true
+	default:
+		p.completed++
+		final = true
+	}
+	p.latencies[p.latCount%len(p.latencies)] = took
+	p.latCount++
+	p.wakeLocked() // Reserved jobs may be able to start now
+	return final
+}
+
+// run calls the handler, turning a panic into that job's error
+func (p *WorkerPool[In, Out]) run(ctx context.Context, in In) (out Out, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return p.handler(ctx, in)
+}
+
+func (p *WorkerPool[In, Out]) forward() {
+	defer close(p.finished)
+	defer close(p.results)
+	for r := range p.done {
+		p.emit(r)
+	}
+}
+
+// emit hands r to the caller. Once the pool is aborted nobody may be
+// reading, so results are dropped rather than blocking forever.
+func (p *WorkerPool[In, Out]) emit(r Result[In, Out]) {
+	select {
+	case p.results <- r:
+	case <-p.ctx.Done():
+	}
+}
+
+// reorder holds results back until every earlier job has been emitted
+func (p *WorkerPool[In, Out]) reorder() {
+	defer close(p.finished)
+	defer close(p.results)
+	pending := make(map[int]Result[In, Out])
+	next := 0
+	for r := range p.done {
+		pending[r.Seq] = r
+		for {
+			ready, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			p.emit(ready)
+			next++
+		}
+	}
+}
+
+// Submit queues in at Medium priority, blocking while the queue is full.
+// It fails with ErrPoolClosed after Shutdown or Abort, or with ctx's error.
+func (p *WorkerPool[In, Out]) Submit(ctx context.Context, in In) error {
+	return p.SubmitPriority(ctx, in, Medium)
+}
+
+func (p *WorkerPool[In, Out]) SubmitPriority(ctx context.Context, in In, priority Priority) error {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return ErrPoolClosed
+		}
+		if err := p.ctx.Err(); err != nil {
+			p.mu.Unlock()
+			return err
+		}
+		if p.queue.Len() < p.opts.QueueSize {
+			heap.Push(&p.queue, task[In]{seq: p.nextSeq, in: in, priority: priority, queued: time.Now(), attempt: 1})
+			p.nextSeq++
+			p.wakeLocked()
+			p.mu.Unlock()
+			return nil
+		}
+		wake := p.wake
+		p.mu.Unlock()
+
+		select {
+		case <-wake: // Something changed; maybe a slot is free
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-p.ctx.Done():
+			return p.ctx.Err()
+		}
+	}
+}
+
+// Results delivers one Result per submitted job and is closed after
+// Shutdown once every job has finished. After Abort, results not yet
+// read are dropped and it closes soon after.
+func (p *WorkerPool[In, Out]) Results() <-chan Result[In, Out] {
+	return p.results
+}
+
+// Resize sets the worker count (at least 1). Extra workers retire once
+// their current job is done; new ones start right away.
+func (p *WorkerPool[In, Out]) Resize(n int) {
+	if n < 1 {
+		n = 1
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.target = n
+	// live == 0 means the pool has finished; its WaitGroup is done with
+	for p.started && p.live > 0 && p.live < p.target {
+		p.spawnLocked()
+	}
+	p.wakeLocked() // Surplus workers notice and retire
+}
+
+// 📈 POOL STATS
+type PoolStats struct {
+	Workers      int // Running, including ones about to retire
+	Active       int // Running a job
+	Idle         int // Waiting for a job
+	Queued       int
+	Retrying     int // Waiting out a backoff before the next attempt
+	Completed    int // Jobs that succeeded
+	Failed       int // Jobs that failed for good (error, panic or timeout)
+	DeadLettered int
+	LatencyP50   time.Duration
+	LatencyP95   time.Duration // Over the last 256 jobs
+}
+
+func (p *WorkerPool[In, Out]) Stats() PoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := min(p.latCount, len(p.latencies))
+	recent := make([]time.Duration, n)
+	copy(recent, p.latencies[:n])
+	sort.Slice(recent, func(i, j int) bool { return recent[i] < recent[j] })
+
+	return PoolStats{
+		Workers:      p.live,
+		Active:       p.active,
+		Idle:         p.live - p.active,
+		Queued:       p.queue.Len(),
+		Retrying:     p.retrying,
+		Completed:    p.completed,
+		Failed:       p.failed,
+		DeadLettered: p.deadLettered,
+		LatencyP50:   percentile(recent, 50),
+		LatencyP95:   percentile(recent, 95),
+	}
+}
+
+// percentile expects sorted input
+func percentile(sorted []time.Duration, pct int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[(len(sorted)-1)*pct/100]
+}
+
+// closeIntake makes Submit fail from now on; workers exit once the
+// queue is empty
+func (p *WorkerPool[In, Out]) closeIntake() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	p.wakeLocked()
+}
+
+// Shutdown stops intake and waits until every queued job has run and
+// Results is closed, so keep reading Results meanwhile. If ctx ends first
+// the pool is aborted and ctx's error returned. Safe to call repeatedly.
+func (p *WorkerPool[In, Out]) Shutdown(ctx context.Context) error {
+	p.Start(context.Background()) // Jobs queued before Start still drain
+	p.closeIntake()
+	select {
+	case <-p.finished:
+		return nil
+	case <-ctx.Done():
+		p.Abort()
+		return ctx.Err()
+	}
+}
+
+// Abort stops intake and cancels running handlers; queued jobs are
+// dropped, and so are results nobody has read yet. It does not wait for
+// handlers to return, but Results is closed once they have, whether or
+// not anyone drains it.
+func (p *WorkerPool[In, Out]) Abort() {
+	p.Start(context.Background())
+	p.closeIntake()
+	p.cancel()
+}
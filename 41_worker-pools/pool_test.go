@@ -0,0 +1,336 @@
+/*
+=============================================================================
+                    🧪 GENERIC WORKER POOL TESTS
+=============================================================================
+
+Checks ordering, per-job errors, panic recovery, cancellation and the
+Shutdown/Abort paths of the generic WorkerPool under concurrent load.
+Run with: go test -v -race -run Pool *.go
+*/
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// sleepHandler finishes later jobs first, so completion order differs
+// from submission order
+func sleepHandler(ctx context.Context, n int) (string, error) {
+	select {
+	case <-time.After(time.Duration(10-n) * 5 * time.Millisecond):
+		return fmt.Sprint(n * n), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func submitAll[In, Out any](t *testing.T, pool *WorkerPool[In, Out], inputs []In) {
+	t.Helper()
+	go func() {
+		for _, in := range inputs {
+			if err := pool.Submit(context.Background(), in); err != nil {
+				t.Errorf("Submit(%v): %v", in, err)
+			}
+		}
+		pool.Shutdown(context.Background())
+	}()
+}
+
+func collect[In, Out any](pool *WorkerPool[In, Out]) []Result[In, Out] {
+	var out []Result[In, Out]
+	for r := range pool.Results() {
+		out = append(out, r)
+	}
+	return out
+}
+
+func TestPoolOrderedKeepsSubmissionOrder(t *testing.T) {
+	pool := NewWorkerPool(sleepHandler, PoolOptions{Workers: 10, Ordered: true})
+	pool.Start(context.Background())
+	submitAll(t, pool, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+
+	results := collect(pool)
+	if len(results) != 10 {
+		t.Fatalf("got %d results; want 10", len(results))
+	}
+	for i, r := range results {
+		if r.Seq != i || r.Input != i || r.Output != fmt.Sprint(i*i) || r.Err != nil {
+			t.Errorf("results[%d] = %+v", i, r)
+		}
+	}
+}
+
+func TestPoolUnorderedIsCompletionOrder(t *testing.T) {
+	pool := NewWorkerPool(sleepHandler, PoolOptions{Workers: 10})
+	pool.Start(context.Background())
+	submitAll(t, pool, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9})
+
+	results := collect(pool)
+	if len(results) != 10 {
+		t.Fatalf("got %d results; want 10", len(results))
+	}
+	// Job 9 sleeps least and job 0 most
+	if results[0].Input != 9 || results[9].Input != 0 {
+		t.Errorf("first = %d, last = %d; want 9 then 0", results[0].Input, results[9].Input)
+	}
+}
+
+func TestPoolPropagatesErrorsAndPanics(t *testing.T) {
+	errOdd := errors.New("odd input")
+	pool := NewWorkerPool(func(ctx context.Context, n int) (int, error) {
+		switch {
+		case n == 3:
+			panic("three is cursed")
+		case n%2 == 1:
+			return 0, fmt.Errorf("job %d: %w", n, errOdd)
+		}
+		return n * 10, nil
+	}, PoolOptions{Workers: 3, Ordered: true})
+	pool.Start(context.Background())
+	submitAll(t, pool, []int{0, 1, 2, 3, 4})
+
+	results := collect(pool)
+	if len(results) != 5 {
+		t.Fatalf("got %d results; want 5", len(results))
+	}
+	if results[0].Output != 0 || results[2].Output != 20 || results[4].Output != 40 {
+		t.Errorf("outputs = %+v", results)
+	}
+	if !errors.Is(results[1].Err, errOdd) {
+		t.Errorf("job 1 err = %v; want errOdd", results[1].Err)
+	}
+	if results[3].Err == nil || results[3].Err.Error() != "job panicked: three is cursed" {
+		t.Errorf("job 3 err = %v; want the recovered panic", results[3].Err)
+	}
+}
+
+func TestPoolCancelStopsWorkers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{}, 4)
+	pool := NewWorkerPool(func(ctx context.Context, n int) (int, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}, PoolOptions{Workers: 2, QueueSize: 4})
+	pool.Start(ctx)
+
+	for i := 0; i < 4; i++ {
+		if err := pool.Submit(context.Background(), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-started
+	<-started
+	cancel()
+
+	// Results closes without waiting for the queued jobs
+	done := make(chan struct{})
+	go func() {
+		for range pool.Results() {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Results not closed after cancel")
+	}
+
+	if err := pool.Submit(context.Background(), 99); !errors.Is(err, context.Canceled) {
+		t.Errorf("Submit after cancel = %v; want context.Canceled", err)
+	}
+}
+
+func TestPoolSubmitHonoursCallerContext(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	pool := NewWorkerPool(func(ctx context.Context, n int) (int, error) {
+		<-block
+		return n, nil
+	}, PoolOptions{Workers: 1, QueueSize: 1})
+	pool.Start(context.Background())
+
+	// One job running, one queued: the third Submit has to wait
+	pool.Submit(context.Background(), 1)
+	pool.Submit(context.Background(), 2)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := pool.Submit(ctx, 3); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Submit on full queue = %v; want DeadlineExceeded", err)
+	}
+}
+
+func TestPoolShutdownDrainsUnderLoad(t *testing.T) {
+	pool := NewWorkerPool(func(ctx context.Context, n int) (int, error) {
+		time.Sleep(time.Millisecond)
+		return n, nil
+	}, PoolOptions{Workers: 4, QueueSize: 8})
+	pool.Start(context.Background())
+
+	// Readers count results while submitters race against Shutdown
+	received := make(chan int)
+	go func() {
+		n := 0
+		for range pool.Results() {
+			n++
+		}
+		received <- n
+	}()
+
+	var accepted, rejected atomic.Int64
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				switch err := pool.Submit(context.Background(), i); {
+				case err == nil:
+					accepted.Add(1)
+				case errors.Is(err, ErrPoolClosed):
+					rejected.Add(1)
+				default:
+					t.Errorf("Submit: %v", err)
+				}
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	wg.Wait()
+
+	// Every accepted job produced a result; nothing was dropped
+	if got := <-received; int64(got) != accepted.Load() {
+		t.Errorf("received %d results for %d accepted jobs", got, accepted.Load())
+	}
+	if accepted.Load()+rejected.Load() != 16*50 || rejected.Load() == 0 {
+		t.Errorf("accepted %d, rejected %d", accepted.Load(), rejected.Load())
+	}
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Errorf("second Shutdown: %v", err)
+	}
+}
+
+func TestPoolAbortCancelsInFlight(t *testing.T) {
+	var cancelled atomic.Int64
+	started := make(chan struct{}, 100)
+	pool := NewWorkerPool(func(ctx context.Context, n int) (int, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		cancelled.Add(1)
+		return 0, ctx.Err()
+	}, PoolOptions{Workers: 3, QueueSize: 50})
+	pool.Start(context.Background())
+
+	// Three jobs running and a full queue, so every later Submit blocks
+	for i := 0; i < 3; i++ {
+		pool.Submit(context.Background(), i)
+	}
+	for i := 0; i < 3; i++ {
+		<-started
+	}
+	for i := 3; i < 53; i++ {
+		pool.Submit(context.Background(), i)
+	}
+
+	// Concurrent Abort, Shutdown and Submit must not panic
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(3)
+		go func() { defer wg.Done(); pool.Abort() }()
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+			defer cancel()
+			pool.Shutdown(ctx)
+		}()
+		go func() {
+			defer wg.Done()
+			if err := pool.Submit(context.Background(), -1); err == nil {
+				t.Error("Submit during Abort was accepted")
+			}
+		}()
+	}
+	wg.Wait()
+
+	results := collect(pool)
+	if cancelled.Load() != 3 {
+		t.Errorf("%d handlers saw cancellation; want the 3 in flight", cancelled.Load())
+	}
+	for _, r := range results {
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("result %+v; want context.Canceled", r)
+		}
+	}
+	if err := pool.Submit(context.Background(), 1); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Submit after Abort = %v; want ErrPoolClosed", err)
+	}
+}
+
+func TestPoolAbortWithoutReadingResults(t *testing.T) {
+	for _, ordered := range []bool{false, true} {
+		t.Run(fmt.Sprint("ordered=", ordered), func(t *testing.T) {
+			pool := NewWorkerPool(func(ctx context.Context, n int) (int, error) {
+				return n, nil
+			}, PoolOptions{Workers: 2, QueueSize: 1, Ordered: ordered})
+			pool.Start(context.Background())
+			for i := 0; i < 4; i++ {
+				pool.Submit(context.Background(), i)
+			}
+			waitFor(t, "Results to fill up", func() bool { return len(pool.Results()) == cap(pool.Results()) })
+
+			pool.Abort() // Nobody ever reads Results
+			select {
+			case <-pool.finished:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Results never closed: forwarding is stuck on a reader that left")
+			}
+		})
+	}
+}
+
+func TestPoolShutdownTimeoutAborts(t *testing.T) {
+	pool := NewWorkerPool(func(ctx context.Context, n int) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}, PoolOptions{Workers: 1})
+	pool.Start(context.Background())
+	pool.Submit(context.Background(), 1)
+	go collect(pool)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := pool.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown = %v; want DeadlineExceeded", err)
+	}
+	// The abort lets the stuck handler return, so a later drain completes
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown after abort: %v", err)
+	}
+}
+
+func TestPoolShutdownBeforeStartDrainsQueue(t *testing.T) {
+	pool := NewWorkerPool(func(ctx context.Context, n int) (int, error) {
+		return n * 2, nil
+	}, PoolOptions{Workers: 2, QueueSize: 3, Ordered: true})
+	for i := 1; i <= 3; i++ {
+		pool.Submit(context.Background(), i)
+	}
+	go pool.Shutdown(context.Background())
+
+	results := collect(pool)
+	if len(results) != 3 || results[2].Output != 6 {
+		t.Errorf("results = %+v", results)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+=============================================================================
+                    🚦 PRIORITY SCHEDULING - WORKER POOLS EXTENSION
+=============================================================================
+
+Queued jobs wait in a heap, so workers always take the most urgent one:
+
+  pool := NewWorkerPool(handler, PoolOptions{
+      Workers: 8,
+      Aging:   2 * time.Second,            // Waiting 2s = one level up
+      Reserve: map[Priority]int{Critical: 1}, // Keep a worker for Critical
+  })
+  pool.SubmitPriority(ctx, report, Low)
+  pool.SubmitPriority(ctx, outage, Critical)
+  pool.Submit(ctx, email) // Medium
+
+• Same priority: first in, first out
+• Aging: a job gains one level per Aging spent queued, so a steady
+  stream of High work can't starve Low work forever
+• Reserve[p] = n: jobs below p may only start while n workers stay free
+  for p and above (at least one worker always serves every level)
+
+Priority mirrors the enum in 16_enums; directories here can't import
+each other.
+*/
+
+package main
+
+import (
+	"container/heap"
+	"time"
+)
+
+// 🎯 PRIORITY LEVELS (same as 16_enums)
+type Priority int
+
+const (
+	Low Priority = iota
+	Medium
+	High
+	Critical
+)
+
+func (p Priority) String() string {
+	switch p {
+	case Low:
+		return "Low Priority"
+	case Medium:
+		return "Medium Priority"
+	case High:
+		return "High Priority"
+	case Critical:
+		return "Critical Priority"
+	default:
+		return "Unknown Priority"
+	}
+}
+
+// 📦 QUEUED TASK
+type task[In any] struct {
+	seq      int
+	in       In
+	priority Priority
+	queued   time.Time
+	attempt  int // 1 on the first run
+}
+
+// 🏔️ TASK HEAP: container/heap ordering by effective priority
+type taskQueue[In any] struct {
+	tasks []task[In]
+	aging time.Duration
+}
+
+func (q *taskQueue[In]) Len() int      { return len(q.tasks) }
+func (q *taskQueue[In]) Swap(i, j int) { q.tasks[i], q.tasks[j] = q.tasks[j], q.tasks[i] }
+func (q *taskQueue[In]) Push(x any)    { q.tasks = append(q.tasks, x.(task[In])) }
+
+func (q *taskQueue[In]) Pop() any {
+	last := q.tasks[len(q.tasks)-1]
+	q.tasks = q.tasks[:len(q.tasks)-1]
+	return last
+}
+
+// Less puts the more urgent task first. Every queued task ages at the same
+// rate, so with aging the order is fixed at push time: a task counts as if
+// it had been queued Aging earlier per priority level.
+func (q *taskQueue[In]) Less(i, j int) bool {
+	a, b := q.tasks[i], q.tasks[j]
+	if q.aging > 0 {
+		ka := a.queued.Add(-time.Duration(a.priority) * q.aging)
+		kb := b.queued.Add(-time.Duration(b.priority) * q.aging)
+		if !ka.Equal(kb) {
+			return ka.Before(kb)
+		}
+	} else if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	return a.seq < b.seq
+}
+
+// take removes the most urgent task with at least minPriority
+func (q *taskQueue[In]) take(minPriority Priority) (task[In], bool) {
+	if len(q.tasks) == 0 {
+		return task[In]{}, false
+	}
+	if q.tasks[0].priority >= minPriority {
+		return heap.Pop(q).(task[In]), true
+	}
+	// An aged low-priority task is on top but may not run yet
+	best := -1
+	for i, t := range q.tasks {
+		if t.priority >= minPriority && (best < 0 || q.Less(i, best)) {
+			best = i
+		}
+	}
+	if best < 0 {
+		return task[In]{}, false
+	}
+	return heap.Remove(q, best).(task[In]), true
+}
+
+// minPriorityLocked is the lowest level an idle worker may start now
+// without eating into Reserve. The caller holds p.mu.
+func (p *WorkerPool[In, Out]) minPriorityLocked() Priority {
+	free := p.live - p.active - 1 // Free workers once this one is busy
+	reserved := 0
+	for level := Critical; level > Low; level-- {
+		reserved += p.opts.Reserve[level]
+		if min(reserved, p.live-1) > free {
+			return level
+		}
+	}
+	return Low
+}
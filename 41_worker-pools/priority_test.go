@@ -0,0 +1,164 @@
+/*
+=============================================================================
+                    🧪 PRIORITY SCHEDULING TESTS
+=============================================================================
+
+Checks heap order, aging on a fixed timeline and worker reservations.
+Run with: go test -v -race -run Priority *.go
+*/
+
+package main
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPriorityOrder(t *testing.T) {
+	gate := make(chan struct{})
+	var mu sync.Mutex
+	var order []string
+	pool := NewWorkerPool(func(ctx context.Context, name string) (string, error) {
+		if name == "gate" {
+			<-gate
+		}
+		mu.Lock()
+		order = append(order, name)
+		mu.Unlock()
+		return name, nil
+	}, PoolOptions{Workers: 1, QueueSize: 10})
+	pool.Start(context.Background())
+	go collect(pool)
+
+	// Hold the only worker so the rest pile up in the queue
+	pool.Submit(context.Background(), "gate")
+	waitFor(t, "gate running", func() bool { return pool.Stats().Active == 1 })
+
+	pool.SubmitPriority(context.Background(), "low", Low)
+	pool.Submit(context.Background(), "medium-1")
+	pool.SubmitPriority(context.Background(), "critical", Critical)
+	pool.Submit(context.Background(), "medium-2")
+	pool.SubmitPriority(context.Background(), "high", High)
+	close(gate)
+	pool.Shutdown(context.Background())
+
+	want := []string{"gate", "critical", "high", "medium-1", "medium-2", "low"}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v; want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v; want %v", order, want)
+		}
+	}
+}
+
+func TestPriorityAgingPreventsStarvation(t *testing.T) {
+	q := &taskQueue[string]{aging: time.Second}
+	t0 := time.Unix(0, 0)
+	push := func(name string, p Priority, at time.Duration) {
+		heap.Push(q, task[string]{seq: q.Len(), in: name, priority: p, queued: t0.Add(at)})
+	}
+
+	// High is two levels up, worth 2s of waiting: arriving 1.5s after
+	// Low it still goes first...
+	push("low", Low, 0)
+	push("high", High, 1500*time.Millisecond)
+	// ...but 3.5s after, Low has aged past it
+	push("high-late", High, 3500*time.Millisecond)
+	push("critical", Critical, 2*time.Second)
+
+	var got []string
+	for q.Len() > 0 {
+		got = append(got, heap.Pop(q).(task[string]).in)
+	}
+	want := []string{"critical", "high", "low", "high-late"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("order = %v; want %v", got, want)
+		}
+	}
+
+	// Without aging only the level matters
+	q = &taskQueue[string]{}
+	push("low", Low, 0)
+	push("high", High, time.Hour)
+	if first := heap.Pop(q).(task[string]).in; first != "high" {
+		t.Errorf("no aging: first = %s; want high", first)
+	}
+}
+
+func TestPriorityTakeSkipsReservedLevels(t *testing.T) {
+	q := &taskQueue[string]{aging: time.Second}
+	t0 := time.Unix(0, 0)
+	heap.Push(q, task[string]{seq: 0, in: "old-low", priority: Low, queued: t0})
+	heap.Push(q, task[string]{seq: 1, in: "critical", priority: Critical, queued: t0.Add(time.Hour)})
+
+	// The aged Low is on top, but only Critical may start
+	if got, ok := q.take(Critical); !ok || got.in != "critical" {
+		t.Errorf("take(Critical) = %v, %v; want critical", got.in, ok)
+	}
+	if _, ok := q.take(Critical); ok {
+		t.Error("take(Critical) returned a Low task")
+	}
+	if got, ok := q.take(Low); !ok || got.in != "old-low" {
+		t.Errorf("take(Low) = %v, %v", got.in, ok)
+	}
+}
+
+func TestPriorityReserveKeepsWorkerForCritical(t *testing.T) {
+	release := make(chan struct{})
+	criticalRan := make(chan struct{})
+	pool := NewWorkerPool(func(ctx context.Context, p Priority) (Priority, error) {
+		if p == Critical {
+			close(criticalRan)
+			return p, nil
+		}
+		<-release
+		return p, nil
+	}, PoolOptions{Workers: 3, QueueSize: 20, Reserve: map[Priority]int{Critical: 1}})
+	pool.Start(context.Background())
+	go collect(pool)
+	defer pool.Abort()
+	defer close(release)
+
+	for i := 0; i < 10; i++ {
+		pool.SubmitPriority(context.Background(), Low, Low)
+	}
+	waitFor(t, "two Low jobs running", func() bool {
+		s := pool.Stats()
+		return s.Active == 2 && s.Queued == 8
+	})
+	// The third worker stays free for Critical work
+	time.Sleep(20 * time.Millisecond)
+	if s := pool.Stats(); s.Active != 2 {
+		t.Fatalf("%d workers busy with Low jobs; one should stay reserved", s.Active)
+	}
+
+	pool.SubmitPriority(context.Background(), Critical, Critical)
+	select {
+	case <-criticalRan:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Critical job did not get the reserved worker")
+	}
+}
+
+func TestPriorityReserveNeverBlocksEverything(t *testing.T) {
+	// One worker and a reservation for it: Low jobs must still run
+	pool := NewWorkerPool(func(ctx context.Context, n int) (int, error) {
+		return n, nil
+	}, PoolOptions{Workers: 1, Reserve: map[Priority]int{Critical: 1, High: 2}})
+	pool.Start(context.Background())
+	go func() {
+		pool.SubmitPriority(context.Background(), 1, Low)
+		pool.Shutdown(context.Background())
+	}()
+
+	results := collect(pool)
+	if len(results) != 1 || results[0].Priority != Low {
+		t.Errorf("results = %+v", results)
+	}
+}
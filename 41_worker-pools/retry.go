@@ -0,0 +1,165 @@
+/*
+=============================================================================
+                    ⏱️ TIMEOUTS AND RETRIES - WORKER POOLS EXTENSION
+=============================================================================
+
+Per-job deadlines and retry with backoff:
+
+  pool := NewWorkerPool(handler, PoolOptions{
+      JobTimeout: 2 * time.Second,
+      Retry: RetryPolicy{
+          MaxAttempts: 4,
+          BaseDelay:   100 * time.Millisecond,
+          MaxDelay:    2 * time.Second,
+      },
+  })
+
+  // In the handler: mark failures worth another try
+  return "", Retryable(err)
+
+• JobTimeout cancels the handler's context. A handler that ignores it is
+  left running in the background and its worker moves on, so one hung
+  job can't hold a worker forever
+• Retryable errors are found with errors.As: anything in the chain with
+  Temporary() bool returning true (Retryable, or e.g. an APIError from
+  27_http-client), plus ErrJobTimeout
+• A retry waits off-worker with full jitter, then goes back into the
+  queue with its original priority and Seq
+• Only the final attempt produces a Result; jobs that still fail go to
+  the dead-letter store if one is set (see deadletter.go)
+*/
+
+package main
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ErrJobTimeout wraps the error of a job that ran past JobTimeout
+var ErrJobTimeout = errors.New("job timed out")
+
+// ⚙️ RETRY POLICY: How many times a failed job runs again, and how soon
+type RetryPolicy struct {
+	MaxAttempts int // Total tries including the first (default 1: no retries)
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+
+	// ShouldRetry overrides the default classification when set
+	ShouldRetry func(err error) bool
+}
+
+func (r RetryPolicy) withDefaults() RetryPolicy {
+	if r.MaxAttempts <= 0 {
+		r.MaxAttempts = 1
+	}
+	if r.BaseDelay <= 0 {
+		r.BaseDelay = 100 * time.Millisecond
+	}
+	if r.MaxDelay <= 0 {
+		r.MaxDelay = 5 * time.Second
+	}
+	if r.MaxDelay < r.BaseDelay {
+		r.MaxDelay = r.BaseDelay
+	}
+	return r
+}
+
+// 🏷️ RETRYABLE ERRORS
+type RetryableError struct {
+	Err error
+}
+
+func (e *RetryableError) Error() string   { return e.Err.Error() }
+func (e *RetryableError) Unwrap() error   { return e.Err }
+func (e *RetryableError) Temporary() bool { return true }
+
+// Retryable marks err as worth retrying (nil stays nil)
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &RetryableError{Err: err}
+}
+
+// IsRetryable is the default classification
+func IsRetryable(err error) bool {
+	var temp interface{ Temporary() bool }
+	if errors.As(err, &temp) && temp.Temporary() {
+		return true
+	}
+	return errors.Is(err, ErrJobTimeout)
+}
+
+func (r RetryPolicy) shouldRetry(attempt int, err error) bool {
+	if err == nil || attempt >= r.MaxAttempts {
+		return false
+	}
+	if r.ShouldRetry != nil {
+		return r.ShouldRetry(err)
+	}
+	return IsRetryable(err)
+}
+
+// backoff returns the pause after the given (1-based) failed attempt,
+// with full jitter: random(0, min(max, base*2^n))
+func (r RetryPolicy) backoff(attempt int) time.Duration {
+	exp := time.Duration(float64(r.BaseDelay) * math.Pow(2, float64(attempt-1)))
+	if exp <= 0 || exp > r.MaxDelay {
+		exp = r.MaxDelay // Also guards against overflow
+	}
+	return time.Duration(rand.Int63n(int64(exp) + 1))
+}
+
+// runAttempt calls the handler under JobTimeout, if one is set
+func (p *WorkerPool[In, Out]) runAttempt(in In) (Out, error) {
+	if p.opts.JobTimeout <= 0 {
+		return p.run(p.ctx, in)
+	}
+	ctx, cancel := context.WithTimeout(p.ctx, p.opts.JobTimeout)
+	defer cancel()
+
+	type outcome struct {
+		out Out
+		err error
+	}
+	finished := make(chan outcome, 1) // Buffered: an abandoned handler mustn't block
+	go func() {
+		out, err := p.run(ctx, in)
+		finished <- outcome{out, err}
+	}()
+
+	select {
+	case o := <-finished:
+		if o.err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && p.ctx.Err() == nil {
+			return o.out, fmt.Errorf("%w after %v: %w", ErrJobTimeout, p.opts.JobTimeout, o.err)
+		}
+		return o.out, o.err
+	case <-ctx.Done():
+		var zero Out
+		if p.ctx.Err() != nil {
+			return zero, p.ctx.Err() // Aborted, not timed out
+		}
+		return zero, fmt.Errorf("%w after %v", ErrJobTimeout, p.opts.JobTimeout)
+	}
+}
+
+// retryLaterLocked puts t back in the queue after its backoff. The caller
+// holds p.mu.
+func (p *WorkerPool[In, Out]) retryLaterLocked(t task[In]) {
+	p.retrying++
+	time.AfterFunc(p.opts.Retry.backoff(t.attempt), func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.retrying--
+		t.attempt++
+		t.queued = time.Now()
+		heap.Push(&p.queue, t) // Already admitted once, so QueueSize doesn't apply
+		p.wakeLocked()
+	})
+}
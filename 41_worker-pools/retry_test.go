@@ -0,0 +1,346 @@
+/*
+=============================================================================
+                    🧪 TIMEOUT, RETRY AND DEAD-LETTER TESTS
+=============================================================================
+
+Flaky, hung and broken handlers against JobTimeout, RetryPolicy and the
+memory and file dead-letter stores, including a re-drive in a new pool.
+Run with: go test -v -race -run 'Retry|Timeout|DeadLetter|Redrive' *.go
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var fastRetry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+// runJobs submits inputs, shuts down and returns the results by input
+func runJobs[In comparable, Out any](t *testing.T, pool *WorkerPool[In, Out], inputs ...In) map[In]Result[In, Out] {
+	t.Helper()
+	pool.Start(context.Background())
+	submitAll(t, pool, inputs)
+	byInput := make(map[In]Result[In, Out])
+	for _, r := range collect(pool) {
+		byInput[r.Input] = r
+	}
+	if len(byInput) != len(inputs) {
+		t.Fatalf("got %d results for %d jobs", len(byInput), len(inputs))
+	}
+	return byInput
+}
+
+// upstreamError looks like the APIError in 27_http-client
+type upstreamError struct{ status int }
+
+func (e *upstreamError) Error() string   { return fmt.Sprintf("upstream returned %d", e.status) }
+func (e *upstreamError) Temporary() bool { return e.status >= 500 }
+
+func TestRetryRetryableErrors(t *testing.T) {
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	pool := NewWorkerPool(func(ctx context.Context, name string) (string, error) {
+		mu.Lock()
+		calls[name]++
+		n := calls[name]
+		mu.Unlock()
+		switch {
+		case name == "flaky" && n < 3:
+			return "", Retryable(errors.New("connection reset"))
+		case name == "503" && n < 2:
+			return "", fmt.Errorf("fetch: %w", &upstreamError{status: 503})
+		case name == "404":
+			return "", &upstreamError{status: 404}
+		case name == "down":
+			return "", Retryable(errors.New("still down"))
+		}
+		return "ok", nil
+	}, PoolOptions{Workers: 2, Retry: fastRetry})
+
+	results := runJobs(t, pool, "flaky", "503", "404", "down", "fine")
+
+	tests := []struct {
+		name     string
+		attempts int
+		ok       bool
+	}{
+		{"flaky", 3, true},
+		{"503", 2, true}, // Found through the %w wrapping
+		{"404", 1, false},
+		{"down", 3, false}, // Out of attempts
+		{"fine", 1, true},
+	}
+	for _, tt := range tests {
+		r := results[tt.name]
+		if r.Attempts != tt.attempts || (r.Err == nil) != tt.ok {
+			t.Errorf("%s: attempts = %d, err = %v; want %d attempts, ok = %v", tt.name, r.Attempts, r.Err, tt.attempts, tt.ok)
+		}
+	}
+	if s := pool.Stats(); s.Completed != 3 || s.Failed != 2 || s.Retrying != 0 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestRetryCustomClassifier(t *testing.T) {
+	var calls atomic.Int64
+	errBusy := errors.New("busy")
+	pool := NewWorkerPool(func(ctx context.Context, n int) (int, error) {
+		if calls.Add(1) < 3 {
+			return 0, errBusy
+		}
+		return n, nil
+	}, PoolOptions{Workers: 1, Retry: RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   time.Millisecond,
+		ShouldRetry: func(err error) bool { return errors.Is(err, errBusy) },
+	}})
+
+	if r := runJobs(t, pool, 7)[7]; r.Err != nil || r.Attempts != 3 {
+		t.Errorf("result = %+v; want success on attempt 3", r)
+	}
+}
+
+func TestTimeoutFreesWorkerFromHungJob(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+	pool := NewWorkerPool(func(ctx context.Context, name string) (string, error) {
+		if name == "hung" {
+			<-hang // Ignores ctx on purpose
+		}
+		return name, nil
+	}, PoolOptions{Workers: 1, JobTimeout: 20 * time.Millisecond})
+
+	start := time.Now()
+	results := runJobs(t, pool, "hung", "next")
+	if !errors.Is(results["hung"].Err, ErrJobTimeout) {
+		t.Errorf("hung err = %v; want ErrJobTimeout", results["hung"].Err)
+	}
+	if results["next"].Err != nil {
+		t.Errorf("next err = %v", results["next"].Err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("took %v; the single worker should move on after the timeout", elapsed)
+	}
+}
+
+func TestTimeoutIsRetried(t *testing.T) {
+	var calls atomic.Int64
+	pool := NewWorkerPool(func(ctx context.Context, n int) (int, error) {
+		if calls.Add(1) == 1 {
+			<-ctx.Done() // First attempt is slow
+			return 0, ctx.Err()
+		}
+		return n, nil
+	}, PoolOptions{Workers: 1, JobTimeout: 20 * time.Millisecond, Retry: fastRetry})
+
+	if r := runJobs(t, pool, 1)[1]; r.Err != nil || r.Attempts != 2 {
+		t.Errorf("result = %+v; want success on attempt 2", r)
+	}
+}
+
+func TestDeadLetterMemoryStore(t *testing.T) {
+	dlq := &MemoryDeadLetters[string]{}
+	pool := NewWorkerPool(func(ctx context.Context, name string) (string, error) {
+		if strings.HasPrefix(name, "bad") {
+			return "", Retryable(errors.New("broken " + name))
+		}
+		return name, nil
+	}, PoolOptions{Workers: 2, Retry: fastRetry})
+	pool.SetDeadLetters(dlq)
+	pool.Start(context.Background())
+	go func() {
+		pool.Submit(context.Background(), "good")
+		pool.SubmitPriority(context.Background(), "bad-1", High)
+		pool.Submit(context.Background(), "bad-2")
+		pool.Shutdown(context.Background())
+	}()
+	collect(pool)
+
+	if dlq.Len() != 2 || pool.Stats().DeadLettered != 2 {
+		t.Fatalf("%d dead letters (stats: %+v); want 2", dlq.Len(), pool.Stats())
+	}
+	letters, _ := dlq.Peek()
+	for _, l := range letters {
+		if l.Attempts != 3 || l.Err == nil || l.FailedAt.IsZero() {
+			t.Errorf("letter = %+v", l)
+		}
+		if l.Input == "bad-1" && l.Priority != High {
+			t.Errorf("bad-1 priority = %v; want High", l.Priority)
+		}
+	}
+	if dlq.Len() != 2 {
+		t.Error("Peek should leave the jobs parked")
+	}
+	dlq.Remove(1)
+	if rest, _ := dlq.Peek(); len(rest) != 1 || rest[0].Input != letters[1].Input {
+		t.Errorf("after Remove(1): %+v; want only the newer letter", rest)
+	}
+}
+
+func TestDeadLetterNotUsedOnAbort(t *testing.T) {
+	dlq := &MemoryDeadLetters[int]{}
+	started := make(chan struct{})
+	pool := NewWorkerPool(func(ctx context.Context, n int) (int, error) {
+		close(started)
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}, PoolOptions{Workers: 1})
+	pool.SetDeadLetters(dlq)
+	pool.Start(context.Background())
+	pool.Submit(context.Background(), 1)
+	<-started
+	pool.Abort()
+	collect(pool)
+
+	if dlq.Len() != 0 {
+		t.Errorf("aborted job was dead-lettered")
+	}
+}
+
+func TestRedriveFromFileInNewPool(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	var downstreamUp atomic.Bool
+	handler := func(ctx context.Context, job Job) (string, error) {
+		if !downstreamUp.Load() {
+			return "", Retryable(fmt.Errorf("job %d: downstream unavailable", job.ID))
+		}
+		return job.Data, nil
+	}
+
+	// First run: the downstream is down and every job ends up parked
+	first := NewWorkerPool(handler, PoolOptions{Workers: 2, Retry: fastRetry})
+	first.SetDeadLetters(NewFileDeadLetters[Job](path))
+	results := runJobs(t, first, Job{1, "a"}, Job{2, "b"}, Job{3, "c"})
+	for _, r := range results {
+		if r.Err == nil {
+			t.Fatalf("job %d succeeded while down", r.Input.ID)
+		}
+	}
+	data, _ := os.ReadFile(path)
+	if lines := strings.Count(string(data), "\n"); lines != 3 || !strings.Contains(string(data), "downstream unavailable") {
+		t.Fatalf("dead-letter file:\n%s", data)
+	}
+
+	// Later, a new process re-drives them
+	downstreamUp.Store(true)
+	second := NewWorkerPool(handler, PoolOptions{Workers: 2, Ordered: true})
+	second.SetDeadLetters(NewFileDeadLetters[Job](path))
+	second.Start(context.Background())
+	n, err := second.Redrive(context.Background())
+	if err != nil || n != 3 {
+		t.Fatalf("Redrive = %d, %v; want 3", n, err)
+	}
+	go second.Shutdown(context.Background())
+	for _, r := range collect(second) {
+		if r.Err != nil || r.Output != r.Input.Data {
+			t.Errorf("redriven result = %+v", r)
+		}
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != 0 {
+		t.Errorf("dead-letter file not emptied after redrive: %v", err)
+	}
+}
+
+func TestRedriveFailurePutsJobsBack(t *testing.T) {
+	dlq := &MemoryDeadLetters[int]{}
+	for i := 0; i < 3; i++ {
+		dlq.Add(DeadLetter[int]{Input: i, Err: errors.New("old failure")})
+	}
+	pool := NewWorkerPool(func(ctx context.Context, n int) (int, error) { return n, nil }, PoolOptions{Workers: 1})
+	pool.SetDeadLetters(dlq)
+	pool.Abort() // A closed pool can't take them
+
+	if n, err := pool.Redrive(context.Background()); n != 0 || !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Redrive = %d, %v; want ErrPoolClosed", n, err)
+	}
+	if dlq.Len() != 3 {
+		t.Errorf("%d jobs back in the store; want 3", dlq.Len())
+	}
+}
+
+func TestRedriveKeepsFileUntilResubmitted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dead.jsonl")
+	dlq := NewFileDeadLetters[int](path)
+	for i := 0; i < 3; i++ {
+		dlq.Add(DeadLetter[int]{Input: i, Err: errors.New("old failure")})
+	}
+	before, _ := os.ReadFile(path)
+
+	closed := NewWorkerPool(func(ctx context.Context, n int) (int, error) { return n, nil }, PoolOptions{Workers: 1})
+	closed.SetDeadLetters(dlq)
+	closed.Abort()
+	if _, err := closed.Redrive(context.Background()); !errors.Is(err, ErrPoolClosed) {
+		t.Fatalf("Redrive = %v; want ErrPoolClosed", err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Errorf("a failed redrive changed the file:\n%s", after)
+	}
+
+	// Letters parked after Peek survive the Remove that follows
+	letters, _ := dlq.Peek()
+	dlq.Add(DeadLetter[int]{Input: 99, Err: errors.New("new failure")})
+	if err := dlq.Remove(len(letters)); err != nil {
+		t.Fatal(err)
+	}
+	if rest, err := dlq.Peek(); err != nil || len(rest) != 1 || rest[0].Input != 99 {
+		t.Errorf("after Remove: %+v, %v; want only job 99", rest, err)
+	}
+}
+
+// brokenRemoveStore parks jobs in memory but can't remove them
+type brokenRemoveStore struct{ MemoryDeadLetters[int] }
+
+var errStoreDown = errors.New("store unavailable")
+
+func (s *brokenRemoveStore) Remove(int) error { return errStoreDown }
+
+func TestRedriveReportsStoreErrors(t *testing.T) {
+	store := &brokenRemoveStore{}
+	store.Add(DeadLetter[int]{Input: 1, Err: errors.New("old failure")})
+	pool := NewWorkerPool(func(ctx context.Context, n int) (int, error) { return n, nil }, PoolOptions{Workers: 1})
+	pool.SetDeadLetters(store)
+
+	pool.Start(context.Background())
+	n, err := pool.Redrive(context.Background())
+	if n != 1 || !errors.Is(err, errStoreDown) {
+		t.Errorf("Redrive = %d, %v; want 1 and the store error", n, err)
+	}
+	go pool.Shutdown(context.Background())
+	collect(pool)
+
+	// A full queue stops the redrive after one job: that one is removed,
+	// and the failed Remove is reported next to the Submit error
+	store.Add(DeadLetter[int]{Input: 2, Err: errors.New("old failure")})
+	full := NewWorkerPool(func(ctx context.Context, n int) (int, error) { return n, nil }, PoolOptions{Workers: 1, QueueSize: 1})
+	full.SetDeadLetters(store)
+	defer full.Abort()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	n, err = full.Redrive(ctx)
+	if n != 1 || !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, errStoreDown) {
+		t.Errorf("Redrive = %d, %v; want 1 with both the Submit and the store error", n, err)
+	}
+}
+
+func TestRetryBackoffBounds(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}.withDefaults()
+	for attempt := 1; attempt <= 10; attempt++ {
+		ceiling := min(policy.MaxDelay, policy.BaseDelay<<(attempt-1))
+		for i := 0; i < 50; i++ {
+			if d := policy.backoff(attempt); d < 0 || d > ceiling {
+				t.Fatalf("backoff(%d) = %v; want within [0, %v]", attempt, d, ceiling)
+			}
+		}
+	}
+}
@@ -33,25 +33,28 @@ Worker Pool = Restaurant Kitchen
 package main
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// 📋 JOB DEFINITIONS
+// 📋 JOB DEFINITIONS: The demos' input type; results are Result[Job, string]
+// (see pool.go)
 type Job struct {
 	ID   int
 	Data string
 }
 
-type Result struct {
-	Job    Job
-	Output string
-	Error  error
-}
-
-// 🏭 BASIC WORKER POOL
+// 🏭 BASIC WORKER POOL: A source, a pool stage and a sink (see pipeline.go)
 func basicWorkerPool() {
 	fmt.Println("🏭 Basic Worker Pool")
 	fmt.Println("===================")
@@ -59,165 +62,56 @@ func basicWorkerPool() {
 	const numWorkers = 3
 	const numJobs = 10
 
-	// Create channels
-	jobs := make(chan Job, numJobs)
-	results := make(chan Result, numJobs)
-
-	// Start workers
-	var wg sync.WaitGroup
-	for w := 1; w <= numWorkers; w++ {
-		wg.Add(1)
-		go func(id int) {
-			defer wg.Done()
-			for job := range jobs {
-				fmt.Printf("Worker %d processing job %d\n", id, job.ID)
-				
-				// Simulate work
-				time.Sleep(time.Duration(rand.Intn(1000)) * time.Millisecond)
-				
-				// Send result
-				results <- Result{
-					Job:    job,
-					Output: fmt.Sprintf("Processed by worker %d", id),
-					Error:  nil,
-				}
-			}
-			fmt.Printf("Worker %d finished\n", id)
-		}(w)
-	}
-
-	// Send jobs
-	go func() {
-		for j := 1; j <= numJobs; j++ {
-			jobs <- Job{ID: j, Data: fmt.Sprintf("job-%d", j)}
-		}
-		close(jobs)
-	}()
-
-	// Collect results
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
-
-	// Process results
-	for result := range results {
-		fmt.Printf("Result: Job %d -> %s\n", result.Job.ID, result.Output)
+	jobs := make([]Job, numJobs)
+	for j := range jobs {
+		jobs[j] = Job{ID: j + 1, Data: fmt.Sprintf("job-%d", j+1)}
 	}
-}
 
-// 🎯 ADVANCED WORKER POOL WITH STRUCT
-type WorkerPool struct {
-	workers    int
-	jobQueue   chan Job
-	resultChan chan Result
-	quit       chan bool
-	wg         sync.WaitGroup
-}
+	// The Map stage is the pool: numWorkers goroutines reading one channel
+	p := NewPipeline(context.Background())
+	processed := Map(Values(p, "jobs", jobs...), "process", StageOptions{Workers: numWorkers},
+		func(ctx context.Context, job Job) (Job, error) {
+			fmt.Printf("Processing job %d\n", job.ID)
 
-func NewWorkerPool(workers int, queueSize int) *WorkerPool {
-	return &WorkerPool{
-		workers:    workers,
-		jobQueue:   make(chan Job, queueSize),
-		resultChan: make(chan Result, queueSize),
-		quit:       make(chan bool),
-	}
-}
+			// Simulate work
+			time.Sleep(time.Duration(rand.Intn(1000)) * time.Millisecond)
 
-func (wp *WorkerPool) Start() {
-	for i := 0; i < wp.workers; i++ {
-		wp.wg.Add(1)
-		go wp.worker(i + 1)
-	}
-}
+			job.Data = "processed " + job.Data
+			return job, nil
+		})
+	ForEach(processed, "print", func(ctx context.Context, job Job) error {
+		fmt.Printf("Result: Job %d -> %s\n", job.ID, job.Data)
+		return nil
+	})
 
-func (wp *WorkerPool) worker(id int) {
-	defer wp.wg.Done()
-	
-	for {
-		select {
-		case job := <-wp.jobQueue:
-			fmt.Printf("🔧 Worker %d processing job %d\n", id, job.ID)
-			
-			// Simulate processing time
-			processingTime := time.Duration(rand.Intn(500)+100) * time.Millisecond
-			time.Sleep(processingTime)
-			
-			// Simulate occasional errors
-			var err error
-			if rand.Float32() < 0.1 { // 10% error rate
-				err = fmt.Errorf("processing failed for job %d", job.ID)
-			}
-			
-			result := Result{
-				Job:    job,
-				Output: fmt.Sprintf("Completed by worker %d in %v", id, processingTime),
-				Error:  err,
-			}
-			
-			wp.resultChan <- result
-			
-		case <-wp.quit:
-			fmt.Printf("🛑 Worker %d stopping\n", id)
-			return
-		}
+	if err := p.Wait(); err != nil {
+		fmt.Printf("❌ %v\n", err)
 	}
+	printStages(p)
 }
 
-func (wp *WorkerPool) Submit(job Job) {
-	wp.jobQueue <- job
-}
-
-func (wp *WorkerPool) Results() <-chan Result {
-	return wp.resultChan
-}
-
-func (wp *WorkerPool) Stop() {
-	close(wp.quit)
-	wp.wg.Wait()
-	close(wp.jobQueue)
-	close(wp.resultChan)
-}
-
-// 📊 BATCH PROCESSOR
-type BatchProcessor struct {
-	workerPool *WorkerPool
-	batchSize  int
-	timeout    time.Duration
-}
-
-func NewBatchProcessor(workers, queueSize, batchSize int, timeout time.Duration) *BatchProcessor {
-	return &BatchProcessor{
-		workerPool: NewWorkerPool(workers, queueSize),
-		batchSize:  batchSize,
-		timeout:    timeout,
+// 📈 STAGE REPORT: One line per pipeline stage
+func printStages(p *Pipeline) {
+	for _, s := range p.Stats() {
+		fmt.Printf("📈 %-10s %d worker(s), %3d in, %3d out, %6.1f/s, p50 %v, p95 %v\n",
+			s.Name, s.Workers, s.In, s.Out, s.Throughput,
+			s.LatencyP50.Round(time.Microsecond), s.LatencyP95.Round(time.Microsecond))
 	}
 }
 
-func (bp *BatchProcessor) ProcessBatch(jobs []Job) []Result {
-	bp.workerPool.Start()
-	defer bp.workerPool.Stop()
-
-	// Submit all jobs
-	for _, job := range jobs {
-		bp.workerPool.Submit(job)
+// 🔐 CHECKSUM JOB: Real CPU work for the pool demos, with real failures
+func checksumJob(ctx context.Context, job Job) (string, error) {
+	if job.Data == "" {
+		return "", fmt.Errorf("job %d: empty payload", job.ID)
 	}
-
-	// Collect results with timeout
-	var results []Result
-	timeout := time.After(bp.timeout)
-	
-	for i := 0; i < len(jobs); i++ {
-		select {
-		case result := <-bp.workerPool.Results():
-			results = append(results, result)
-		case <-timeout:
-			fmt.Printf("⏰ Timeout reached, collected %d/%d results\n", len(results), len(jobs))
-			return results
+	sum := sha256.Sum256([]byte(job.Data))
+	for i := 0; i < 20000; i++ {
+		if i%1000 == 0 && ctx.Err() != nil {
+			return "", ctx.Err()
 		}
+		sum = sha256.Sum256(sum[:])
 	}
-	
-	return results
+	return hex.EncodeToString(sum[:8]), nil
 }
 
 // 🌐 HTTP REQUEST WORKER POOL EXAMPLE
@@ -315,63 +209,65 @@ func main() {
 	fmt.Println("\n🎯 Advanced Worker Pool")
 	fmt.Println("=======================")
 
-	pool := NewWorkerPool(4, 20)
-	pool.Start()
+	ctx := context.Background()
+	pool := NewWorkerPool(checksumJob, PoolOptions{Workers: 4, QueueSize: 20, Ordered: true})
+	pool.Start(ctx)
 
-	// Submit jobs
+	// Submit jobs; every 5th one has no payload and fails
 	numJobs := 15
 	go func() {
 		for i := 1; i <= numJobs; i++ {
-			job := Job{
-				ID:   i,
-				Data: fmt.Sprintf("task-%d", i),
+			job := Job{ID: i, Data: fmt.Sprintf("task-%d", i)}
+			if i%5 == 0 {
+				job.Data = ""
 			}
-			pool.Submit(job)
+			pool.Submit(ctx, job)
 		}
+		pool.Shutdown(ctx)
 	}()
 
-	// Collect results
+	// Collect results, in submission order thanks to Ordered
 	var successCount, errorCount int
-	for i := 0; i < numJobs; i++ {
-		result := <-pool.Results()
-		if result.Error != nil {
-			fmt.Printf("❌ Job %d failed: %v\n", result.Job.ID, result.Error)
+	for result := range pool.Results() {
+		if result.Err != nil {
+			fmt.Printf("❌ Job %d failed: %v\n", result.Input.ID, result.Err)
 			errorCount++
 		} else {
-			fmt.Printf("✅ Job %d: %s\n", result.Job.ID, result.Output)
+			fmt.Printf("✅ Job %d: checksum %s\n", result.Input.ID, result.Output)
 			successCount++
 		}
 	}
 
-	pool.Stop()
 	fmt.Printf("📊 Summary: %d successful, %d failed\n", successCount, errorCount)
 
 	// 🎯 DEMO 3: Batch Processor
 	fmt.Println("\n🎯 Batch Processor")
 	fmt.Println("==================")
 
-	batchProcessor := NewBatchProcessor(3, 10, 5, 5*time.Second)
-	
-	// Create batch of jobs
-	var batchJobs []Job
-	for i := 1; i <= 8; i++ {
-		batchJobs = append(batchJobs, Job{
-			ID:   i,
-			Data: fmt.Sprintf("batch-job-%d", i),
-		})
-	}
-
-	fmt.Printf("Processing batch of %d jobs...\n", len(batchJobs))
-	results := batchProcessor.ProcessBatch(batchJobs)
-	
-	fmt.Printf("Batch completed: %d results\n", len(results))
-	for _, result := range results {
-		if result.Error != nil {
-			fmt.Printf("❌ Batch job %d: %v\n", result.Job.ID, result.Error)
-		} else {
-			fmt.Printf("✅ Batch job %d: %s\n", result.Job.ID, result.Output)
+	// Items trickle in; batches go out when 5 are collected or the oldest
+	// item has waited 150ms
+	batcher := NewBatchProcessor(func(ctx context.Context, batch []Job) error {
+		ids := make([]int, len(batch))
+		for i, job := range batch {
+			ids[i] = job.ID
+		}
+		fmt.Printf("📦 Flushing %d jobs: %v\n", len(batch), ids)
+		return nil
+	}, BatchOptions{BatchSize: 5, MaxWait: 150 * time.Millisecond})
+	batcher.Start(ctx)
+
+	for i := 1; i <= 12; i++ {
+		batcher.Add(ctx, Job{ID: i, Data: fmt.Sprintf("batch-job-%d", i)})
+		if i == 7 {
+			time.Sleep(250 * time.Millisecond) // A lull: the partial batch goes out on time
 		}
 	}
+	if err := batcher.Shutdown(ctx); err != nil {
+		fmt.Printf("❌ %v\n", err)
+	}
+	bs := batcher.Stats()
+	fmt.Printf("📊 %d items in %d batches (%d full, %d on time, %d at shutdown)\n\n",
+		bs.Items, bs.Batches, bs.BySize, bs.ByTime, bs.Final)
 
 	// 🎯 DEMO 4: HTTP Worker Pool (simulated)
 	httpWorkerPool()
@@ -393,12 +289,21 @@ func main() {
 	start = time.Now()
 	fmt.Println("Parallel processing with worker pool...")
 	
-	parallelPool := NewWorkerPool(5, 10)
-	parallelPool.Start()
+	// The handler sleeps like an I/O-bound call would, but honours ctx
+	simulatedIO := func(ctx context.Context, job Job) (string, error) {
+		select {
+		case <-time.After(100 * time.Millisecond):
+			return job.Data, nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	parallelPool := NewWorkerPool(simulatedIO, PoolOptions{Workers: 5, QueueSize: 10})
+	parallelPool.Start(ctx)
 
 	// Submit jobs
 	for i := 1; i <= 10; i++ {
-		parallelPool.Submit(Job{ID: i, Data: fmt.Sprintf("perf-test-%d", i)})
+		parallelPool.Submit(ctx, Job{ID: i, Data: fmt.Sprintf("perf-test-%d", i)})
 	}
 
 	// Collect results
@@ -406,11 +311,217 @@ func main() {
 		<-parallelPool.Results()
 	}
 
-	parallelPool.Stop()
+	parallelPool.Shutdown(ctx)
 	parallelTime := time.Since(start)
 	fmt.Printf("Parallel time: %v\n", parallelTime)
 	fmt.Printf("Speedup: %.2fx\n", float64(sequentialTime)/float64(parallelTime))
 
+	// 🎯 DEMO 6: Autoscaling
+	fmt.Println("\n🎯 Autoscaling Under a Burst")
+	fmt.Println("============================")
+
+	scaling := NewWorkerPool(simulatedIO, PoolOptions{
+		MinWorkers:    1,
+		MaxWorkers:    16,
+		QueueSize:     100,
+		ScaleInterval: 50 * time.Millisecond,
+		TargetWait:    200 * time.Millisecond,
+		IdleTimeout:   300 * time.Millisecond,
+	})
+	scaling.Start(ctx)
+	go func() {
+		for range scaling.Results() {
+		}
+	}()
+
+	for i := 1; i <= 100; i++ {
+		scaling.Submit(ctx, Job{ID: i, Data: fmt.Sprintf("burst-%d", i)})
+	}
+	for i := 0; i < 12; i++ {
+		s := scaling.Stats()
+		fmt.Printf("📈 workers=%-2d active=%-2d idle=%-2d queued=%-3d done=%-3d p50=%v\n",
+			s.Workers, s.Active, s.Idle, s.Queued, s.Completed, s.LatencyP50.Round(time.Millisecond))
+		time.Sleep(150 * time.Millisecond)
+	}
+	scaling.Shutdown(ctx)
+
+	// 🎯 DEMO 7: Priority Scheduling
+	fmt.Println("\n🎯 Priority Scheduling")
+	fmt.Println("======================")
+
+	// One worker for everything else, one kept free for Critical jobs
+	prioritized := NewWorkerPool(simulatedIO, PoolOptions{
+		Workers:   2,
+		QueueSize: 20,
+		Aging:     300 * time.Millisecond,
+		Reserve:   map[Priority]int{Critical: 1},
+	})
+	levels := []Priority{Low, Low, Medium, High, Low, Critical, Medium, High, Critical, Low}
+	for i, level := range levels {
+		prioritized.SubmitPriority(ctx, Job{ID: i + 1, Data: fmt.Sprintf("job-%d", i+1)}, level)
+	}
+	prioritized.Start(ctx)
+	go prioritized.Shutdown(ctx)
+
+	for result := range prioritized.Results() {
+		fmt.Printf("🚦 Job %-2d %s\n", result.Input.ID, result.Priority)
+	}
+
+	// 🎯 DEMO 8: Timeouts, Retries and Dead Letters
+	fmt.Println("\n🎯 Timeouts, Retries and Dead Letters")
+	fmt.Println("=====================================")
+
+	var downstreamUp atomic.Bool
+	var attempts atomic.Int64
+	flaky := func(ctx context.Context, job Job) (string, error) {
+		switch {
+		case job.ID == 3:
+			select { // Hangs until JobTimeout
+			case <-time.After(time.Hour):
+			case <-ctx.Done():
+			}
+			return "", ctx.Err()
+		case !downstreamUp.Load() && job.ID%2 == 0:
+			return "", Retryable(fmt.Errorf("job %d: downstream unavailable", job.ID))
+		case job.ID == 2 && attempts.Add(1) < 3:
+			return "", Retryable(errors.New("connection reset"))
+		}
+		return job.Data, nil
+	}
+
+	dlq := &MemoryDeadLetters[Job]{}
+	resilient := NewWorkerPool(flaky, PoolOptions{
+		Workers:    3,
+		JobTimeout: 100 * time.Millisecond,
+		Retry:      RetryPolicy{MaxAttempts: 3, BaseDelay: 20 * time.Millisecond},
+	})
+	resilient.SetDeadLetters(dlq)
+	resilient.Start(ctx)
+	go func() {
+		for i := 1; i <= 5; i++ {
+			resilient.Submit(ctx, Job{ID: i, Data: fmt.Sprintf("payload-%d", i)})
+		}
+		time.Sleep(time.Second) // Let the failures settle, then "fix" the downstream
+		downstreamUp.Store(true)
+		n, _ := resilient.Redrive(ctx)
+		fmt.Printf("🔁 Re-drove %d dead-lettered jobs\n", n)
+		resilient.Shutdown(ctx)
+	}()
+
+	for result := range resilient.Results() {
+		if result.Err != nil {
+			fmt.Printf("☠️ Job %d failed after %d attempt(s): %v\n", result.Input.ID, result.Attempts, result.Err)
+		} else {
+			fmt.Printf("✅ Job %d: %s (attempt %d)\n", result.Input.ID, result.Output, result.Attempts)
+		}
+	}
+
+	// 🎯 DEMO 9: Durable Queue
+	fmt.Println("\n🎯 Durable Queue Across a Restart")
+	fmt.Println("=================================")
+
+	logDir, _ := os.MkdirTemp("", "worker-pools-demo")
+	defer os.RemoveAll(logDir)
+	logPath := filepath.Join(logDir, "jobs.log")
+
+	queue, err := OpenDurableQueue[Job](logPath, DurableOptions{})
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	for i := 1; i <= 6; i++ {
+		queue.Enqueue(Job{ID: i, Data: fmt.Sprintf("durable-%d", i)}, Medium)
+	}
+	// The first "process" acks two jobs, leases a third and then dies
+	for i := 0; i < 3; i++ {
+		d, _ := queue.Dequeue(ctx)
+		if i < 2 {
+			queue.Ack(d)
+			fmt.Printf("✅ Job %d done before the crash\n", d.Input.ID)
+		} else {
+			fmt.Printf("💥 Crash while job %d was running\n", d.Input.ID)
+		}
+	}
+	queue.Close()
+
+	// The next one replays the log and finishes the rest
+	queue, err = OpenDurableQueue[Job](logPath, DurableOptions{})
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return
+	}
+	fmt.Printf("🔄 Reopened with %d jobs ready\n", queue.Stats().Ready)
+	durablePool := NewWorkerPool(DurableHandler(queue, checksumJob), PoolOptions{Workers: 2})
+	durableCtx, stopFeeding := context.WithCancel(ctx)
+	durablePool.Start(durableCtx)
+	go FeedPool(durableCtx, queue, durablePool)
+	for result := range durablePool.Results() {
+		fmt.Printf("✅ Job %d done after the restart\n", result.Input.Input.ID)
+		if s := queue.Stats(); s.Ready == 0 && s.InFlight == 0 {
+			stopFeeding()
+		}
+	}
+	queue.Close()
+
+	// 🎯 DEMO 10: ETL Pipeline
+	fmt.Println("\n🎯 ETL Pipeline")
+	fmt.Println("===============")
+
+	logLines := []string{
+		"the cache missed and the db answered",
+		"a retry hit the cache",
+		"the queue drained and the pool shrank",
+	}
+	stopwords := map[string]bool{"the": true, "a": true, "and": true}
+
+	etl := NewPipeline(ctx)
+	words := FlatMap(Values(etl, "read", logLines...), "split", StageOptions{Workers: 2},
+		func(ctx context.Context, line string) ([]string, error) {
+			return strings.Fields(line), nil
+		})
+	kept := Filter(words, "stopwords", StageOptions{Workers: 2}, func(ctx context.Context, w string) (bool, error) {
+		return !stopwords[w], nil
+	})
+	hashed := Map(kept, "checksum", StageOptions{Workers: 4, Ordered: true}, func(ctx context.Context, w string) (string, error) {
+		sum, err := checksumJob(ctx, Job{Data: w})
+		return w + ":" + sum[:6], err
+	})
+	ForEach(Batch(hashed, "batch", BatchOptions{BatchSize: 4, MaxWait: 50 * time.Millisecond}), "insert",
+		func(ctx context.Context, rows []string) error {
+			fmt.Printf("💾 Inserted %d rows: %v\n", len(rows), rows)
+			return nil
+		})
+	if err := etl.Wait(); err != nil {
+		fmt.Printf("❌ %v\n", err)
+	}
+	printStages(etl)
+
+	// 🎯 DEMO 11: Fan-in Pipeline
+	// Mirrors DEMO 4 (Fan-in Pattern) in 21_select/select.go. Directories
+	// don't import each other, so its worker1..3 are rewritten as sources,
+	// with the sleeps cut from seconds to tenths of a second.
+	fmt.Println("\n🎯 Fan-in Pipeline")
+	fmt.Println("==================")
+
+	fanIn := NewPipeline(ctx)
+	selectWorker := func(n int, delay time.Duration) Stream[string] {
+		return Source(fanIn, fmt.Sprintf("worker%d", n), func(ctx context.Context, emit func(string) error) error {
+			time.Sleep(delay)
+			return emit(fmt.Sprintf("Worker %d finished", n))
+		})
+	}
+	merged := Merge("fan-in",
+		selectWorker(1, 200*time.Millisecond),
+		selectWorker(2, 100*time.Millisecond),
+		selectWorker(3, 300*time.Millisecond))
+	ForEach(merged, "print", func(ctx context.Context, msg string) error {
+		fmt.Println("📥", msg)
+		return nil
+	})
+	if err := fanIn.Wait(); err != nil {
+		fmt.Printf("❌ %v\n", err)
+	}
+
 	fmt.Println("\n✨ All worker pool demos completed!")
 }
 
@@ -423,7 +534,7 @@ func main() {
 ┌─────────────────────────────────────────────────────────────────────────┐
 │ // Basic structure                                                      │
 │ jobs := make(chan Job, queueSize)                                       │
-│ results := make(chan Result, queueSize)                                 │
+│ results := make(chan Result[Job, string], queueSize)                    │
 │                                                                         │
 │ // Start workers                                                        │
 │ for w := 1; w <= numWorkers; w++ {                                      │
@@ -451,43 +562,65 @@ func main() {
 
 🎯 WORKER POOL COMPONENTS:
 ┌─────────────────────────────────────────────────────────────────────────┐
-│ type WorkerPool struct {                                                │
-│     workers    int              // Number of worker goroutines          │
-│     jobQueue   chan Job         // Channel for incoming jobs            │
-│     resultChan chan Result      // Channel for results                  │
-│     quit       chan bool        // Channel for shutdown signal          │
-│     wg         sync.WaitGroup   // Wait for workers to finish           │
+│ // Generic: the work is a handler, the pool only does concurrency       │
+│ type Handler[In, Out any] func(ctx context.Context, in In) (Out, error) │
+│                                                                         │
+│ type Result[In, Out any] struct {                                       │
+│     Seq    int  // Submission order                                     │
+│     Input  In                                                           │
+│     Output Out                                                          │
+│     Err    error // Handler error or recovered panic                    │
 │ }                                                                       │
 │                                                                         │
+│ pool := NewWorkerPool(handler, PoolOptions{Workers: 4, Ordered: true})  │
+│                                                                         │
 │ // Key methods                                                          │
-│ func (wp *WorkerPool) Start()                // Start workers           │
-│ func (wp *WorkerPool) Submit(job Job)        // Submit job              │
-│ func (wp *WorkerPool) Results() <-chan Result // Get results channel    │
-│ func (wp *WorkerPool) Stop()                 // Graceful shutdown       │
+│ pool.Start(ctx)                // Start workers; cancel ctx to stop     │
+│ pool.Submit(ctx, in) error     // Blocks while the queue is full        │
+│ pool.Results()                 // <-chan Result[In, Out]                │
+│ pool.Shutdown(ctx) error       // No more jobs; drain, close Results    │
+│ pool.Abort()                   // Cancel running jobs, drop the queue   │
+│ // Submit after either returns ErrPoolClosed, never panics              │
 └─────────────────────────────────────────────────────────────────────────┘
 
 🔧 IMPLEMENTATION PATTERNS:
 ┌─────────────────────────────────────────────────────────────────────────┐
-│ // Worker function with select                                          │
-│ func (wp *WorkerPool) worker(id int) {                                  │
-│     for {                                                               │
+│ // Worker loop: run the most urgent job, or sleep until woken           │
+│ func (p *WorkerPool[In, Out]) worker() {                                │
+│     defer p.wg.Done()                                                   │
+│     for p.ctx.Err() == nil {                                            │
+│         p.mu.Lock()                                                     │
+│         t, ok := p.queue.take(p.minPriorityLocked())                    │
+│         if !ok {                                                        │
+│             if p.closed && p.queue.Len() == 0 {                         │
+│                 p.mu.Unlock()                                           │
+│                 return // Shutdown and drained                          │
+│             }                                                           │
+│             wake := p.wake // Closed on every queue or worker change    │
+│             p.mu.Unlock()                                               │
+│             select {                                                    │
+│             case <-wake:                                                │
+│             case <-p.ctx.Done(): // Abort                               │
+│             }                                                           │
+│             continue                                                    │
+│         }                                                               │
+│         p.mu.Unlock()                                                   │
+│         out, err := p.runAttempt(t.in) // Timeout, recovers panics      │
+│         r := Result[In, Out]{Seq: t.seq, Output: out, Err: err}         │
 │         select {                                                        │
-│         case job := <-wp.jobQueue:                                      │
-│             // Process job                                              │
-│             result := processJob(job)                                   │
-│             wp.resultChan <- result                                     │
-│         case <-wp.quit:                                                 │
+│         case p.done <- r:                                               │
+│         case <-p.ctx.Done():                                            │
 │             return                                                      │
 │         }                                                               │
 │     }                                                                   │
 │ }                                                                       │
 │                                                                         │
-│ // Graceful shutdown                                                    │
-│ func (wp *WorkerPool) Stop() {                                          │
-│     close(wp.quit)        // Signal workers to stop                     │
-│     wp.wg.Wait()          // Wait for workers to finish                 │
-│     close(wp.jobQueue)    // Close job queue                            │
-│     close(wp.resultChan)  // Close result channel                       │
+│ // Submission order: hold results until all earlier ones are out        │
+│ pending[r.Seq] = r                                                      │
+│ for ready, ok := pending[next]; ok; ready, ok = pending[next] {         │
+│     delete(pending, next)                                               │
+│     p.emit(ready) // Dropped instead of blocking after Abort            │
+│     next++                                                              │
 │ }                                                                       │
 └─────────────────────────────────────────────────────────────────────────┘
 
@@ -504,34 +637,56 @@ func main() {
 │ • Large queue: Better throughput, more memory                           │
 │ • Buffered channels: len(jobs) = 2-10x number of workers                │
 │                                                                         │
-│ // Monitoring metrics                                                   │
-│ • Queue length: len(jobQueue)                                           │
-│ • Active workers: Track in worker function                              │
-│ • Processing time: Measure per job                                      │
-│ • Error rate: Track failed jobs                                         │
+│ // Monitoring metrics: pool.Stats()                                     │
+│ • Queued, Active, Idle, Completed, Failed                               │
+│ • LatencyP50 / LatencyP95 over recent jobs                              │
+│                                                                         │
+│ // Let the pool size itself (autoscale.go)                              │
+│ PoolOptions{MinWorkers: 2, MaxWorkers: 32, TargetWait: time.Second}     │
+│ • Grows when queued × p50 ÷ workers stays above TargetWait              │
+│ • Shrinks after IdleTimeout with idle workers and an empty queue        │
+│ • pool.Resize(n) sets the count by hand                                 │
 └─────────────────────────────────────────────────────────────────────────┘
 
 ⚡ PERFORMANCE OPTIMIZATION:
 ┌─────────────────────────────────────────────────────────────────────────┐
-│ // Batch processing                                                     │
-│ type Batch []Job                                                        │
-│ func processBatch(batch Batch) []Result {                               │
-│     // Process multiple jobs together                                   │
-│ }                                                                       │
+│ // Batch processing: flush when full or after MaxWait (batch.go)        │
+│ batcher := NewBatchProcessor(func(ctx context.Context, b []Row) error { │
+│     return db.BulkInsert(ctx, b)                                        │
+│ }, BatchOptions{BatchSize: 500, MaxWait: time.Second})                  │
+│ batcher.Add(ctx, row)     // Blocks when flushes fall behind            │
+│ batcher.Shutdown(ctx)     // Flushes the partial batch                  │
 │                                                                         │
-│ // Worker pools with different priorities                               │
-│ highPriorityJobs := make(chan Job, 100)                                 │
-│ lowPriorityJobs := make(chan Job, 1000)                                 │
+│ // Priorities: the queue is a heap (priority.go)                        │
+│ pool.SubmitPriority(ctx, job, Critical)                                 │
+│ pool.Submit(ctx, job) // Medium                                         │
 │                                                                         │
-│ // Worker with priority handling                                        │
-│ select {                                                                │
-│ case job := <-highPriorityJobs:                                         │
-│     // Process high priority job                                        │
-│ case job := <-lowPriorityJobs:                                          │
-│     // Process low priority job                                         │
-│ case <-quit:                                                            │
-│     return                                                              │
+│ PoolOptions{                                                            │
+│     Aging:   time.Second,                   // Low work can't starve    │
+│     Reserve: map[Priority]int{Critical: 1}, // Always a worker free     │
 │ }                                                                       │
+│                                                                         │
+│ // Why not a select over one channel per priority? When both are        │
+│ // ready, select picks at random, so High only wins half the time.      │
+└─────────────────────────────────────────────────────────────────────────┘
+
+🔗 PIPELINES:
+┌─────────────────────────────────────────────────────────────────────────┐
+│ // Stages connected by typed channels (pipeline.go)                     │
+│ p := NewPipeline(ctx)                                                   │
+│ lines := Values(p, "read", logLines...)                                 │
+│ words := FlatMap(lines, "split", StageOptions{Workers: 2}, split)       │
+│ kept := Filter(words, "stopwords", StageOptions{}, notStopword)         │
+│ sums := Map(kept, "hash", StageOptions{Workers: 4, Ordered: true}, h)   │
+│ rows := Batch(sums, "batch", BatchOptions{BatchSize: 500})              │
+│ ForEach(rows, "insert", insertRows)                                     │
+│ err := p.Wait() // "stage hash: ..." from the first failure             │
+│                                                                         │
+│ // Fan-in: Merge("fan-in", s1, s2, s3) emits in arrival order           │
+│ • Map, Filter and FlatMap each run on a WorkerPool                      │
+│ • A full Buffer blocks the stage before it: backpressure end to end     │
+│ • The first error or a cancelled ctx stops every stage                  │
+│ • p.Stats(): In, Out, Throughput, LatencyP50/P95 per stage              │
 └─────────────────────────────────────────────────────────────────────────┘
 
 💡 BEST PRACTICES: